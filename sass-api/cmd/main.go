@@ -7,6 +7,7 @@ import (
 	"github.com/Aebroyx/sass-api/internal/database"
 	"github.com/Aebroyx/sass-api/internal/handlers"
 	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
 	"github.com/Aebroyx/sass-api/internal/routes"
 	"github.com/Aebroyx/sass-api/internal/services"
 )
@@ -40,33 +41,78 @@ func main() {
 	}
 
 	// Initialize services
-	tokenService := services.NewTokenService(db.DB, cfg)
 	auditService := services.NewAuditService(db.DB)
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	keyRing, err := services.NewKeyRing(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	tokenService := services.NewTokenService(db.DB, cfg, auditService, keyRing)
+	if cfg.NotifyOnTokenReuse {
+		tokenService.SetSecurityNotifier(services.MailSecurityNotifier{Mailer: mail})
+	}
 	rateLimiterService := services.NewRateLimiterService(cfg)
-	userService := services.NewUserService(db.DB, cfg, tokenService)
+	mfaService := services.NewMFAService(db.DB, cfg, tokenService)
+	webAuthnService, err := services.NewWebAuthnService(db.DB, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize WebAuthn: %v", err)
+	}
+	passwordPolicyService, err := services.NewPasswordPolicyService(db.DB, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize password policy: %v", err)
+	}
+	userService := services.NewUserService(db.DB, cfg, tokenService, mfaService, webAuthnService, passwordPolicyService)
+	passwordResetService := services.NewPasswordResetService(db.DB, cfg, mail, tokenService, auditService, passwordPolicyService)
+	emailVerificationService := services.NewEmailVerificationService(db.DB, cfg, mail, tokenService, auditService)
 	roleService := services.NewRoleService(db.DB, cfg)
 	menuService := services.NewMenuService(db.DB, cfg)
 	rightsAccessService := services.NewRightsAccessService(db.DB, cfg)
 	permissionService := services.NewPermissionService(db.DB, cfg, menuService)
 	searchService := services.NewSearchService(db.DB, cfg, permissionService)
+	apiKeyService := services.NewAPIKeyService(db.DB, cfg, permissionService)
+	oidcService := services.NewOIDCService(db.DB, cfg, tokenService)
+	oauthService := services.NewOAuthService(db.DB, cfg, tokenService, permissionService)
+	sessionService := services.NewSessionService(db.DB, tokenService)
+	magicLinkService := services.NewMagicLinkService(db.DB, cfg, mail, tokenService, auditService, rateLimiterService)
+	organizationService := services.NewOrganizationService(db.DB, cfg)
+	invitationService := services.NewInvitationService(db.DB, cfg, mail, tokenService, auditService, passwordPolicyService)
+	grantExpiryService := services.NewGrantExpiryService(db.DB, cfg)
+	defer grantExpiryService.Stop()
 
 	// Initialize handlers
 	h := &routes.Handlers{
-		Auth:         handlers.NewAuthHandler(userService, auditService),
-		User:         handlers.NewUserHandler(userService),
-		Role:         handlers.NewRoleHandler(roleService),
-		Menu:         handlers.NewMenuHandler(menuService),
-		RightsAccess: handlers.NewRightsAccessHandler(rightsAccessService),
-		Search:       handlers.NewSearchHandler(searchService),
-		Token:        handlers.NewTokenHandler(tokenService, userService, cfg, db.DB),
-		Audit:        handlers.NewAuditHandler(auditService),
+		Auth:              handlers.NewAuthHandler(userService, auditService, emailVerificationService),
+		User:              handlers.NewUserHandler(userService, auditService),
+		Role:              handlers.NewRoleHandler(roleService),
+		Menu:              handlers.NewMenuHandler(menuService),
+		RightsAccess:      handlers.NewRightsAccessHandler(rightsAccessService),
+		Search:            handlers.NewSearchHandler(searchService),
+		Token:             handlers.NewTokenHandler(tokenService, userService, cfg, db.DB),
+		Audit:             handlers.NewAuditHandler(auditService),
+		MFA:               handlers.NewMFAHandler(mfaService, auditService),
+		WebAuthn:          handlers.NewWebAuthnHandler(webAuthnService, userService, auditService),
+		PasswordReset:     handlers.NewPasswordResetHandler(passwordResetService),
+		EmailVerification: handlers.NewEmailVerificationHandler(emailVerificationService),
+		PasswordPolicy:    handlers.NewPasswordPolicyHandler(passwordPolicyService),
+		APIKey:            handlers.NewAPIKeyHandler(apiKeyService, auditService),
+		OIDC:              handlers.NewOIDCHandler(oidcService, userService, auditService, cfg),
+		OAuth:             handlers.NewOAuthHandler(oauthService, auditService),
+		Session:           handlers.NewSessionHandler(sessionService, auditService),
+		MagicLink:         handlers.NewMagicLinkHandler(magicLinkService, userService, auditService, cfg),
+		Organization:      handlers.NewOrganizationHandler(organizationService),
+		Invitation:        handlers.NewInvitationHandler(invitationService),
 	}
 
 	// Initialize services struct for router
 	svc := &routes.Services{
+		Token:       tokenService,
 		Permission:  permissionService,
 		Audit:       auditService,
 		RateLimiter: rateLimiterService,
+		APIKey:      apiKeyService,
 	}
 
 	// Setup router
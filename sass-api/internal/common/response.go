@@ -53,6 +53,15 @@ const (
 	CodeNotFound        = "NOT_FOUND"
 	CodeBadRequest      = "BAD_REQUEST"
 	CodeConflict        = "CONFLICT"
+
+	CodeInvalidMFACode        = "INVALID_MFA_CODE"
+	CodeMFAEnrollmentRequired = "MFA_ENROLLMENT_REQUIRED"
+	CodeEmailNotVerified      = "EMAIL_NOT_VERIFIED"
+	CodePasswordPolicy        = "PASSWORD_POLICY_VIOLATION"
+	CodePasswordExpired       = "PASSWORD_EXPIRED"
+	CodeLocked                = "LOCKED"
+	CodeSessionLimit          = "SESSION_LIMIT_REACHED"
+	CodeReauthRequired        = "REAUTH_REQUIRED"
 )
 
 // Common error responses
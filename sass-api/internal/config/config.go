@@ -3,11 +3,18 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Session limit policies for a login that would exceed the concurrent session cap
+const (
+	SessionLimitEvictOldest = "evict_oldest" // Sign out the oldest session to make room
+	SessionLimitReject      = "reject"       // Refuse the login
+)
+
 type Config struct {
 	// Server config
 	Environment string
@@ -28,6 +35,132 @@ type Config struct {
 	JWTExpiry          time.Duration
 	RefreshTokenExpiry time.Duration
 
+	// JWT signing keys. HS256 signs with JWTSecret; RS256 and EdDSA sign with the
+	// private key file, while retiring key files keep verifying older tokens.
+	JWTSigningAlg       string
+	JWTPrivateKeyFile   string
+	JWTRetiringKeyFiles []string
+
+	// JWTAcceptLegacyHS256Until keeps HS256 tokens signed with JWTSecret valid until this
+	// time after moving to RS256 or EdDSA. Zero rejects them right away.
+	JWTAcceptLegacyHS256Until time.Time
+
+	// RefreshTokenReuseGrace is how long a just-rotated refresh token can still be
+	// rotated by the client it was rotated for, so concurrent requests sharing the
+	// same cookie don't race each other into reuse detection
+	RefreshTokenReuseGrace time.Duration
+
+	// Session lifetime. A session ends when its refresh token isn't rotated within
+	// SessionIdleTimeout (0 uses RefreshTokenExpiry), and SessionMaxLifetime after the
+	// original login however often it is rotated (0 disables the limit). Roles can
+	// override both.
+	SessionIdleTimeout time.Duration
+	SessionMaxLifetime time.Duration
+
+	// MaxSessionsPerUser caps concurrent sessions per user (0 = unlimited; roles can
+	// override it). SessionLimitPolicy decides what a login over the cap does.
+	MaxSessionsPerUser int
+	SessionLimitPolicy string
+
+	// ReauthMaxAge is how recently a user must have signed in or re-authenticated
+	// to perform sensitive operations
+	ReauthMaxAge time.Duration
+
+	// NotifyOnTokenReuse alerts users when a stolen refresh token is replayed
+	NotifyOnTokenReuse bool
+
+	// AuthTokenSources lists where access tokens are read from, in order of precedence
+	// ("cookie" for the access_token cookie, "header" for an Authorization: Bearer header)
+	AuthTokenSources []string
+
+	// MFA config
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+
+	// WebAuthn (passkey) config
+	WebAuthnRPID          string
+	WebAuthnRPDisplayName string
+	WebAuthnRPOrigins     []string
+	WebAuthnSessionTTL    time.Duration
+
+	// Mail config
+	MailDriver   string // smtp, file or log
+	MailFrom     string
+	MailFileDir  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// AppBaseURL is the frontend URL used to build links sent by email
+	AppBaseURL string
+
+	// APIBaseURL is the public URL of this API, used to build OAuth callback URLs
+	APIBaseURL string
+
+	// Password reset config
+	PasswordResetTTL        time.Duration
+	PasswordResetRateLimit  int // Forgot-password requests per IP per window
+	PasswordResetRateWindow time.Duration
+
+	// Magic link (passwordless email login) config
+	MagicLinkEnabled        bool
+	MagicLinkTTL            time.Duration
+	MagicLinkRateLimit      int // Link requests per IP per window
+	MagicLinkEmailRateLimit int // Link requests per email address per window
+	MagicLinkRateWindow     time.Duration
+
+	// Email verification config
+	RequireEmailVerification    bool
+	EmailVerificationTTL        time.Duration
+	EmailVerificationRateLimit  int // Resend requests per IP per window
+	EmailVerificationRateWindow time.Duration
+
+	// Account lockout config
+	LockoutThreshold    int           // Failed logins before the account is locked, 0 disables lockout
+	LockoutBaseDuration time.Duration // First lockout duration, doubled for each consecutive lockout
+	LockoutMaxDuration  time.Duration
+	LockoutResetAfter   time.Duration // Failed attempts older than this are forgotten
+
+	// Password policy config
+	PasswordMinLength        int
+	PasswordMaxLength        int // bcrypt ignores anything past 72 bytes
+	PasswordRequireUpper     bool
+	PasswordRequireLower     bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	PasswordDisallowUserInfo bool          // Reject passwords containing the username or email name
+	PasswordDenylistFile     string        // Common passwords, one per line; empty disables the check
+	PasswordHistorySize      int           // Previous passwords that can't be reused, 0 disables the check
+	PasswordMaxAge           time.Duration // 0 disables password expiry
+
+	// External OpenID Connect providers
+	OIDCProviders []OIDCProviderConfig
+	OIDCStateTTL  time.Duration // How long a login may take at the provider
+
+	// OAuth2 authorization server config, tokens are issued with APIBaseURL as the issuer
+	OAuthAccessTokenTTL  time.Duration
+	OAuthRefreshTokenTTL time.Duration
+	OAuthCodeTTL         time.Duration
+
+	// Impersonation config
+	ImpersonationTTL time.Duration // Lifetime of an impersonation access token, which can't be refreshed
+
+	// API key config
+	APIKeyMaxLifetime time.Duration // Also the default lifetime, 0 allows keys that never expire
+	APIKeyMaxPerUser  int
+
+	// Multi-tenancy config
+	DefaultOrganization string // Slug of the organization self-registered users join
+	TenantBaseDomain    string // With "app.example.com", acme.app.example.com acts in "acme"; empty disables subdomains
+
+	// Organization invitation config
+	InvitationTTL time.Duration
+
+	// Time-bound permission grants
+	GrantSweepInterval  time.Duration // How often expired grants are removed; 0 disables the sweeper
+	GrantExpiringWindow time.Duration // Default look-ahead when listing grants that expire soon
+
 	// CORS config
 	CORSAllowedOrigins string
 
@@ -54,12 +187,164 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_EXPIRY format: %v", err)
 	}
 
+	// Parse the end of the HS256 migration window
+	var jwtAcceptLegacyHS256Until time.Time
+	if value := getEnv("JWT_ACCEPT_LEGACY_HS256_UNTIL", ""); value != "" {
+		jwtAcceptLegacyHS256Until, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_ACCEPT_LEGACY_HS256_UNTIL format, expected RFC 3339: %v", err)
+		}
+	}
+
 	// Parse refresh token expiry duration
 	refreshTokenExpiry, err := time.ParseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "168h"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid REFRESH_TOKEN_EXPIRY format: %v", err)
 	}
 
+	// Parse refresh token reuse grace period
+	refreshTokenReuseGrace, err := time.ParseDuration(getEnv("REFRESH_TOKEN_REUSE_GRACE", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REFRESH_TOKEN_REUSE_GRACE format: %v", err)
+	}
+
+	// Parse session lifetimes
+	sessionIdleTimeout, err := time.ParseDuration(getEnv("SESSION_IDLE_TIMEOUT", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_IDLE_TIMEOUT format: %v", err)
+	}
+	sessionMaxLifetime, err := time.ParseDuration(getEnv("SESSION_MAX_LIFETIME", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_MAX_LIFETIME format: %v", err)
+	}
+
+	reauthMaxAge, err := time.ParseDuration(getEnv("REAUTH_MAX_AGE", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REAUTH_MAX_AGE format: %v", err)
+	}
+
+	sessionLimitPolicy := getEnv("SESSION_LIMIT_POLICY", SessionLimitEvictOldest)
+	if sessionLimitPolicy != SessionLimitEvictOldest && sessionLimitPolicy != SessionLimitReject {
+		return nil, fmt.Errorf("invalid SESSION_LIMIT_POLICY %q: must be %s or %s", sessionLimitPolicy, SessionLimitEvictOldest, SessionLimitReject)
+	}
+
+	// Parse MFA challenge lifetime
+	mfaChallengeTTL, err := time.ParseDuration(getEnv("MFA_CHALLENGE_TTL", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MFA_CHALLENGE_TTL format: %v", err)
+	}
+
+	// Parse WebAuthn ceremony lifetime
+	webAuthnSessionTTL, err := time.ParseDuration(getEnv("WEBAUTHN_SESSION_TTL", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBAUTHN_SESSION_TTL format: %v", err)
+	}
+
+	// Parse password reset durations
+	passwordResetTTL, err := time.ParseDuration(getEnv("PASSWORD_RESET_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_TTL format: %v", err)
+	}
+	passwordResetRateWindow, err := time.ParseDuration(getEnv("PASSWORD_RESET_RATE_WINDOW", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_RATE_WINDOW format: %v", err)
+	}
+
+	// Parse magic link durations
+	magicLinkTTL, err := time.ParseDuration(getEnv("MAGIC_LINK_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAGIC_LINK_TTL format: %v", err)
+	}
+	magicLinkRateWindow, err := time.ParseDuration(getEnv("MAGIC_LINK_RATE_WINDOW", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAGIC_LINK_RATE_WINDOW format: %v", err)
+	}
+
+	// Parse invitation lifetime
+	invitationTTL, err := time.ParseDuration(getEnv("INVITATION_TTL", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INVITATION_TTL format: %v", err)
+	}
+
+	// Parse time-bound grant durations
+	grantSweepInterval, err := time.ParseDuration(getEnv("GRANT_SWEEP_INTERVAL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRANT_SWEEP_INTERVAL format: %v", err)
+	}
+	grantExpiringWindow, err := time.ParseDuration(getEnv("GRANT_EXPIRING_WINDOW", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRANT_EXPIRING_WINDOW format: %v", err)
+	}
+
+	// Parse email verification durations
+	emailVerificationTTL, err := time.ParseDuration(getEnv("EMAIL_VERIFICATION_TTL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_TTL format: %v", err)
+	}
+	emailVerificationRateWindow, err := time.ParseDuration(getEnv("EMAIL_VERIFICATION_RATE_WINDOW", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_RATE_WINDOW format: %v", err)
+	}
+
+	// Parse account lockout durations
+	lockoutBaseDuration, err := time.ParseDuration(getEnv("LOCKOUT_BASE_DURATION", "1m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOCKOUT_BASE_DURATION format: %v", err)
+	}
+	lockoutMaxDuration, err := time.ParseDuration(getEnv("LOCKOUT_MAX_DURATION", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOCKOUT_MAX_DURATION format: %v", err)
+	}
+	lockoutResetAfter, err := time.ParseDuration(getEnv("LOCKOUT_RESET_AFTER", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOCKOUT_RESET_AFTER format: %v", err)
+	}
+
+	// Parse password max age
+	passwordMaxAge, err := time.ParseDuration(getEnv("PASSWORD_MAX_AGE", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_MAX_AGE format: %v", err)
+	}
+
+	// Parse API key lifetime
+	apiKeyMaxLifetime, err := time.ParseDuration(getEnv("API_KEY_MAX_LIFETIME", "8760h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_KEY_MAX_LIFETIME format: %v", err)
+	}
+
+	// Parse impersonation token lifetime
+	impersonationTTL, err := time.ParseDuration(getEnv("IMPERSONATION_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMPERSONATION_TTL format: %v", err)
+	}
+
+	// Parse OIDC providers
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	oidcStateTTL, err := time.ParseDuration(getEnv("OIDC_STATE_TTL", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC_STATE_TTL format: %v", err)
+	}
+
+	// Parse OAuth2 authorization server durations
+	oauthAccessTokenTTL, err := time.ParseDuration(getEnv("OAUTH_ACCESS_TOKEN_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAUTH_ACCESS_TOKEN_TTL format: %v", err)
+	}
+
+	oauthRefreshTokenTTL, err := time.ParseDuration(getEnv("OAUTH_REFRESH_TOKEN_TTL", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAUTH_REFRESH_TOKEN_TTL format: %v", err)
+	}
+
+	oauthCodeTTL, err := time.ParseDuration(getEnv("OAUTH_CODE_TTL", "1m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAUTH_CODE_TTL format: %v", err)
+	}
+
 	// Parse rate limit window duration
 	rateLimitWindow, err := time.ParseDuration(getEnv("RATE_LIMIT_WINDOW", "1h"))
 	if err != nil {
@@ -86,6 +371,107 @@ func Load() (*Config, error) {
 		JWTExpiry:          jwtExpiry,
 		RefreshTokenExpiry: refreshTokenExpiry,
 
+		JWTSigningAlg:       getEnv("JWT_SIGNING_ALG", "HS256"),
+		JWTPrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTRetiringKeyFiles: getEnvList("JWT_RETIRING_KEY_FILES", ""),
+
+		JWTAcceptLegacyHS256Until: jwtAcceptLegacyHS256Until,
+
+		RefreshTokenReuseGrace: refreshTokenReuseGrace,
+		SessionIdleTimeout:     sessionIdleTimeout,
+		SessionMaxLifetime:     sessionMaxLifetime,
+		MaxSessionsPerUser:     getEnvInt("MAX_SESSIONS_PER_USER", 0),
+		SessionLimitPolicy:     sessionLimitPolicy,
+		ReauthMaxAge:           reauthMaxAge,
+		NotifyOnTokenReuse:     getEnv("NOTIFY_ON_TOKEN_REUSE", "true") == "true",
+
+		AuthTokenSources: getEnvList("AUTH_TOKEN_SOURCES", "cookie,header"),
+
+		// MFA config
+		MFAIssuer:       getEnv("MFA_ISSUER", "SaaS Kit"),
+		MFAChallengeTTL: mfaChallengeTTL,
+
+		// WebAuthn (passkey) config
+		WebAuthnRPID:          getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPDisplayName: getEnv("WEBAUTHN_RP_DISPLAY_NAME", "SaaS Kit"),
+		WebAuthnRPOrigins:     getEnvList("WEBAUTHN_RP_ORIGINS", "http://localhost:3000"),
+		WebAuthnSessionTTL:    webAuthnSessionTTL,
+
+		// Mail config
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailFileDir:  getEnv("MAIL_FILE_DIR", "./tmp/mail"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		AppBaseURL: getEnv("APP_BASE_URL", "http://localhost:3000"),
+		APIBaseURL: getEnv("API_BASE_URL", "http://localhost:8080"),
+
+		// Password reset config
+		PasswordResetTTL:        passwordResetTTL,
+		PasswordResetRateLimit:  getEnvInt("PASSWORD_RESET_RATE_LIMIT", 5),
+		PasswordResetRateWindow: passwordResetRateWindow,
+
+		// Magic link config
+		MagicLinkEnabled:        getEnv("MAGIC_LINK_ENABLED", "false") == "true",
+		MagicLinkTTL:            magicLinkTTL,
+		MagicLinkRateLimit:      getEnvInt("MAGIC_LINK_RATE_LIMIT", 5),
+		MagicLinkEmailRateLimit: getEnvInt("MAGIC_LINK_EMAIL_RATE_LIMIT", 3),
+		MagicLinkRateWindow:     magicLinkRateWindow,
+
+		// Email verification config
+		RequireEmailVerification:    getEnv("REQUIRE_EMAIL_VERIFICATION", "true") == "true",
+		EmailVerificationTTL:        emailVerificationTTL,
+		EmailVerificationRateLimit:  getEnvInt("EMAIL_VERIFICATION_RATE_LIMIT", 5),
+		EmailVerificationRateWindow: emailVerificationRateWindow,
+
+		// Account lockout config
+		LockoutThreshold:    getEnvInt("LOCKOUT_THRESHOLD", 5),
+		LockoutBaseDuration: lockoutBaseDuration,
+		LockoutMaxDuration:  lockoutMaxDuration,
+		LockoutResetAfter:   lockoutResetAfter,
+
+		// Password policy config
+		PasswordMinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        getEnvInt("PASSWORD_MAX_LENGTH", 72),
+		PasswordRequireUpper:     getEnv("PASSWORD_REQUIRE_UPPER", "true") == "true",
+		PasswordRequireLower:     getEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+		PasswordRequireDigit:     getEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+		PasswordRequireSymbol:    getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+		PasswordDisallowUserInfo: getEnv("PASSWORD_DISALLOW_USER_INFO", "true") == "true",
+		PasswordDenylistFile:     getEnv("PASSWORD_DENYLIST_FILE", ""),
+		PasswordHistorySize:      getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		PasswordMaxAge:           passwordMaxAge,
+
+		// External OpenID Connect providers
+		OIDCProviders: oidcProviders,
+		OIDCStateTTL:  oidcStateTTL,
+
+		// OAuth2 authorization server config
+		OAuthAccessTokenTTL:  oauthAccessTokenTTL,
+		OAuthRefreshTokenTTL: oauthRefreshTokenTTL,
+		OAuthCodeTTL:         oauthCodeTTL,
+
+		// Impersonation config
+		ImpersonationTTL: impersonationTTL,
+
+		// API key config
+		APIKeyMaxLifetime: apiKeyMaxLifetime,
+		APIKeyMaxPerUser:  getEnvInt("API_KEY_MAX_PER_USER", 10),
+
+		// Multi-tenancy config
+		DefaultOrganization: getEnv("DEFAULT_ORGANIZATION", "default"),
+		TenantBaseDomain:    strings.ToLower(getEnv("TENANT_BASE_DOMAIN", "")),
+
+		// Organization invitation config
+		InvitationTTL: invitationTTL,
+
+		// Time-bound permission grants
+		GrantSweepInterval:  grantSweepInterval,
+		GrantExpiringWindow: grantExpiringWindow,
+
 		// CORS config
 		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000"),
 
@@ -108,6 +494,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList gets a comma-separated environment variable as a slice or returns a default value
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvInt gets an environment variable as int or returns a default value
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -121,14 +518,39 @@ func getEnvInt(key string, defaultValue int) int {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	switch c.JWTSigningAlg {
+	case "HS256":
+		if c.JWTSecret == "" {
+			return fmt.Errorf("JWT_SECRET is required")
+		}
+	case "RS256", "EdDSA":
+		if c.JWTPrivateKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s signing", c.JWTSigningAlg)
+		}
+		if !c.JWTAcceptLegacyHS256Until.IsZero() && c.JWTSecret == "" {
+			return fmt.Errorf("JWT_SECRET is required to accept legacy HS256 tokens")
+		}
+	default:
+		return fmt.Errorf("invalid JWT_SIGNING_ALG %q, expected HS256, RS256 or EdDSA", c.JWTSigningAlg)
 	}
 
 	if c.DBPassword == "" {
 		return fmt.Errorf("DB_PASSWORD is required")
 	}
 
+	if len(c.AuthTokenSources) == 0 {
+		return fmt.Errorf("AUTH_TOKEN_SOURCES must list at least one source")
+	}
+	for _, source := range c.AuthTokenSources {
+		if source != "cookie" && source != "header" {
+			return fmt.Errorf("invalid AUTH_TOKEN_SOURCES entry %q, expected cookie or header", source)
+		}
+	}
+
+	if c.PasswordMinLength < 1 || c.PasswordMaxLength < c.PasswordMinLength {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH must be at least PASSWORD_MIN_LENGTH, which must be at least 1")
+	}
+
 	return nil
 }
 
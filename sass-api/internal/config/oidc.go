@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OIDCProviderConfig configures an external OpenID Connect identity provider
+type OIDCProviderConfig struct {
+	Name         string // Used in URLs, e.g. /api/auth/oidc/{name}/login
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Claim mapping to Users fields
+	UsernameClaim string
+	NameClaim     string
+	EmailClaim    string
+	GroupsClaim   string
+
+	// RoleMappings assigns a role from the groups claim; the first matching group wins
+	RoleMappings []OIDCRoleMapping
+
+	// AllowSignup creates an account on first login when no user has the verified email
+	AllowSignup bool
+}
+
+// OIDCRoleMapping maps an IdP group to a role name
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
+}
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS. Each provider is
+// configured with OIDC_<NAME>_* variables, e.g. OIDC_OKTA_ISSUER.
+func loadOIDCProviders() ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvList("OIDC_PROVIDERS", "") {
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		provider := OIDCProviderConfig{
+			Name:          strings.ToLower(name),
+			DisplayName:   getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:        getEnv(prefix+"ISSUER", ""),
+			ClientID:      getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:  getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:        getEnvList(prefix+"SCOPES", "openid,email,profile"),
+			UsernameClaim: getEnv(prefix+"USERNAME_CLAIM", "preferred_username"),
+			NameClaim:     getEnv(prefix+"NAME_CLAIM", "name"),
+			EmailClaim:    getEnv(prefix+"EMAIL_CLAIM", "email"),
+			GroupsClaim:   getEnv(prefix+"GROUPS_CLAIM", "groups"),
+			AllowSignup:   getEnv(prefix+"ALLOW_SIGNUP", "false") == "true",
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+
+		// Mappings are written as group=role, comma separated
+		for _, mapping := range getEnvList(prefix+"ROLE_MAPPING", "") {
+			group, role, ok := strings.Cut(mapping, "=")
+			if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(role) == "" {
+				return nil, fmt.Errorf("invalid %sROLE_MAPPING entry %q, expected group=role", prefix, mapping)
+			}
+			provider.RoleMappings = append(provider.RoleMappings, OIDCRoleMapping{
+				Group: strings.TrimSpace(group),
+				Role:  strings.TrimSpace(role),
+			})
+		}
+
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
@@ -39,18 +39,61 @@ var RoutePermissions = map[string]RoutePermission{
 	"PUT:/api/menu/":    {MenuPath: "/menus-management", Permission: PermissionUpdate},
 	"DELETE:/api/menu/": {MenuPath: "/menus-management", Permission: PermissionDelete},
 
+	// Organization invitations (inherit users-management permissions)
+	"GET:/api/invitations":    {MenuPath: "/users-management", Permission: PermissionRead},
+	"POST:/api/invitation/":   {MenuPath: "/users-management", Permission: PermissionWrite},
+	"DELETE:/api/invitation/": {MenuPath: "/users-management", Permission: PermissionDelete},
+
 	// Rights access (inherits users-management permissions)
 	"GET:/api/rights-access/":    {MenuPath: "/users-management", Permission: PermissionRead},
 	"POST:/api/rights-access":    {MenuPath: "/users-management", Permission: PermissionWrite},
 	"DELETE:/api/rights-access/": {MenuPath: "/users-management", Permission: PermissionDelete},
+
+	// OAuth client registration (inherits users-management permissions)
+	"GET:/api/oauth/clients":     {MenuPath: "/users-management", Permission: PermissionRead},
+	"POST:/api/oauth/clients":    {MenuPath: "/users-management", Permission: PermissionWrite},
+	"DELETE:/api/oauth/clients/": {MenuPath: "/users-management", Permission: PermissionDelete},
+}
+
+// APIKeyBlockedPrefixes contains path prefixes that can't be reached with an API key,
+// so a leaked key can't be used to take over the owner's credentials
+var APIKeyBlockedPrefixes = []string{
+	"/api/auth/",
+	"/api/oauth/",
+	"/api/user/impersonate/",
+}
+
+// ImpersonatorRoles contains the roles allowed to act as other users. Users with these
+// roles can't be impersonated themselves.
+var ImpersonatorRoles = []string{"root", "admin"}
+
+// CrossTenantRoles contains the roles that act across organizations. They see every
+// organization's data unless they pick one with the X-Organization header or a subdomain.
+var CrossTenantRoles = []string{"root"}
+
+// ImpersonationBlockedPrefixes contains path prefixes that can't be reached while
+// impersonating: credential and account security changes, and impersonating further
+var ImpersonationBlockedPrefixes = []string{
+	"/api/auth/change-email",
+	"/api/auth/mfa",
+	"/api/auth/webauthn",
+	"/api/auth/api-keys",
+	"/api/auth/revoke-",
+	"/api/auth/sessions",
+	"/api/auth/reauthenticate",
+	"/api/user/reset-password/",
+	"/api/user/impersonate/",
+	"/api/oauth/",
 }
 
 // WhitelistedRoutes contains routes that bypass permission checks
 // These routes are accessible to any authenticated user
 var WhitelistedRoutes = map[string]bool{
-	"GET:/api/me":           true,
-	"POST:/api/auth/logout": true,
-	"GET:/api/menus/user":   true,
-	"GET:/api/menus/tree":   true,
-	"GET:/api/roles/active": true,
+	"GET:/api/me":                       true,
+	"POST:/api/auth/logout":             true,
+	"POST:/api/auth/impersonation/stop": true,
+	"POST:/api/auth/reauthenticate":     true,
+	"GET:/api/menus/user":               true,
+	"GET:/api/menus/tree":               true,
+	"GET:/api/roles/active":             true,
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/tenant"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -33,6 +34,11 @@ func NewConnection(cfg *config.Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
+	// Scope queries to the organization in the statement context
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tenant plugin: %v", err)
+	}
+
 	return &DB{db}, nil
 }
 
@@ -47,7 +53,7 @@ func (db *DB) Initialize(cfg *config.Config) error {
 	log.Println("Auto-migration is enabled")
 
 	// Run auto-migrations
-	if err := RunMigrations(db.DB); err != nil {
+	if err := RunMigrations(db.DB, cfg); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -4,15 +4,31 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
 	"gorm.io/gorm"
 )
 
 // RunMigrations runs all database migrations in the correct order
 // Models are migrated in dependency order to ensure foreign keys are created properly
-func RunMigrations(db *gorm.DB) error {
+func RunMigrations(db *gorm.DB, cfg *config.Config) error {
 	log.Println("Running database migrations...")
 
+	// Step 0: Organizations come first, everything else can belong to one
+	log.Println("Step 0: Migrating Organization table...")
+	if err := db.AutoMigrate(&models.Organization{}); err != nil {
+		return fmt.Errorf("failed to migrate Organization: %w", err)
+	}
+	defaultOrganization, err := seedDefaultOrganization(db, cfg.DefaultOrganization)
+	if err != nil {
+		return fmt.Errorf("failed to seed default organization: %w", err)
+	}
+
+	// Step 0b: Role names become unique per organization instead of globally
+	if err := dropGlobalRoleNameIndex(db); err != nil {
+		return fmt.Errorf("failed to drop role name index: %w", err)
+	}
+
 	// Step 1: Migrate independent tables first (no FK dependencies)
 	log.Println("Step 1: Migrating Role and Menu tables...")
 	if err := db.AutoMigrate(&models.Role{}, &models.Menu{}); err != nil {
@@ -33,31 +49,90 @@ func RunMigrations(db *gorm.DB) error {
 
 	// Step 4: Now migrate Users table with FK constraint
 	log.Println("Step 4: Migrating Users table with foreign key...")
+	addingEmailVerification := !db.Migrator().HasColumn(&models.Users{}, "email_verified_at")
+	addingUserOrganizations := !db.Migrator().HasColumn(&models.Users{}, "organization_id")
 	if err := db.AutoMigrate(&models.Users{}); err != nil {
 		return fmt.Errorf("failed to migrate Users: %w", err)
 	}
 
+	// Step 4a: Users created before organizations existed join the default one
+	if addingUserOrganizations {
+		if err := backfillUserOrganizations(db, defaultOrganization.ID); err != nil {
+			log.Printf("Warning: Failed to assign existing users to the default organization: %v", err)
+		}
+	}
+
+	// Step 4b: Users created before email verification existed keep access
+	if addingEmailVerification {
+		if err := backfillEmailVerified(db); err != nil {
+			log.Printf("Warning: Failed to mark existing users as verified: %v", err)
+		}
+	}
+
 	// Step 5: Migrate pivot/relationship tables
 	log.Println("Step 5: Migrating relationship tables...")
 	relationshipModels := []interface{}{
 		&models.RoleMenu{},
 		&models.UserMenu{},
 		&models.RightsAccess{},
+		&models.UserRole{},
 	}
 	if err := db.AutoMigrate(relationshipModels...); err != nil {
 		return fmt.Errorf("failed to migrate relationship tables: %w", err)
 	}
 
-	// Step 6: Migrate RefreshToken and AuditLog tables
-	log.Println("Step 6: Migrating RefreshToken and AuditLog tables...")
+	// Step 5a: Every user holds their primary role through user_roles too
+	if err := backfillUserRoles(db); err != nil {
+		return fmt.Errorf("failed to backfill user roles: %w", err)
+	}
+
+	// Step 5b: Replace plaintext refresh tokens with hashes before the new columns are enforced
+	if err := hashRefreshTokens(db); err != nil {
+		return fmt.Errorf("failed to hash refresh tokens: %w", err)
+	}
+
+	// Step 6: Migrate RefreshToken, AuditLog and other auth security tables
+	log.Println("Step 6: Migrating RefreshToken, AuditLog, MFA, WebAuthn and PasswordResetToken tables...")
+	addingAuditOrganizations := db.Migrator().HasTable(&models.AuditLog{}) && !db.Migrator().HasColumn(&models.AuditLog{}, "organization_id")
 	securityModels := []interface{}{
 		&models.RefreshToken{},
+		&models.Session{},
 		&models.AuditLog{},
+		&models.MFARecoveryCode{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnSession{},
+		&models.PasswordResetToken{},
+		&models.MagicLinkToken{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.ExternalIdentity{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthToken{},
+		&models.OAuthConsent{},
+		&models.Invitation{},
 	}
 	if err := db.AutoMigrate(securityModels...); err != nil {
 		return fmt.Errorf("failed to migrate security tables: %w", err)
 	}
 
+	// Step 6a: Existing audit logs belong to the organization of the user who acted
+	if addingAuditOrganizations {
+		if err := backfillAuditLogOrganizations(db); err != nil {
+			log.Printf("Warning: Failed to assign existing audit logs to organizations: %v", err)
+		}
+	}
+
+	// Step 6b: Assign token families to refresh tokens issued before family tracking
+	if err := backfillRefreshTokenFamilies(db); err != nil {
+		log.Printf("Warning: Failed to backfill refresh token families: %v", err)
+	}
+
+	// Step 6c: Create sessions for refresh tokens issued before session tracking
+	if err := backfillSessions(db); err != nil {
+		log.Printf("Warning: Failed to backfill sessions: %v", err)
+	}
+
 	// Step 7: Seed Audit Logs menu
 	log.Println("Step 7: Seeding Audit Logs menu...")
 	if err := seedAuditLogsMenu(db); err != nil {
@@ -68,6 +143,97 @@ func RunMigrations(db *gorm.DB) error {
 	return nil
 }
 
+// seedDefaultOrganization creates the organization self-registered users join
+func seedDefaultOrganization(db *gorm.DB, slug string) (*models.Organization, error) {
+	var organization models.Organization
+	err := db.Where("slug = ?", slug).First(&organization).Error
+	if err == nil {
+		return &organization, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	organization = models.Organization{Name: "Default", Slug: slug, IsActive: true}
+	if err := db.Create(&organization).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("Created default organization: %s (ID: %d)", organization.Slug, organization.ID)
+
+	return &organization, nil
+}
+
+// backfillUserRoles adds the user_roles row for each user's primary role where it is
+// missing, e.g. for users created before users could hold several roles
+func backfillUserRoles(db *gorm.DB) error {
+	result := db.Exec(`
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		SELECT u.id, u.role_id, NOW()
+		FROM users u
+		WHERE NOT EXISTS (
+			SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role_id = u.role_id
+		)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Added primary roles of %d users to user_roles", result.RowsAffected)
+	}
+	return nil
+}
+
+// dropGlobalRoleNameIndex drops the old unique constraint on roles.name, so two
+// organizations can each have a role with the same name
+func dropGlobalRoleNameIndex(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Role{}) {
+		return nil
+	}
+
+	statements := []string{
+		"ALTER TABLE roles DROP CONSTRAINT IF EXISTS uni_roles_name",
+		"DROP INDEX IF EXISTS idx_roles_name",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillUserOrganizations assigns every existing user except cross-tenant ones to the
+// default organization. It only runs in the migration that adds the column.
+func backfillUserOrganizations(db *gorm.DB, organizationID uint) error {
+	result := db.Exec(`UPDATE users SET organization_id = ?
+		WHERE organization_id IS NULL AND role_id NOT IN (SELECT id FROM roles WHERE name IN ?)`,
+		organizationID, config.CrossTenantRoles)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Assigned %d existing users to the default organization", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// backfillAuditLogOrganizations copies the acting user's organization onto audit logs
+// written before organizations existed
+func backfillAuditLogOrganizations(db *gorm.DB) error {
+	result := db.Exec(`UPDATE audit_logs SET organization_id = users.organization_id
+		FROM users WHERE audit_logs.user_id = users.id AND audit_logs.organization_id IS NULL`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Assigned organizations to %d existing audit logs", result.RowsAffected)
+	}
+
+	return nil
+}
+
 // updateExistingUsersRole updates users with invalid role_id to default role
 func updateExistingUsersRole(db *gorm.DB) error {
 	// Get default role
@@ -91,6 +257,82 @@ func updateExistingUsersRole(db *gorm.DB) error {
 	return nil
 }
 
+// backfillEmailVerified marks all existing users as verified. It only runs in the
+// migration that adds the email_verified_at column.
+func backfillEmailVerified(db *gorm.DB) error {
+	result := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL")
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Marked %d existing users as email verified", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// backfillRefreshTokenFamilies gives every legacy refresh token its own family so
+// reuse detection can revoke descendants of tokens rotated from now on
+func backfillRefreshTokenFamilies(db *gorm.DB) error {
+	result := db.Exec("UPDATE refresh_tokens SET family_id = CAST(id AS TEXT) WHERE family_id IS NULL OR family_id = ''")
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Assigned token families to %d existing refresh tokens", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// hashRefreshTokens moves refresh tokens stored in plaintext to a lookup prefix and a
+// SHA-256 hash, then drops the plaintext column. Existing sessions keep working.
+func hashRefreshTokens(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.RefreshToken{}) || !migrator.HasColumn(&models.RefreshToken{}, "token") {
+		return nil
+	}
+
+	log.Println("Hashing stored refresh tokens...")
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_prefix VARCHAR(12)",
+			"ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64)",
+			"UPDATE refresh_tokens SET token_prefix = LEFT(token, 12), token_hash = ENCODE(SHA256(CONVERT_TO(token, 'UTF8')), 'hex')",
+			"ALTER TABLE refresh_tokens DROP COLUMN token",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillSessions creates a session for every active token family that has none, using
+// the device details of its newest refresh token
+func backfillSessions(db *gorm.DB) error {
+	result := db.Exec(`INSERT INTO sessions (id, user_id, ip_address, user_agent, last_seen_at, created_at, updated_at)
+		SELECT DISTINCT ON (family_id) family_id, user_id, ip_address, user_agent, created_at,
+			MIN(created_at) OVER (PARTITION BY family_id), created_at
+		FROM refresh_tokens
+		WHERE is_revoked = false AND deleted_at IS NULL
+			AND family_id NOT IN (SELECT id FROM sessions)
+		ORDER BY family_id, created_at DESC`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Created sessions for %d existing token families", result.RowsAffected)
+	}
+
+	return nil
+}
+
 // seedDefaultRoles creates default roles if they don't exist
 func seedDefaultRoles(db *gorm.DB) error {
 	defaultRoles := []models.Role{
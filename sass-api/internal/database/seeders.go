@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Aebroyx/sass-api/internal/domain/models"
 	"golang.org/x/crypto/bcrypt"
@@ -137,6 +138,17 @@ func SeedDefaultRoleMenus(db *gorm.DB) error {
 		log.Printf("Found role: %s (ID: %d)", roleName, role.ID)
 	}
 
+	// Admin inherits from user, so it only needs rows for the menus it adds
+	if admin, ok := roleMap["admin"]; ok && admin.ParentID == nil {
+		if user, ok := roleMap["user"]; ok {
+			if err := db.Model(&admin).Update("parent_id", user.ID).Error; err != nil {
+				log.Printf("Warning: Failed to make admin inherit from user: %v", err)
+			} else {
+				log.Printf("Admin role now inherits from user")
+			}
+		}
+	}
+
 	// Step 2: Get all menus
 	var menus []models.Menu
 	if err := db.Order("order_index ASC").Find(&menus).Error; err != nil {
@@ -153,6 +165,10 @@ func SeedDefaultRoleMenus(db *gorm.DB) error {
 		CanDelete  bool
 	}
 
+	userMenus := func(m models.Menu) bool {
+		return m.Name == "Dashboard" // Dashboard only
+	}
+
 	rolePermissions := map[string]RolePermissions{
 		"root": {
 			MenuFilter: func(m models.Menu) bool { return true }, // All menus
@@ -162,16 +178,14 @@ func SeedDefaultRoleMenus(db *gorm.DB) error {
 			CanDelete:  true,
 		},
 		"admin": {
-			MenuFilter: func(m models.Menu) bool { return true }, // All menus
+			MenuFilter: func(m models.Menu) bool { return !userMenus(m) }, // All menus, inheriting the user's
 			CanRead:    true,
 			CanWrite:   true,
 			CanUpdate:  true,
 			CanDelete:  true,
 		},
 		"user": {
-			MenuFilter: func(m models.Menu) bool {
-				return m.Name == "Dashboard" // Dashboard only
-			},
+			MenuFilter: userMenus,
 			CanRead:    true,
 			CanWrite:   false,
 			CanUpdate:  false,
@@ -259,18 +273,24 @@ func SeedDefaultRootUser(db *gorm.DB) error {
 	}
 
 	// Step 4: Create root user
+	now := time.Now()
 	rootUser := models.Users{
-		Username: "root",
-		Email:    "root@localhost",
-		Password: string(hashedPassword),
-		Name:     "Root User",
-		RoleID:   rootRole.ID,
+		Username:        "root",
+		Email:           "root@localhost",
+		Password:        string(hashedPassword),
+		Name:            "Root User",
+		RoleID:          rootRole.ID,
+		EmailVerifiedAt: &now,
 	}
 
 	if err := db.Create(&rootUser).Error; err != nil {
 		return fmt.Errorf("failed to create root user: %w", err)
 	}
 
+	if err := db.Create(&models.UserRole{UserID: rootUser.ID, RoleID: rootRole.ID}).Error; err != nil {
+		return fmt.Errorf("failed to assign root role: %w", err)
+	}
+
 	log.Printf("Created default root user (ID: %d, Username: root, Password: P@ssw0rd)", rootUser.ID)
 	log.Printf("Root user will inherit full permissions from 'root' role via role_menus table")
 
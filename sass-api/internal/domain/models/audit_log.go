@@ -19,6 +19,14 @@ type AuditLog struct {
 	IPAddress      string         `json:"ip_address" gorm:"size:45;index"` // IPv6 support
 	UserAgent      string         `json:"user_agent" gorm:"size:500"`
 	CorrelationID  string         `json:"correlation_id" gorm:"size:100;index"`
+
+	// Real actor when an admin was impersonating UserID
+	ImpersonatorID       *uint  `json:"impersonator_id,omitempty" gorm:"index"`
+	ImpersonatorUsername string `json:"impersonator_username,omitempty" gorm:"size:50"`
+
+	// Organization the action happened in; nil for platform actions
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
 	Timestamp      time.Time      `json:"timestamp" gorm:"not null;index"`
 	CreatedAt      time.Time      `json:"created_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
@@ -36,6 +44,7 @@ type AuditLogQueryParams struct {
 	ResourceID    string    `form:"resource_id"`
 	IPAddress     string    `form:"ip_address"`
 	CorrelationID string    `form:"correlation_id"`
+	ImpersonatorID *uint    `form:"impersonator_id"`
 	StartDate     time.Time `form:"start_date"`
 	EndDate       time.Time `form:"end_date"`
 	Page          int       `form:"page"`
@@ -58,6 +67,11 @@ type AuditLogResponse struct {
 	UserAgent     string     `json:"user_agent"`
 	CorrelationID string     `json:"correlation_id"`
 	Timestamp     time.Time  `json:"timestamp"`
+
+	ImpersonatorID       *uint  `json:"impersonator_id,omitempty"`
+	ImpersonatorUsername string `json:"impersonator_username,omitempty"`
+
+	OrganizationID *uint `json:"organization_id,omitempty"`
 }
 
 // CreateAuditLogRequest represents request data for creating an audit log
@@ -72,4 +86,9 @@ type CreateAuditLogRequest struct {
 	IPAddress     string `json:"ip_address"`
 	UserAgent     string `json:"user_agent"`
 	CorrelationID string `json:"correlation_id"`
+
+	ImpersonatorID       *uint  `json:"impersonator_id,omitempty"`
+	ImpersonatorUsername string `json:"impersonator_username,omitempty"`
+
+	OrganizationID *uint `json:"organization_id,omitempty"`
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ExternalIdentity links a user to an account at an external OpenID Connect provider
+type ExternalIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Provider    string     `json:"provider" gorm:"not null;size:50;uniqueIndex:idx_external_identity_subject"`
+	Subject     string     `json:"subject" gorm:"not null;size:255;uniqueIndex:idx_external_identity_subject"`
+	Email       string     `json:"email" gorm:"size:255"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	User Users `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// OIDCStateClaims is kept in a signed cookie while the user is at the provider, so
+// the callback can be tied to the browser that started the login
+type OIDCStateClaims struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"` // PKCE
+	RedirectPath string `json:"redirect_path"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProviderResponse describes a provider users can sign in with
+type OIDCProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
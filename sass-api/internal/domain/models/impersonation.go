@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ImpersonateRequest represents the request to act as another user
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=255"` // Recorded in the audit log
+}
+
+// ImpersonationResponse carries the short-lived access token for an impersonation session
+type ImpersonationResponse struct {
+	User        RegisterResponse `json:"user"`
+	AccessToken string           `json:"access_token"`
+	TokenType   string           `json:"token_type"`
+	ExpiresIn   int64            `json:"expires_in"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+}
+
+// ImpersonatorResponse identifies the admin behind an impersonation session
+type ImpersonatorResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
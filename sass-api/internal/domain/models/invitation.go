@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation invites an email address to join an organization with a role chosen up
+// front. The token sent by email is stored hashed.
+type Invitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID *uint      `json:"organization_id" gorm:"index"`
+	Email          string     `json:"email" gorm:"not null;size:255;index"`
+	RoleID         uint       `json:"role_id" gorm:"not null"`
+	InvitedByID    uint       `json:"invited_by_id" gorm:"not null"`
+	TokenHash      string     `json:"-" gorm:"unique;not null;size:64"`
+	Status         string     `json:"status" gorm:"not null;size:20;default:pending;index"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	SentAt         time.Time  `json:"sent_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uint      `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Role      Role  `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	InvitedBy Users `json:"-" gorm:"foreignKey:InvitedByID"`
+}
+
+// CreateInvitationRequest represents the request payload for inviting someone to the organization
+type CreateInvitationRequest struct {
+	Email  string `json:"email" validate:"required,email,max=255"`
+	RoleID uint   `json:"role_id" validate:"required"`
+}
+
+// InvitationTokenRequest identifies an invitation by the token from its email
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AcceptInvitationRequest represents the request to accept an invitation. Username, name
+// and password are only used when the invited email has no account yet.
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username" validate:"omitempty,min=3,max=50"`
+	Name     string `json:"name" validate:"omitempty,max=100"`
+	Password string `json:"password"`
+}
+
+// InvitationDetailsResponse describes an invitation to the person who received it
+type InvitationDetailsResponse struct {
+	Email            string    `json:"email"`
+	OrganizationName string    `json:"organization_name"`
+	RoleName         string    `json:"role_name"`
+	InvitedBy        string    `json:"invited_by"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	AccountExists    bool      `json:"account_exists"` // Accepting links the existing account instead of creating one
+}
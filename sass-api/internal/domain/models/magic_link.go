@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MagicLinkToken is a single-use passwordless login link (stored hashed). It only works
+// in the browser that requested it, which holds the matching nonce cookie.
+type MagicLinkToken struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash   string     `json:"-" gorm:"unique;not null;size:64"`
+	BrowserHash string     `json:"-" gorm:"not null;size:64"` // SHA-256 of the requesting browser's nonce cookie
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+	IPAddress   string     `json:"ip_address" gorm:"size:45"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relationships
+	User Users `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// MagicLinkRequest represents the request to email a magic login link
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MagicLinkVerifyRequest represents the request to sign in with the token from a magic link
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" validate:"required"`
+}
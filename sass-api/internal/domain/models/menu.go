@@ -19,6 +19,9 @@ type Menu struct {
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Owning organization; nil for the platform menus every organization sees
+	OrganizationID *uint `json:"organization_id" gorm:"index"`
+
 	// Self-referential relationship for hierarchy
 	Parent   *Menu  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
 	Children []Menu `json:"children,omitempty" gorm:"foreignKey:ParentID"`
@@ -29,6 +32,9 @@ type Menu struct {
 	RightsAccess []RightsAccess `json:"rights_access,omitempty" gorm:"foreignKey:MenuID"`
 }
 
+// SharedAcrossTenants makes menus without an organization visible to every organization
+func (Menu) SharedAcrossTenants() {}
+
 // CreateMenuRequest represents the request payload for creating a menu
 type CreateMenuRequest struct {
 	Name       string `json:"name" validate:"required,min=1,max=100"`
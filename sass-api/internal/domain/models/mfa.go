@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MFARecoveryCode represents a single-use MFA recovery code (stored hashed)
+type MFARecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:64;index"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	User Users `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// MFAChallengeClaims represents the claims of the short-lived token issued between
+// the password step and the MFA step of login
+type MFAChallengeClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// MFAChallengeResponse is returned by login when a second factor is required
+type MFAChallengeResponse struct {
+	MFAToken           string `json:"mfa_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // Role requires MFA but the user has not enrolled yet
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// MFAVerifyRequest represents the second step of an MFA login
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAChallengeEnrollRequest starts enrolment for a user whose role requires MFA during login
+type MFAChallengeEnrollRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+// MFAEnrollmentResponse contains the data needed to add the account to an authenticator app
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG data URI of the otpauth URL
+}
+
+// MFACodeRequest represents a request carrying a single MFA code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFADisableRequest represents a request to turn off MFA
+type MFADisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFARecoveryCodesResponse returns freshly generated recovery codes (shown once)
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse represents the MFA state of the current user
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RequiredByRole         bool       `json:"required_by_role"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuth client types
+const (
+	OAuthClientConfidential = "confidential" // Can keep a secret, e.g. a server-side app
+	OAuthClientPublic       = "public"       // Can't keep a secret, e.g. a SPA or mobile app; must use PKCE
+)
+
+// OAuth grant types
+const (
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantRefreshToken      = "refresh_token"
+	OAuthGrantClientCredentials = "client_credentials"
+)
+
+// OAuth token types stored in OAuthToken
+const (
+	OAuthTokenAccess  = "access"
+	OAuthTokenRefresh = "refresh"
+)
+
+// OAuthClient is an application that uses this API as its identity provider
+type OAuthClient struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ClientID     string    `json:"client_id" gorm:"unique;not null;size:64"`
+	SecretHash   string    `json:"-" gorm:"size:64"` // Empty for public clients
+	Name         string    `json:"name" gorm:"not null;size:100"`
+	Type         string    `json:"type" gorm:"not null;size:20"`
+	RedirectURIs string    `json:"-" gorm:"type:text"`          // Space separated, matched exactly
+	Scopes       string    `json:"-" gorm:"type:text;not null"` // Space separated scopes the client may request
+	GrantTypes   string    `json:"-" gorm:"not null;size:100"`  // Space separated
+	IsActive     bool      `json:"is_active" gorm:"default:true"`
+	CreatedBy    uint      `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// OrganizationID is the organization that registered the client, nil for platform clients
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+}
+
+// OAuthAuthorizationCode is a single-use code issued after the user consents
+type OAuthAuthorizationCode struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CodeHash      string     `json:"-" gorm:"unique;not null;size:64"`
+	ClientID      string     `json:"client_id" gorm:"not null;size:64;index"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	RedirectURI   string     `json:"redirect_uri" gorm:"type:text;not null"`
+	Scope         string     `json:"scope" gorm:"type:text"`
+	Nonce         string     `json:"-" gorm:"size:255"`
+	CodeChallenge string     `json:"-" gorm:"size:128"` // PKCE S256 challenge
+	FamilyID      string     `json:"-" gorm:"size:36"`  // Tokens issued for the code, revoked if the code is replayed
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// OAuthToken tracks an issued OAuth access or refresh token so it can be introspected
+// and revoked. Tokens issued from the same grant share a family.
+type OAuthToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TokenHash string     `json:"-" gorm:"unique;not null;size:64"` // SHA-256 of the refresh token, or of the access token's jti
+	TokenType string     `json:"token_type" gorm:"not null;size:10"`
+	ClientID  string     `json:"client_id" gorm:"not null;size:64;index"`
+	UserID    *uint      `json:"user_id,omitempty" gorm:"index"` // Nil for client credentials tokens
+	Scope     string     `json:"scope" gorm:"type:text"`
+	FamilyID  string     `json:"-" gorm:"not null;size:36;index"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// OAuthConsent remembers the scopes a user has granted a client
+type OAuthConsent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_oauth_consent"`
+	ClientID  string    `json:"client_id" gorm:"not null;size:64;uniqueIndex:idx_oauth_consent"`
+	Scope     string    `json:"scope" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthAccessClaims are the claims of an OAuth access token. The audience is the
+// client, so these tokens are not accepted by this API's own auth middleware.
+type OAuthAccessClaims struct {
+	Scope    string `json:"scope"`
+	ClientID string `json:"client_id"`
+	jwt.RegisteredClaims
+}
+
+// CreateOAuthClientRequest represents the request to register an OAuth client
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	Type         string   `json:"type" validate:"required,oneof=confidential public"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,required,url"`
+	Scopes       []string `json:"scopes" validate:"required,min=1,dive,required"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials"`
+}
+
+// OAuthClientResponse represents an OAuth client without its secret
+type OAuthClientResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	OrganizationID *uint `json:"organization_id,omitempty"`
+}
+
+// CreateOAuthClientResponse includes the client secret, which is only ever returned once
+type CreateOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OAuthAuthorizeRequest holds the authorization request parameters, read from the
+// query string for consent data and from the JSON body when the user decides
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	Approve             bool   `json:"approve" form:"-"`
+}
+
+// OAuthScopeDescription describes a requested scope on the consent screen
+type OAuthScopeDescription struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthConsentResponse is the data the frontend needs to render the consent screen
+type OAuthConsentResponse struct {
+	ClientID        string                  `json:"client_id"`
+	ClientName      string                  `json:"client_name"`
+	Scopes          []OAuthScopeDescription `json:"scopes"`
+	ConsentRequired bool                    `json:"consent_required"` // False when the user already granted these scopes
+}
+
+// OAuthAuthorizeResponse tells the frontend where to send the browser after the decision
+type OAuthAuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse is the token endpoint response (RFC 6749 section 5.1)
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthIntrospectionResponse is the introspection endpoint response (RFC 7662)
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// OIDCDiscoveryDocument is served at /.well-known/openid-configuration
+type OIDCDiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
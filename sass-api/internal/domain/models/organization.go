@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization is a tenant. Users, roles, menus and audit logs that belong to an
+// organization are only visible inside it; rows without one belong to the platform.
+type Organization struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null;size:100"`
+	Slug      string         `json:"slug" gorm:"unique;not null;size:63"` // Used as the subdomain and in the X-Organization header
+	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CreateOrganizationRequest represents the request payload for creating an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	Slug string `json:"slug" validate:"required,min=2,max=63"`
+}
+
+// UpdateOrganizationRequest represents the request payload for updating an organization
+type UpdateOrganizationRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Slug     string `json:"slug" validate:"required,min=2,max=63"`
+	IsActive *bool  `json:"is_active" validate:"required"`
+}
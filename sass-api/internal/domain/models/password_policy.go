@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// PasswordHistory stores previous password hashes so recently used passwords can't be reused
+type PasswordHistory struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;index"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+
+	// Relationships
+	User Users `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// PasswordPolicyViolation describes a single password rule that was not met
+type PasswordPolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyResponse describes the active password rules so clients can render a checklist
+type PasswordPolicyResponse struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DisallowUserInfo bool `json:"disallow_user_info"`
+	DenylistEnabled  bool `json:"denylist_enabled"`
+	HistorySize      int  `json:"history_size"`
+	MaxAgeDays       int  `json:"max_age_days"`
+}
+
+// ChangeExpiredPasswordRequest represents the request to replace an expired password at login
+type ChangeExpiredPasswordRequest struct {
+	Username        string `json:"username" validate:"required"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
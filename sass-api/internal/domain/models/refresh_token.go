@@ -9,7 +9,9 @@ import (
 // RefreshToken represents a refresh token in the database
 type RefreshToken struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
-	Token        string         `json:"token" gorm:"unique;not null;size:255;index"`
+	Token        string         `json:"-" gorm:"-"`                           // Plaintext secret, only set when issued
+	TokenPrefix  string         `json:"-" gorm:"unique;not null;size:12"`     // Leading characters of the token, used for lookup
+	TokenHash    string         `json:"-" gorm:"not null;size:64"`            // SHA-256 of the full token
 	UserID       uint           `json:"user_id" gorm:"not null;index"`
 	ExpiresAt    time.Time      `json:"expires_at" gorm:"not null;index"`
 	IsRevoked    bool           `json:"is_revoked" gorm:"default:false;index"`
@@ -20,6 +22,7 @@ type RefreshToken struct {
 	IPAddress    string         `json:"ip_address" gorm:"size:45"` // IPv6 support
 	UserAgent    string         `json:"user_agent" gorm:"size:500"`
 	ReplacedBy   *uint          `json:"replaced_by,omitempty"` // Token ID that replaced this one during rotation
+	FamilyID     string         `json:"family_id" gorm:"size:36;index"` // Shared by every token in one rotation chain
 
 	// Relationships
 	User Users `json:"user" gorm:"foreignKey:UserID"`
@@ -19,6 +19,10 @@ type RightsAccess struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Optional validity window; outside it the override doesn't apply
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorm:"index"` // Removed by the grant sweeper once passed
+
 	// Relationships
 	User Users `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Menu Menu  `json:"menu,omitempty" gorm:"foreignKey:MenuID"`
@@ -32,6 +36,9 @@ type CreateRightsAccessRequest struct {
 	CanWrite  *bool `json:"can_write"`
 	CanUpdate *bool `json:"can_update"`
 	CanDelete *bool `json:"can_delete"`
+
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
 }
 
 // UpdateRightsAccessRequest represents the request to update permission overrides
@@ -40,6 +47,9 @@ type UpdateRightsAccessRequest struct {
 	CanWrite  *bool `json:"can_write"`
 	CanUpdate *bool `json:"can_update"`
 	CanDelete *bool `json:"can_delete"`
+
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
 }
 
 // BulkUserRightsAccessRequest represents the request to bulk update user rights
@@ -54,6 +64,9 @@ type UserMenuPermission struct {
 	CanWrite  *bool `json:"can_write"`
 	CanUpdate *bool `json:"can_update"`
 	CanDelete *bool `json:"can_delete"`
+
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
 }
 
 // RightsAccessResponse represents the response for rights access
@@ -68,4 +81,18 @@ type RightsAccessResponse struct {
 	Menu      MenuResponse `json:"menu,omitempty"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
+
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// ExpiringGrantResponse describes a time-bound grant, either a permission override or a
+// direct menu assignment, that is about to expire
+type ExpiringGrantResponse struct {
+	Type       string       `json:"type"` // "rights_access" or "user_menu"
+	ID         uint         `json:"id"`
+	UserID     uint         `json:"user_id"`
+	Username   string       `json:"username"`
+	Menu       MenuResponse `json:"menu"`
+	ValidUntil time.Time    `json:"valid_until"`
 }
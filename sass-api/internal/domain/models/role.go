@@ -9,26 +9,56 @@ import (
 // Role represents a user role in the system
 type Role struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"unique;not null;size:50"`
+	Name        string         `json:"name" gorm:"not null;size:50;uniqueIndex:idx_roles_organization_name,priority:2"`
 	DisplayName string         `json:"display_name" gorm:"not null;size:100"`
 	Description string         `json:"description" gorm:"size:255"`
 	IsDefault   bool           `json:"is_default" gorm:"default:false"`
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	RequireMFA  bool           `json:"require_mfa" gorm:"default:false"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Session lifetime overrides in minutes; 0 uses the global setting
+	SessionIdleTimeoutMinutes int `json:"session_idle_timeout_minutes" gorm:"default:0"`
+	SessionMaxLifetimeMinutes int `json:"session_max_lifetime_minutes" gorm:"default:0"`
+
+	// Concurrent session cap; 0 uses the global setting
+	MaxSessions int `json:"max_sessions" gorm:"default:0"`
+
+	// Stops users with this role from signing in with magic links
+	MagicLinkDisabled bool `json:"magic_link_disabled" gorm:"default:false"`
+
+	// Owning organization; nil for the built-in roles every organization can assign
+	OrganizationID *uint `json:"organization_id" gorm:"uniqueIndex:idx_roles_organization_name,priority:1"`
+
+	// Role whose menu permissions this role inherits and adds to
+	ParentID *uint `json:"parent_id" gorm:"index"`
+
 	// Relationships
+	Parent    *Role      `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
 	Users     []Users    `json:"users,omitempty" gorm:"foreignKey:RoleID"`
 	RoleMenus []RoleMenu `json:"role_menus,omitempty" gorm:"foreignKey:RoleID"`
 }
 
+// SharedAcrossTenants makes roles without an organization visible to every organization
+func (Role) SharedAcrossTenants() {}
+
 // CreateRoleRequest represents the request payload for creating a role
 type CreateRoleRequest struct {
 	Name        string `json:"name" validate:"required,min=2,max=50"`
 	DisplayName string `json:"display_name" validate:"required,min=2,max=100"`
 	Description string `json:"description" validate:"max=255"`
 	IsDefault   bool   `json:"is_default"`
+	RequireMFA  bool   `json:"require_mfa"`
+
+	SessionIdleTimeoutMinutes int `json:"session_idle_timeout_minutes" validate:"min=0"`
+	SessionMaxLifetimeMinutes int `json:"session_max_lifetime_minutes" validate:"min=0"`
+	MaxSessions               int `json:"max_sessions" validate:"min=0"`
+
+	MagicLinkDisabled bool `json:"magic_link_disabled"`
+
+	ParentID *uint `json:"parent_id" validate:"omitempty,min=1"`
 }
 
 // UpdateRoleRequest represents the request payload for updating a role
@@ -38,6 +68,15 @@ type UpdateRoleRequest struct {
 	Description string `json:"description" validate:"max=255"`
 	IsDefault   bool   `json:"is_default"`
 	IsActive    bool   `json:"is_active"`
+	RequireMFA  bool   `json:"require_mfa"`
+
+	SessionIdleTimeoutMinutes int `json:"session_idle_timeout_minutes" validate:"min=0"`
+	SessionMaxLifetimeMinutes int `json:"session_max_lifetime_minutes" validate:"min=0"`
+	MaxSessions               int `json:"max_sessions" validate:"min=0"`
+
+	MagicLinkDisabled bool `json:"magic_link_disabled"`
+
+	ParentID *uint `json:"parent_id" validate:"omitempty,min=1"` // Nil stops inheriting
 }
 
 // RoleResponse represents the response payload for role data
@@ -48,6 +87,17 @@ type RoleResponse struct {
 	Description string    `json:"description"`
 	IsDefault   bool      `json:"is_default"`
 	IsActive    bool      `json:"is_active"`
+	RequireMFA  bool      `json:"require_mfa"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	SessionIdleTimeoutMinutes int `json:"session_idle_timeout_minutes"`
+	SessionMaxLifetimeMinutes int `json:"session_max_lifetime_minutes"`
+	MaxSessions               int `json:"max_sessions"`
+
+	MagicLinkDisabled bool `json:"magic_link_disabled"`
+
+	OrganizationID *uint `json:"organization_id"` // Nil for shared roles, which organizations can't edit
+
+	ParentID *uint `json:"parent_id,omitempty"`
 }
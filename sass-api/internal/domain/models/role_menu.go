@@ -51,3 +51,20 @@ type RoleMenuResponse struct {
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
 }
+
+// RoleEffectivePermissionResponse describes what a role can do on a menu once its
+// inherited permissions are included
+type RoleEffectivePermissionResponse struct {
+	Menu        MenuResponse         `json:"menu"`
+	Permissions EffectivePermissions `json:"permissions"`
+	Grants      []PermissionGrant    `json:"grants"`
+}
+
+// PermissionGrant names the role in the hierarchy that grants a permission. When several
+// do, the nearest one is reported.
+type PermissionGrant struct {
+	Permission string `json:"permission"` // read, write, update or delete
+	RoleID     uint   `json:"role_id"`
+	RoleName   string `json:"role_name"`
+	Inherited  bool   `json:"inherited"` // Granted by an ancestor rather than the role itself
+}
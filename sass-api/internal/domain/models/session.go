@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Session is a signed-in device. Its ID is the family ID shared by the refresh tokens
+// rotated from one login, so sessions can be listed and revoked without exposing
+// the token secret. A session is active while its family has a live refresh token.
+type Session struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:36"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	IPAddress  string    `json:"ip_address" gorm:"size:45"`
+	UserAgent  string    `json:"user_agent" gorm:"size:500"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"` // Updated whenever the refresh token is rotated
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// AuthenticatedAt is when the user last signed in or re-authenticated in this session
+	AuthenticatedAt *time.Time `json:"authenticated_at,omitempty"`
+}
+
+// ReauthenticateRequest confirms the user's identity with their password or an MFA code
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// ReauthenticateResponse carries an access token stamped with a fresh auth_time
+type ReauthenticateResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresIn   int64     `json:"expires_in"`
+	AuthTime    time.Time `json:"auth_time"`
+}
+
+// SessionResponse represents an active session with device details parsed from its user agent
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // The session making the request
+}
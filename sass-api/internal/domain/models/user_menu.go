@@ -15,6 +15,10 @@ type UserMenu struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Optional validity window; outside it the menu isn't assigned
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorm:"index"` // Removed by the grant sweeper once passed
+
 	// Relationships
 	User Users `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Menu Menu  `json:"menu,omitempty" gorm:"foreignKey:MenuID"`
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// UserRole represents the pivot table of the roles a user holds. Users.RoleID is the
+// primary role and always has a row here too; menu permissions are the union of all of
+// the user's roles.
+type UserRole struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_role"`
+	RoleID    uint      `json:"role_id" gorm:"not null;uniqueIndex:idx_user_role;index"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Role Role `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+}
+
+// Roles returns every role the user holds, primary role first. Role and UserRoles.Role
+// must be preloaded.
+func (u Users) Roles() []Role {
+	roles := []Role{u.Role}
+	for _, userRole := range u.UserRoles {
+		if userRole.RoleID != u.RoleID {
+			roles = append(roles, userRole.Role)
+		}
+	}
+	return roles
+}
+
+// RoleIDs returns the IDs of every role the user holds, primary role first
+func (u Users) RoleIDs() []uint {
+	ids := []uint{u.RoleID}
+	for _, userRole := range u.UserRoles {
+		if userRole.RoleID != u.RoleID {
+			ids = append(ids, userRole.RoleID)
+		}
+	}
+	return ids
+}
+
+// RoleNames returns the names of every role the user holds, primary role first
+func (u Users) RoleNames() []string {
+	roles := u.Roles()
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
+// HasAnyRole reports whether any of the user's roles is one of names
+func (u Users) HasAnyRole(names []string) bool {
+	for _, name := range u.RoleNames() {
+		if slices.Contains(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// The security policy of a user holding several roles is the strictest of them. Like
+// Roles, these helpers need Role and UserRoles.Role preloaded.
+
+// RequiresMFA reports whether any of the user's roles requires multi-factor authentication
+func (u Users) RequiresMFA() bool {
+	return slices.ContainsFunc(u.Roles(), func(role Role) bool { return role.RequireMFA })
+}
+
+// MagicLinkDisabled reports whether any of the user's roles disables magic link login
+func (u Users) MagicLinkDisabled() bool {
+	return slices.ContainsFunc(u.Roles(), func(role Role) bool { return role.MagicLinkDisabled })
+}
+
+// SessionIdleTimeout returns the shortest idle timeout set by the user's roles, or zero
+// if none sets one
+func (u Users) SessionIdleTimeout() time.Duration {
+	return time.Duration(u.smallestRoleLimit(func(role Role) int { return role.SessionIdleTimeoutMinutes })) * time.Minute
+}
+
+// SessionMaxLifetime returns the shortest absolute session lifetime set by the user's
+// roles, or zero if none sets one
+func (u Users) SessionMaxLifetime() time.Duration {
+	return time.Duration(u.smallestRoleLimit(func(role Role) int { return role.SessionMaxLifetimeMinutes })) * time.Minute
+}
+
+// MaxSessions returns the lowest concurrent session cap set by the user's roles, or zero
+// if none sets one
+func (u Users) MaxSessions() int {
+	return u.smallestRoleLimit(func(role Role) int { return role.MaxSessions })
+}
+
+// smallestRoleLimit returns the smallest non-zero limit of the user's roles, zero meaning
+// that no role sets one
+func (u Users) smallestRoleLimit(limit func(Role) int) int {
+	smallest := 0
+	for _, role := range u.Roles() {
+		if value := limit(role); value > 0 && (smallest == 0 || value < smallest) {
+			smallest = value
+		}
+	}
+	return smallest
+}
@@ -13,15 +13,39 @@ type Users struct {
 	Email     string         `json:"email" gorm:"unique;not null;size:255"`
 	Password  string         `json:"-" gorm:"not null"`
 	Name      string         `json:"name" gorm:"not null;size:100"`
-	RoleID    uint           `json:"role_id" gorm:"not null;default:2"`
+	RoleID    uint           `json:"role_id" gorm:"not null;default:2"` // Primary role; UserRoles holds every role the user has
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	IsDeleted bool           `json:"is_deleted" gorm:"default:false"`
 	IsActive  bool           `json:"is_active"`
 
+	// Multi-factor authentication
+	MFAEnabled     bool       `json:"mfa_enabled" gorm:"default:false"`
+	MFAEnabledAt   *time.Time `json:"mfa_enabled_at,omitempty"`
+	MFASecret      string     `json:"-" gorm:"size:255"`  // Pending until enrolment is confirmed
+	MFALastCounter int64      `json:"-" gorm:"default:0"` // Last accepted TOTP time step, prevents code replay
+
+	// Email verification
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	PendingEmail    string     `json:"pending_email,omitempty" gorm:"size:255"` // New address awaiting verification
+
+	// Account lockout
+	FailedLoginAttempts int        `json:"failed_login_attempts" gorm:"default:0"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at,omitempty"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	LockoutCount        int        `json:"-" gorm:"default:0"` // Consecutive lockouts, drives the exponential backoff
+
+	// Password policy
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"` // Falls back to CreatedAt when unset
+
+	// Tenancy; only platform users such as root have no organization
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
 	// Relationships
 	Role         Role           `json:"role" gorm:"foreignKey:RoleID"`
+	UserRoles    []UserRole     `json:"user_roles,omitempty" gorm:"foreignKey:UserID"`
+	Organization *Organization  `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
 	UserMenus    []UserMenu     `json:"user_menus,omitempty" gorm:"foreignKey:UserID"`
 	RightsAccess []RightsAccess `json:"rights_access,omitempty" gorm:"foreignKey:UserID"`
 }
@@ -30,7 +54,7 @@ type Users struct {
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email,max=255"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required,max=100"`
 }
 
@@ -42,6 +66,16 @@ type RegisterResponse struct {
 	Name     string       `json:"name"`
 	Role     RoleResponse `json:"role"`
 	IsActive bool         `json:"is_active"`
+
+	// Every role the user holds, primary role first
+	Roles []RoleResponse `json:"roles"`
+
+	EmailVerified bool `json:"email_verified"`
+
+	OrganizationID *uint `json:"organization_id,omitempty"`
+
+	// Set when an admin is acting as this user
+	ImpersonatedBy *ImpersonatorResponse `json:"impersonated_by,omitempty"`
 }
 
 // LoginRequest represents the login request payload
@@ -56,12 +90,18 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
+
+	// Seconds until the refresh token expires, when the session's idle timeout or lifetime ends
+	RefreshExpiresIn int64 `json:"refresh_expires_in"`
 }
 
 // LoginResponse represents the login response payload
+// When MFA is set, the password step succeeded but no tokens were issued yet
 type LoginResponse struct {
-	User  RegisterResponse `json:"user"`
-	Token TokenResponse    `json:"token"`
+	User          RegisterResponse      `json:"user"`
+	Token         TokenResponse         `json:"token"`
+	MFA           *MFAChallengeResponse `json:"mfa,omitempty"`
+	RecoveryCodes []string              `json:"recovery_codes,omitempty"` // Only set when enrolment completes during login
 }
 
 // Claims represents the JWT claims
@@ -71,6 +111,26 @@ type Claims struct {
 	Email    string `json:"email"`
 	RoleID   uint   `json:"role_id"`
 	RoleName string `json:"role_name"`
+
+	// Every role the user holds, primary role first. Permissions are the union of them.
+	RoleIDs   []uint   `json:"role_ids,omitempty"`
+	RoleNames []string `json:"role_names,omitempty"`
+
+	// The organization the user belongs to; absent for platform users and on tokens
+	// issued before organizations existed
+	OrganizationID uint `json:"org_id,omitempty"`
+
+	// The session the token was issued for; empty on impersonation tokens
+	SessionID string `json:"sid,omitempty"`
+
+	// When the user last proved who they are in this session, by signing in or
+	// re-authenticating. Sensitive operations require it to be recent.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+
+	// Set on impersonation tokens, identifying the admin acting as UserID
+	ImpersonatorID       uint   `json:"impersonator_id,omitempty"`
+	ImpersonatorUsername string `json:"impersonator_username,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
@@ -78,21 +138,23 @@ type Claims struct {
 type CreateUserRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email,max=255"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required,max=100"`
 	RoleID   uint   `json:"role_id" validate:"required,min=1"`
+	RoleIDs  []uint `json:"role_ids" validate:"omitempty,dive,min=1"` // Additional roles
 	IsActive *bool  `json:"is_active" validate:"required"`
 }
 
 // CreateUserResponse represents the response payload for creating a user
 type CreateUserResponse struct {
-	ID        uint         `json:"id"`
-	Username  string       `json:"username"`
-	Email     string       `json:"email"`
-	Name      string       `json:"name"`
-	Role      RoleResponse `json:"role"`
-	IsActive  bool         `json:"is_active"`
-	CreatedAt time.Time    `json:"created_at"`
+	ID        uint           `json:"id"`
+	Username  string         `json:"username"`
+	Email     string         `json:"email"`
+	Name      string         `json:"name"`
+	Role      RoleResponse   `json:"role"`
+	Roles     []RoleResponse `json:"roles"`
+	IsActive  bool           `json:"is_active"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 type UpdateUserRequest struct {
@@ -100,12 +162,38 @@ type UpdateUserRequest struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Name     string `json:"name" validate:"required,max=100"`
 	RoleID   uint   `json:"role_id" validate:"required,min=1"`
-	Password string `json:"password,omitempty" validate:"omitempty,min=6"`
+	RoleIDs  []uint `json:"role_ids" validate:"omitempty,dive,min=1"` // Additional roles; omit to keep the current ones
+	Password string `json:"password,omitempty"`
 	IsActive *bool  `json:"is_active" validate:"required"`
 }
 
 type ResetUserPasswordRequest struct {
-	CurrentPassword string `json:"current_password" validate:"required,min=6"`
-	NewPassword     string `json:"new_password" validate:"required,min=6"`
-	ConfirmPassword string `json:"confirm_password" validate:"required,min=6"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+// EmailVerificationClaims represents the claims of a signed email verification link.
+// The address is part of the claims so links for a replaced address stop working.
+type EmailVerificationClaims struct {
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// VerifyEmailRequest represents the request to confirm an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest represents the request to send a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ChangeEmailRequest represents the request to change the authenticated user's email
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
 }
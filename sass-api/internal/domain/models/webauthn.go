@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebAuthnCredential represents a passkey registered by a user
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	Name            string     `json:"name" gorm:"not null;size:100"`
+	CredentialID    string     `json:"-" gorm:"unique;not null;size:255"` // base64url encoded credential ID
+	PublicKey       []byte     `json:"-" gorm:"not null"`                 // COSE encoded public key
+	AttestationType string     `json:"-" gorm:"size:32"`
+	Transports      string     `json:"transports" gorm:"size:255"` // Comma-separated authenticator transports
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-" gorm:"default:0"`
+	CloneWarning    bool       `json:"clone_warning" gorm:"default:false"`
+	BackupEligible  bool       `json:"backup_eligible" gorm:"default:false"`
+	BackupState     bool       `json:"backup_state" gorm:"default:false"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relationships
+	User Users `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// WebAuthnSession stores the server side state of a registration or login ceremony.
+// Each session can be finished exactly once.
+type WebAuthnSession struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:36"`
+	Purpose   string    `json:"purpose" gorm:"not null;size:20"`
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"` // Empty for discoverable logins
+	Data      string    `json:"-" gorm:"type:text;not null"`    // JSON encoded webauthn.SessionData
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebAuthnBeginResponse returns the options to pass to navigator.credentials and the ceremony session ID
+type WebAuthnBeginResponse struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+}
+
+// WebAuthnFinishRequest carries the authenticator response of a ceremony
+type WebAuthnFinishRequest struct {
+	SessionID  string          `json:"session_id" validate:"required"`
+	Name       string          `json:"name" validate:"max=100"` // Only used for registration
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// WebAuthnBeginLoginRequest represents the request to start a passkey login.
+// Username is optional; without it the browser offers any discoverable passkey.
+type WebAuthnBeginLoginRequest struct {
+	Username string `json:"username"`
+}
+
+// WebAuthnCredentialResponse represents a passkey in the user's profile
+type WebAuthnCredentialResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Transports  []string   `json:"transports"`
+	BackupState bool       `json:"backup_state"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
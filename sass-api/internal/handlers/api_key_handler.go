@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+	auditService  *services.AuditService
+	validate      *validator.Validate
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService, auditService *services.AuditService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		auditService:  auditService,
+		validate:      validator.New(),
+	}
+}
+
+// ListKeys returns the authenticated user's API keys
+// GET /api/auth/api-keys
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "API keys retrieved successfully", gin.H{
+		"api_keys": keys,
+		"total":    len(keys),
+	})
+}
+
+// CreateKey creates an API key; the full key is only returned in this response
+// POST /api/auth/api-keys
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "API_KEY_CREATED", "api_key", strconv.FormatUint(uint64(key.ID), 10), nil, key.APIKeyResponse)
+
+	common.SendSuccess(c, http.StatusCreated, "API key created successfully, copy it now as it won't be shown again", key)
+}
+
+// RevokeKey deletes one of the authenticated user's API keys
+// DELETE /api/auth/api-keys/:id
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid API key ID", common.CodeBadRequest, nil)
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(userID, uint(keyID)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "API_KEY_REVOKED", "api_key", c.Param("id"), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "API key revoked successfully", nil)
+}
+
+// handleError maps API key service errors to HTTP responses
+func (h *APIKeyHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+	case "api key not found":
+		common.SendError(c, http.StatusNotFound, "API key not found", common.CodeNotFound, nil)
+	case "api key limit reached":
+		common.SendError(c, http.StatusConflict, "Maximum number of API keys reached", common.CodeConflict, nil)
+	case "invalid scope":
+		common.SendError(c, http.StatusBadRequest, "Scopes must have the form permission:/menu-path", common.CodeValidationError, nil)
+	case "scope not permitted":
+		common.SendError(c, http.StatusForbidden, "API key scopes must be permissions you hold", common.CodeForbidden, nil)
+	case "expiry must be in the future", "expiry exceeds maximum lifetime":
+		common.SendError(c, http.StatusBadRequest, "Invalid API key expiry", common.CodeValidationError, err.Error())
+	default:
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+	}
+}
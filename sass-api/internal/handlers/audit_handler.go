@@ -63,7 +63,7 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 	}
 
 	// Get audit logs
-	result, err := h.auditService.GetAuditLogs(&params)
+	result, err := h.auditService.WithContext(c.Request.Context()).GetAuditLogs(&params)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to retrieve audit logs", common.CodeInternalError, err.Error())
 		return
@@ -98,7 +98,7 @@ func (h *AuditHandler) GetUserAuditLogs(c *gin.Context) {
 	}
 
 	// Get audit logs for user
-	result, err := h.auditService.GetUserAuditLogs(uint(userID), page, limit)
+	result, err := h.auditService.WithContext(c.Request.Context()).GetUserAuditLogs(uint(userID), page, limit)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to retrieve user audit logs", common.CodeInternalError, err.Error())
 		return
@@ -134,7 +134,7 @@ func (h *AuditHandler) GetResourceAuditLogs(c *gin.Context) {
 	}
 
 	// Get audit logs for resource
-	result, err := h.auditService.GetResourceAuditLogs(resourceType, resourceID, page, limit)
+	result, err := h.auditService.WithContext(c.Request.Context()).GetResourceAuditLogs(resourceType, resourceID, page, limit)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to retrieve resource audit logs", common.CodeInternalError, err.Error())
 		return
@@ -1,28 +1,37 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Aebroyx/sass-api/internal/common"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
 	"github.com/Aebroyx/sass-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
+// sessionLimitMessage is returned when a login is refused for exceeding the concurrent session cap
+const sessionLimitMessage = "You are signed in on too many devices, sign out of another session first"
+
 type AuthHandler struct {
-	userService  *services.UserService
-	auditService *services.AuditService
-	validate     *validator.Validate
+	userService              *services.UserService
+	auditService             *services.AuditService
+	emailVerificationService *services.EmailVerificationService
+	validate                 *validator.Validate
 }
 
-func NewAuthHandler(userService *services.UserService, auditService *services.AuditService) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, auditService *services.AuditService, emailVerificationService *services.EmailVerificationService) *AuthHandler {
 	return &AuthHandler{
-		userService:  userService,
-		auditService: auditService,
-		validate:     validator.New(),
+		userService:              userService,
+		auditService:             auditService,
+		emailVerificationService: emailVerificationService,
+		validate:                 validator.New(),
 	}
 }
 
@@ -44,6 +53,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Register user
 	user, err := h.userService.Register(&req)
 	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+
 		switch err.Error() {
 		case "username already exists":
 			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
@@ -55,6 +68,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// New accounts stay unverified until the emailed link is opened
+	if err := h.emailVerificationService.SendVerification(user.ID); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	// Return success response
 	c.JSON(http.StatusCreated, user)
 }
@@ -78,41 +96,43 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		// Log failed login attempt
 		go h.logLoginAttempt(c, req.Username, 0, false)
 
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			h.respondAccountLocked(c, lockedErr)
+			return
+		}
+
 		switch err.Error() {
 		case "invalid username or password":
 			common.SendError(c, http.StatusBadRequest, "Invalid username or password", common.CodeBadRequest, nil)
 		case "user is not active":
 			common.SendError(c, http.StatusForbidden, "User is not active", common.CodeForbidden, nil)
+		case "email not verified":
+			common.SendError(c, http.StatusForbidden, "Please verify your email address before logging in", common.CodeEmailNotVerified, nil)
+		case "password expired":
+			common.SendError(c, http.StatusForbidden, "Your password has expired, please choose a new one", common.CodePasswordExpired, nil)
+		case "session limit reached":
+			common.SendError(c, http.StatusConflict, sessionLimitMessage, common.CodeSessionLimit, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
 		}
 		return
 	}
 
+	// Password was correct but a second factor is still required
+	if response.MFA != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa":          response.MFA,
+			"user":         response.User,
+		})
+		return
+	}
+
 	// Log successful login
 	go h.logLoginAttempt(c, response.User.Username, response.User.ID, true)
 
-	// Set access token cookie
-	c.SetCookie(
-		"access_token",
-		response.Token.AccessToken,
-		int(response.Token.ExpiresIn),
-		"/",   // path
-		"",    // domain (empty for current domain)
-		false, // secure (set to false for development)
-		true,  // httpOnly
-	)
-
-	// Set refresh token cookie (7 days)
-	c.SetCookie(
-		"refresh_token",
-		response.Token.RefreshToken,
-		int(7*24*time.Hour.Seconds()), // 7 days
-		"/",                           // path
-		"",                            // domain (empty for current domain)
-		false,                         // secure (set to false for development)
-		true,                          // httpOnly
-	)
+	setLoginCookies(c, response.Token)
 
 	// Return user data only (tokens are in cookies)
 	c.JSON(http.StatusOK, gin.H{
@@ -120,6 +140,180 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// ChangeExpiredPassword lets a user whose password has expired choose a new one
+// POST /api/auth/change-expired-password
+func (h *AuthHandler) ChangeExpiredPassword(c *gin.Context) {
+	var req models.ChangeExpiredPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	user, err := h.userService.ChangeExpiredPassword(&req)
+	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			h.respondAccountLocked(c, lockedErr)
+			return
+		}
+
+		switch err.Error() {
+		case "invalid username or password":
+			common.SendError(c, http.StatusBadRequest, "Invalid username or password", common.CodeBadRequest, nil)
+		case "user is not active":
+			common.SendError(c, http.StatusForbidden, "User is not active", common.CodeForbidden, nil)
+		case "password not expired":
+			common.SendError(c, http.StatusBadRequest, "Password has not expired", common.CodeBadRequest, nil)
+		case "new password and confirm password do not match":
+			common.SendError(c, http.StatusBadRequest, "New password and confirm password do not match", common.CodeValidationError, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	if h.auditService != nil {
+		userID := user.ID
+		username := user.Username
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+		correlationID := c.GetString("correlation_id")
+
+		go func() {
+			_ = h.auditService.LogWithContext(&userID, username, "PASSWORD_EXPIRED_CHANGED", "user", strconv.FormatUint(uint64(userID), 10),
+				nil, nil, ipAddress, userAgent, correlationID)
+		}()
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Password changed successfully, please log in again", nil)
+}
+
+// VerifyMFA completes a login that was paused for a second factor
+// POST /api/auth/mfa/verify
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	response, err := h.userService.CompleteMFALogin(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			h.respondAccountLocked(c, lockedErr)
+			return
+		}
+
+		switch err.Error() {
+		case "invalid mfa token":
+			common.SendError(c, http.StatusUnauthorized, "MFA session is invalid or has expired", common.CodeUnauthorized, nil)
+		case "invalid mfa code":
+			go h.logLoginAttempt(c, "", 0, false)
+			common.SendError(c, http.StatusBadRequest, "Invalid MFA code", common.CodeInvalidMFACode, nil)
+		case "mfa enrollment required", "mfa enrollment not started":
+			common.SendError(c, http.StatusForbidden, "MFA enrollment required", common.CodeMFAEnrollmentRequired, nil)
+		case "user is not active":
+			common.SendError(c, http.StatusForbidden, "User is not active", common.CodeForbidden, nil)
+		case "session limit reached":
+			common.SendError(c, http.StatusConflict, sessionLimitMessage, common.CodeSessionLimit, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	if len(response.RecoveryCodes) > 0 {
+		go h.logMFAEvent(c, response.User.ID, response.User.Username, "MFA_ENROLLED")
+	}
+	go h.logLoginAttempt(c, response.User.Username, response.User.ID, true)
+
+	setLoginCookies(c, response.Token)
+
+	result := gin.H{"user": response.User}
+	if len(response.RecoveryCodes) > 0 {
+		result["recovery_codes"] = response.RecoveryCodes
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// BeginMFAChallengeEnrollment starts TOTP enrolment during a login that requires MFA
+// POST /api/auth/mfa/challenge/enroll
+func (h *AuthHandler) BeginMFAChallengeEnrollment(c *gin.Context) {
+	var req models.MFAChallengeEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	enrollment, err := h.userService.BeginMFAChallengeEnrollment(req.MFAToken)
+	if err != nil {
+		switch err.Error() {
+		case "invalid mfa token":
+			common.SendError(c, http.StatusUnauthorized, "MFA session is invalid or has expired", common.CodeUnauthorized, nil)
+		case "mfa already enabled":
+			common.SendError(c, http.StatusConflict, "MFA is already enabled", common.CodeConflict, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "MFA enrollment started", enrollment)
+}
+
+// respondAccountLocked tells the client how long the account stays locked and
+// audits the lockout when this attempt triggered it
+func (h *AuthHandler) respondAccountLocked(c *gin.Context, lockedErr *services.AccountLockedError) {
+	if lockedErr.NewlyLocked && h.auditService != nil {
+		userID := lockedErr.UserID
+		username := lockedErr.Username
+		lockedUntil := lockedErr.Until
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+		correlationID := c.GetString("correlation_id")
+
+		go func() {
+			_ = h.auditService.LogWithContext(&userID, username, "ACCOUNT_LOCKED", "user", strconv.FormatUint(uint64(userID), 10),
+				nil, gin.H{"locked_until": lockedUntil}, ipAddress, userAgent, correlationID)
+		}()
+	}
+
+	retryAfter := int(time.Until(lockedErr.Until).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	common.SendError(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts", common.CodeLocked, gin.H{
+		"locked_until":        lockedErr.Until,
+		"retry_after_seconds": retryAfter,
+	})
+}
+
+// setLoginCookies sets the access and refresh token cookies after a successful login
+func setLoginCookies(c *gin.Context, token models.TokenResponse) {
+	now := time.Now()
+	middleware.SetAuthCookies(c, token.AccessToken, token.RefreshToken,
+		now.Add(time.Duration(token.ExpiresIn)*time.Second),
+		now.Add(time.Duration(token.RefreshExpiresIn)*time.Second))
+}
+
 func (h *AuthHandler) Logout(c *gin.Context) {
 	// Get user info from context (set by auth middleware)
 	var userID uint
@@ -169,6 +363,83 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// Reauthenticate confirms the signed-in user's password or MFA code so they can perform
+// sensitive operations, and issues an access token stamped with the new auth_time
+// POST /api/auth/reauthenticate
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	response, err := h.userService.Reauthenticate(userID, c.GetString(middleware.SessionIDKey), &req)
+	if err != nil {
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			h.respondAccountLocked(c, lockedErr)
+			return
+		}
+
+		switch err.Error() {
+		case "invalid credentials":
+			middleware.AuditAction(c, h.auditService, "REAUTHENTICATION_FAILED", "user", strconv.FormatUint(uint64(userID), 10), nil, nil)
+			common.SendError(c, http.StatusBadRequest, "Invalid password or code", common.CodeBadRequest, nil)
+		case "password or mfa code required":
+			common.SendError(c, http.StatusBadRequest, "Enter your password or an MFA code", common.CodeValidationError, nil)
+		case "session not found", "user not found":
+			common.SendError(c, http.StatusUnauthorized, "Your session can't be re-authenticated, please sign in again", common.CodeUnauthorized, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "REAUTHENTICATED", "user", strconv.FormatUint(uint64(userID), 10), nil, nil)
+
+	c.SetCookie(
+		"access_token",
+		response.AccessToken,
+		int(response.ExpiresIn),
+		"/",   // path
+		"",    // domain (empty for current domain)
+		false, // secure (set to false for development)
+		true,  // httpOnly
+	)
+
+	common.SendSuccess(c, http.StatusOK, "Re-authenticated successfully", response)
+}
+
+// StopImpersonation ends an impersonation session. Clearing the access token cookie
+// lets the auth middleware sign the admin back in with their own refresh token.
+// POST /api/auth/impersonation/stop
+func (h *AuthHandler) StopImpersonation(c *gin.Context) {
+	impersonatorID, _ := middleware.GetImpersonator(c)
+	if impersonatorID == nil {
+		common.SendError(c, http.StatusBadRequest, "Not impersonating a user", common.CodeBadRequest, nil)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "IMPERSONATION_ENDED", "user", strconv.FormatUint(uint64(c.GetUint("user_id")), 10), nil, nil)
+
+	c.SetCookie(
+		"access_token",
+		"",
+		-1,    // MaxAge -1 means delete immediately
+		"/",   // path
+		"",    // domain (empty for current domain)
+		false, // secure (set to false for development)
+		true,  // httpOnly
+	)
+
+	common.SendSuccess(c, http.StatusOK, "Impersonation ended", nil)
+}
+
 func (h *AuthHandler) GetMe(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -216,6 +487,36 @@ func (h *AuthHandler) logLoginAttempt(c *gin.Context, username string, userID ui
 	_ = h.auditService.Log(req)
 }
 
+// logMFAEvent logs MFA enrolment changes for a user
+func (h *AuthHandler) logMFAEvent(c *gin.Context, userID uint, username string, action string) {
+	if h.auditService == nil {
+		return
+	}
+
+	correlationID := ""
+	if cid, exists := c.Get("correlation_id"); exists {
+		if cidStr, ok := cid.(string); ok {
+			correlationID = cidStr
+		}
+	}
+
+	impersonatorID, impersonatorUsername := middleware.GetImpersonator(c)
+	req := &models.CreateAuditLogRequest{
+		UserID:               &userID,
+		Username:             username,
+		Action:               action,
+		ResourceType:         "mfa",
+		ResourceID:           strconv.FormatUint(uint64(userID), 10),
+		IPAddress:            c.ClientIP(),
+		UserAgent:            c.Request.UserAgent(),
+		CorrelationID:        correlationID,
+		ImpersonatorID:       impersonatorID,
+		ImpersonatorUsername: impersonatorUsername,
+	}
+
+	_ = h.auditService.Log(req)
+}
+
 // logLogoutAction logs logout actions
 func (h *AuthHandler) logLogoutAction(c *gin.Context, userID uint, username string) {
 	if h.auditService == nil {
@@ -229,15 +530,18 @@ func (h *AuthHandler) logLogoutAction(c *gin.Context, userID uint, username stri
 		}
 	}
 
+	impersonatorID, impersonatorUsername := middleware.GetImpersonator(c)
 	req := &models.CreateAuditLogRequest{
-		UserID:        &userID,
-		Username:      username,
-		Action:        "LOGOUT",
-		ResourceType:  "auth",
-		ResourceID:    "",
-		IPAddress:     c.ClientIP(),
-		UserAgent:     c.Request.UserAgent(),
-		CorrelationID: correlationID,
+		UserID:               &userID,
+		Username:             username,
+		Action:               "LOGOUT",
+		ResourceType:         "auth",
+		ResourceID:           "",
+		IPAddress:            c.ClientIP(),
+		UserAgent:            c.Request.UserAgent(),
+		CorrelationID:        correlationID,
+		ImpersonatorID:       impersonatorID,
+		ImpersonatorUsername: impersonatorUsername,
 	}
 
 	_ = h.auditService.Log(req)
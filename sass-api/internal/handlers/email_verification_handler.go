@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type EmailVerificationHandler struct {
+	emailVerificationService *services.EmailVerificationService
+	validate                 *validator.Validate
+}
+
+func NewEmailVerificationHandler(emailVerificationService *services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+		validate:                 validator.New(),
+	}
+}
+
+// VerifyEmail confirms an email address using the token from the verification email
+// POST /api/auth/verify-email
+func (h *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	user, err := h.emailVerificationService.VerifyEmail(req.Token, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch err.Error() {
+		case "invalid verification token":
+			common.SendError(c, http.StatusBadRequest, "Verification link is invalid or has expired", common.CodeBadRequest, nil)
+		case "email already exists":
+			common.SendError(c, http.StatusConflict, "Email already exists", common.CodeEmailExists, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Email verified successfully", gin.H{
+		"email": user.Email,
+	})
+}
+
+// ResendVerification sends a new verification email
+// POST /api/auth/resend-verification
+func (h *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.emailVerificationService.ResendVerification(req.Email); err != nil {
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		return
+	}
+
+	// Same response whether or not the email is awaiting verification
+	common.SendSuccess(c, http.StatusOK, "If that email is awaiting verification, a new link has been sent", nil)
+}
+
+// ChangeEmail starts changing the authenticated user's email address
+// POST /api/auth/change-email
+func (h *EmailVerificationHandler) ChangeEmail(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.emailVerificationService.ChangeEmail(userID, &req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		switch err.Error() {
+		case "user not found":
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+		case "invalid password":
+			common.SendError(c, http.StatusUnauthorized, "Invalid password", common.CodeUnauthorized, nil)
+		case "email already exists":
+			common.SendError(c, http.StatusConflict, "Email already exists", common.CodeEmailExists, nil)
+		case "new email is the same as the current email":
+			common.SendError(c, http.StatusBadRequest, "New email is the same as the current email", common.CodeBadRequest, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "A verification link has been sent to the new email address", nil)
+}
+
+// bind decodes and validates the request body, writing the error response on failure
+func (h *EmailVerificationHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+	validate          *validator.Validate
+}
+
+func NewInvitationHandler(invitationService *services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		validate:          validator.New(),
+	}
+}
+
+// GetPendingInvitations handles GET /api/invitations
+func (h *InvitationHandler) GetPendingInvitations(c *gin.Context) {
+	var params pagination.QueryParams
+	if err := params.Bind(c); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid query parameters", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	response, err := h.invitationService.WithContext(c.Request.Context()).GetPendingInvitations(params)
+	if err != nil {
+		common.SendError(c, http.StatusInternalServerError, "Failed to fetch invitations", common.CodeInternalError, err.Error())
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Invitations fetched successfully", response)
+}
+
+// CreateInvitation handles POST /api/invitation/create
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	inviterID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	invitation, err := h.invitationService.WithContext(c.Request.Context()).CreateInvitation(inviterID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create invitation")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusCreated, "Invitation sent successfully", invitation)
+}
+
+// ResendInvitation handles POST /api/invitation/:id/resend
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid invitation ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	invitation, err := h.invitationService.WithContext(c.Request.Context()).ResendInvitation(uint(id))
+	if err != nil {
+		h.handleError(c, err, "Failed to resend invitation")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Invitation resent successfully", invitation)
+}
+
+// RevokeInvitation handles DELETE /api/invitation/:id
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid invitation ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	invitation, err := h.invitationService.WithContext(c.Request.Context()).RevokeInvitation(uint(id))
+	if err != nil {
+		h.handleError(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Invitation revoked successfully", invitation)
+}
+
+// GetInvitationDetails describes the invitation behind an emailed token
+// POST /api/auth/invitation/details
+func (h *InvitationHandler) GetInvitationDetails(c *gin.Context) {
+	var req models.InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	details, err := h.invitationService.GetInvitationDetails(req.Token)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch invitation")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Invitation fetched successfully", details)
+}
+
+// AcceptInvitation joins the organization with the invited role, creating the account
+// if the invited email has none. The user signs in afterwards as usual.
+// POST /api/auth/invitation/accept
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	user, err := h.invitationService.AcceptInvitation(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+		h.handleError(c, err, "Failed to accept invitation")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Invitation accepted successfully", user)
+}
+
+// handleError maps invitation service errors to responses
+func (h *InvitationHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch errMsg := err.Error(); errMsg {
+	case "invitation not found":
+		common.SendError(c, http.StatusNotFound, "Invitation not found", common.CodeNotFound, nil)
+	case "user is already a member":
+		common.SendError(c, http.StatusConflict, "User is already a member of this organization", common.CodeConflict, nil)
+	case "invitation already pending":
+		common.SendError(c, http.StatusConflict, "An invitation is already pending for this email", common.CodeConflict, nil)
+	case "username already exists":
+		common.SendError(c, http.StatusConflict, "Username already exists", common.CodeConflict, nil)
+	case "invalid role":
+		common.SendError(c, http.StatusBadRequest, "Invalid role", common.CodeValidationError, nil)
+	case "username, name and password are required":
+		common.SendError(c, http.StatusBadRequest, "Username, name and password are required to create your account", common.CodeValidationError, nil)
+	case "invalid or expired invitation",
+		"invitation is no longer pending",
+		"account belongs to another organization":
+		common.SendError(c, http.StatusBadRequest, errMsg, common.CodeBadRequest, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, fallback, common.CodeInternalError, errMsg)
+	}
+}
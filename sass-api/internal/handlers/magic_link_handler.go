@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// magicLinkNonceCookie ties a magic link to the browser that requested it
+	magicLinkNonceCookie = "magic_link_nonce"
+	magicLinkCookiePath  = "/api/auth/magic-link"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+	userService      *services.UserService
+	auditService     *services.AuditService
+	config           *config.Config
+	validate         *validator.Validate
+}
+
+func NewMagicLinkHandler(magicLinkService *services.MagicLinkService, userService *services.UserService, auditService *services.AuditService, cfg *config.Config) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		userService:      userService,
+		auditService:     auditService,
+		config:           cfg,
+		validate:         validator.New(),
+	}
+}
+
+// Request emails a single-use login link
+// POST /api/auth/magic-link
+func (h *MagicLinkHandler) Request(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	browserNonce, err := h.magicLinkService.RequestLink(req.Email, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if err.Error() == "too many magic link requests" {
+			c.Header("Retry-After", strconv.Itoa(int(h.config.MagicLinkRateWindow.Seconds())))
+			common.SendError(c, http.StatusTooManyRequests, "Too many requests, please try again later", "RATE_LIMIT_EXCEEDED", nil)
+			return
+		}
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		return
+	}
+
+	// Lax so the cookie is sent when the link from the email opens the frontend
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkNonceCookie, browserNonce, int(h.config.MagicLinkTTL.Seconds()), magicLinkCookiePath, "", false, true)
+
+	// Same response whether or not the email belongs to an account
+	common.SendSuccess(c, http.StatusOK, "If an account with that email exists, a sign-in link has been sent", nil)
+}
+
+// Verify signs in with the token from a magic link. The response matches Login: either
+// the user with cookies set, or an MFA challenge.
+// POST /api/auth/magic-link/verify
+func (h *MagicLinkHandler) Verify(c *gin.Context) {
+	var req models.MagicLinkVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	browserNonce, _ := c.Cookie(magicLinkNonceCookie)
+
+	user, err := h.magicLinkService.ConsumeLink(req.Token, browserNonce)
+	if err != nil {
+		middleware.LogLoginAction(c, h.auditService, 0, "", false)
+
+		switch err.Error() {
+		case "invalid or expired magic link":
+			common.SendError(c, http.StatusBadRequest, "Sign-in link is invalid or has expired", common.CodeBadRequest, nil)
+		case "magic link opened in another browser":
+			common.SendError(c, http.StatusBadRequest, "Open the sign-in link in the browser you requested it from", common.CodeBadRequest, nil)
+		case "magic link login disabled":
+			common.SendError(c, http.StatusForbidden, "Sign-in links are not available for this account", common.CodeForbidden, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	// The link is spent either way
+	c.SetCookie(magicLinkNonceCookie, "", -1, magicLinkCookiePath, "", false, true)
+
+	response, err := h.userService.LoginWithMagicLink(user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		middleware.LogLoginAction(c, h.auditService, user.ID, user.Username, false)
+
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			retryAfter := int(time.Until(lockedErr.Until).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			common.SendError(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts", common.CodeLocked, gin.H{
+				"locked_until":        lockedErr.Until,
+				"retry_after_seconds": retryAfter,
+			})
+			return
+		}
+
+		switch err.Error() {
+		case "user is not active":
+			common.SendError(c, http.StatusForbidden, "User is not active", common.CodeForbidden, nil)
+		case "session limit reached":
+			common.SendError(c, http.StatusConflict, sessionLimitMessage, common.CodeSessionLimit, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	// The link was valid but a second factor is still required
+	if response.MFA != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa":          response.MFA,
+			"user":         response.User,
+		})
+		return
+	}
+
+	middleware.LogLoginAction(c, h.auditService, response.User.ID, response.User.Username, true)
+
+	setLoginCookies(c, response.Token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": response.User,
+	})
+}
@@ -32,7 +32,7 @@ func (h *MenuHandler) GetAllMenus(c *gin.Context) {
 		return
 	}
 
-	response, err := h.menuService.GetAllMenus(params)
+	response, err := h.menuService.WithContext(c.Request.Context()).GetAllMenus(params)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch menus", common.CodeInternalError, err.Error())
 		return
@@ -43,7 +43,7 @@ func (h *MenuHandler) GetAllMenus(c *gin.Context) {
 
 // GetMenuTree handles GET /api/menus/tree
 func (h *MenuHandler) GetMenuTree(c *gin.Context) {
-	menus, err := h.menuService.GetMenuTree()
+	menus, err := h.menuService.WithContext(c.Request.Context()).GetMenuTree()
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch menu tree", common.CodeInternalError, err.Error())
 		return
@@ -67,20 +67,20 @@ func (h *MenuHandler) GetUserMenus(c *gin.Context) {
 		return
 	}
 
-	// Get roleID from context
-	roleIDInterface, exists := c.Get("roleID")
+	// Get the user's role IDs from context
+	roleIDsInterface, exists := c.Get("roleIDs")
 	if !exists {
-		common.SendError(c, http.StatusInternalServerError, "Failed to get role from context", common.CodeInternalError, nil)
+		common.SendError(c, http.StatusInternalServerError, "Failed to get roles from context", common.CodeInternalError, nil)
 		return
 	}
 
-	roleID, ok := roleIDInterface.(uint)
+	roleIDs, ok := roleIDsInterface.([]uint)
 	if !ok {
-		common.SendError(c, http.StatusInternalServerError, "Failed to get role ID from context", common.CodeInternalError, nil)
+		common.SendError(c, http.StatusInternalServerError, "Failed to get role IDs from context", common.CodeInternalError, nil)
 		return
 	}
 
-	menus, err := h.menuService.GetUserMenus(user.ID, roleID)
+	menus, err := h.menuService.WithContext(c.Request.Context()).GetUserMenus(user.ID, roleIDs)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch user menus", common.CodeInternalError, err.Error())
 		return
@@ -97,7 +97,7 @@ func (h *MenuHandler) GetMenuByID(c *gin.Context) {
 		return
 	}
 
-	menu, err := h.menuService.GetMenuByID(uint(id))
+	menu, err := h.menuService.WithContext(c.Request.Context()).GetMenuByID(uint(id))
 	if err != nil {
 		if err.Error() == "menu not found" {
 			common.SendError(c, http.StatusNotFound, "Menu not found", common.CodeNotFound, nil)
@@ -123,7 +123,7 @@ func (h *MenuHandler) CreateMenu(c *gin.Context) {
 		return
 	}
 
-	menu, err := h.menuService.CreateMenu(&req)
+	menu, err := h.menuService.WithContext(c.Request.Context()).CreateMenu(&req)
 	if err != nil {
 		if err.Error() == "parent menu not found" {
 			common.SendError(c, http.StatusBadRequest, "Parent menu not found", common.CodeBadRequest, nil)
@@ -155,7 +155,7 @@ func (h *MenuHandler) UpdateMenu(c *gin.Context) {
 		return
 	}
 
-	menu, err := h.menuService.UpdateMenu(uint(id), &req)
+	menu, err := h.menuService.WithContext(c.Request.Context()).UpdateMenu(uint(id), &req)
 	if err != nil {
 		switch err.Error() {
 		case "menu not found":
@@ -164,6 +164,8 @@ func (h *MenuHandler) UpdateMenu(c *gin.Context) {
 			common.SendError(c, http.StatusBadRequest, "Parent menu not found", common.CodeBadRequest, nil)
 		case "menu cannot be its own parent":
 			common.SendError(c, http.StatusBadRequest, "Menu cannot be its own parent", common.CodeBadRequest, nil)
+		case "cannot modify a shared menu":
+			common.SendError(c, http.StatusForbidden, "Shared menus can't be changed by an organization", common.CodeForbidden, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to update menu", common.CodeInternalError, err.Error())
 		}
@@ -181,12 +183,14 @@ func (h *MenuHandler) DeleteMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.DeleteMenu(uint(id)); err != nil {
+	if err := h.menuService.WithContext(c.Request.Context()).DeleteMenu(uint(id)); err != nil {
 		switch err.Error() {
 		case "menu not found":
 			common.SendError(c, http.StatusNotFound, "Menu not found", common.CodeNotFound, nil)
 		case "cannot delete menu with children":
 			common.SendError(c, http.StatusBadRequest, "Cannot delete menu with children", common.CodeBadRequest, nil)
+		case "cannot modify a shared menu":
+			common.SendError(c, http.StatusForbidden, "Shared menus can't be changed by an organization", common.CodeForbidden, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to delete menu", common.CodeInternalError, err.Error())
 		}
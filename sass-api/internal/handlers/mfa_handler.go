@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type MFAHandler struct {
+	mfaService   *services.MFAService
+	auditService *services.AuditService
+	validate     *validator.Validate
+}
+
+func NewMFAHandler(mfaService *services.MFAService, auditService *services.AuditService) *MFAHandler {
+	return &MFAHandler{
+		mfaService:   mfaService,
+		auditService: auditService,
+		validate:     validator.New(),
+	}
+}
+
+// GetStatus returns the MFA state of the authenticated user
+// GET /api/auth/mfa
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.GetStatus(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "MFA status retrieved successfully", status)
+}
+
+// BeginEnrollment generates a new TOTP secret for the authenticated user
+// POST /api/auth/mfa/enroll
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfaService.BeginEnrollment(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "MFA enrollment started", enrollment)
+}
+
+// ConfirmEnrollment enables MFA once the first authenticator code has been verified
+// POST /api/auth/mfa/enroll/confirm
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "MFA_ENROLLED", "mfa", strconv.FormatUint(uint64(userID), 10), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "MFA enabled successfully", codes)
+}
+
+// Disable turns off MFA for the authenticated user
+// POST /api/auth/mfa/disable
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFADisableRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.mfaService.Disable(userID, &req); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "MFA_DISABLED", "mfa", strconv.FormatUint(uint64(userID), 10), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "MFA disabled successfully", nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the authenticated user
+// POST /api/auth/mfa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "MFA_RECOVERY_CODES_REGENERATED", "mfa", strconv.FormatUint(uint64(userID), 10), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "Recovery codes regenerated successfully", codes)
+}
+
+// bind decodes and validates the request body, writing the error response on failure
+func (h *MFAHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// handleError maps MFA service errors to HTTP responses
+func (h *MFAHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+	case "mfa already enabled":
+		common.SendError(c, http.StatusConflict, "MFA is already enabled", common.CodeConflict, nil)
+	case "mfa not enabled":
+		common.SendError(c, http.StatusBadRequest, "MFA is not enabled", common.CodeBadRequest, nil)
+	case "mfa enrollment not started":
+		common.SendError(c, http.StatusBadRequest, "MFA enrollment has not been started", common.CodeBadRequest, nil)
+	case "mfa required by role":
+		common.SendError(c, http.StatusForbidden, "MFA is required for your role and cannot be disabled", common.CodeForbidden, nil)
+	case "invalid mfa code":
+		common.SendError(c, http.StatusBadRequest, "Invalid MFA code", common.CodeInvalidMFACode, nil)
+	case "invalid password":
+		common.SendError(c, http.StatusUnauthorized, "Invalid password", common.CodeUnauthorized, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+	}
+}
+
+// getAuthUserID reads the authenticated user ID set by the auth middleware,
+// writing the error response when it is missing
+func getAuthUserID(c *gin.Context) (uint, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		common.SendError(c, http.StatusUnauthorized, "Authentication required", common.CodeUnauthorized, nil)
+		return 0, false
+	}
+
+	userID, ok := userIDValue.(uint)
+	if !ok {
+		common.SendError(c, http.StatusInternalServerError, "Invalid user ID", common.CodeInternalError, nil)
+		return 0, false
+	}
+
+	return userID, true
+}
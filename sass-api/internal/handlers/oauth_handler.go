@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+	auditService *services.AuditService
+	validate     *validator.Validate
+}
+
+func NewOAuthHandler(oauthService *services.OAuthService, auditService *services.AuditService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		auditService: auditService,
+		validate:     validator.New(),
+	}
+}
+
+// Discovery serves the OpenID Connect discovery document
+// GET /.well-known/openid-configuration
+func (h *OAuthHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.oauthService.Discovery())
+}
+
+// GetConsent validates an authorization request for the signed-in user and returns
+// the data for the consent screen
+// GET /api/oauth/authorize?client_id=...&redirect_uri=...&response_type=code&scope=...
+func (h *OAuthHandler) GetConsent(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid authorization request", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	consent, err := h.oauthService.GetConsent(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Authorization request is valid", consent)
+}
+
+// Authorize records the user's consent decision and returns where to redirect the browser
+// POST /api/oauth/authorize
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.OAuthAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	redirectTo, err := h.oauthService.Authorize(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if req.Approve {
+		middleware.AuditAction(c, h.auditService, "OAUTH_CONSENT_GRANTED", "oauth_client", req.ClientID, nil, gin.H{"scope": req.Scope})
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Authorization decision recorded", models.OAuthAuthorizeResponse{RedirectTo: redirectTo})
+}
+
+// Token issues tokens for the authorization_code, refresh_token and client_credentials grants
+// POST /api/oauth/token (application/x-www-form-urlencoded)
+func (h *OAuthHandler) Token(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	var response *models.OAuthTokenResponse
+	var err error
+	switch c.PostForm("grant_type") {
+	case models.OAuthGrantAuthorizationCode:
+		response, err = h.oauthService.ExchangeCode(client, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	case models.OAuthGrantRefreshToken:
+		response, err = h.oauthService.RefreshToken(client, c.PostForm("refresh_token"), c.PostForm("scope"))
+	case models.OAuthGrantClientCredentials:
+		response, err = h.oauthService.ClientCredentials(client, c.PostForm("scope"))
+	default:
+		err = &services.OAuthError{Code: "unsupported_grant_type", Description: "Unsupported grant_type"}
+	}
+	if err != nil {
+		h.sendOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, response)
+}
+
+// Introspect reports whether a token is active (RFC 7662). Only confidential
+// clients, such as resource servers, may introspect.
+// POST /api/oauth/introspect
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+	if client.Type != models.OAuthClientConfidential {
+		h.sendOAuthError(c, &services.OAuthError{Code: "unauthorized_client", Description: "Public clients may not introspect tokens"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, h.oauthService.Introspect(c.PostForm("token")))
+}
+
+// Revoke revokes an access or refresh token issued to the client (RFC 7009)
+// POST /api/oauth/revoke
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	if err := h.oauthService.Revoke(client, c.PostForm("token")); err != nil {
+		h.sendOAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// UserInfo returns claims about the user an OAuth access token was issued for
+// GET|POST /api/oauth/userinfo
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	accessToken, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || accessToken == "" {
+		c.Header("WWW-Authenticate", `Bearer`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	claims, err := h.oauthService.UserInfo(accessToken)
+	if err != nil {
+		if err.Error() == "insufficient scope" {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+			return
+		}
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+// ListClients returns the registered OAuth clients
+// GET /api/oauth/clients
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.oauthService.WithContext(c.Request.Context()).ListClients()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "OAuth clients retrieved successfully", gin.H{
+		"clients": clients,
+		"total":   len(clients),
+	})
+}
+
+// CreateClient registers an OAuth client; the secret is only returned in this response
+// POST /api/oauth/clients
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	client, err := h.oauthService.WithContext(c.Request.Context()).CreateClient(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "OAUTH_CLIENT_CREATED", "oauth_client", client.ClientID, nil, client.OAuthClientResponse)
+
+	common.SendSuccess(c, http.StatusCreated, "OAuth client created successfully, copy the secret now as it won't be shown again", client)
+}
+
+// DeleteClient removes an OAuth client and revokes everything issued to it
+// DELETE /api/oauth/clients/:id
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid OAuth client ID", common.CodeBadRequest, nil)
+		return
+	}
+
+	client, err := h.oauthService.WithContext(c.Request.Context()).DeleteClient(uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "OAUTH_CLIENT_DELETED", "oauth_client", client.ClientID, client, nil)
+
+	common.SendSuccess(c, http.StatusOK, "OAuth client deleted successfully", nil)
+}
+
+// authenticateClient reads client credentials from HTTP Basic auth or the form body
+func (h *OAuthHandler) authenticateClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes the credentials before base64
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	client, err := h.oauthService.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		h.sendOAuthError(c, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// sendOAuthError writes an error in the RFC 6749 format expected by OAuth clients
+func (h *OAuthHandler) sendOAuthError(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+// handleError maps OAuth service errors on the consent and client management endpoints
+func (h *OAuthHandler) handleError(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		common.SendError(c, http.StatusBadRequest, oauthErr.Description, common.CodeBadRequest, gin.H{"error": oauthErr.Code})
+		return
+	}
+
+	switch err.Error() {
+	case "oauth client not found":
+		common.SendError(c, http.StatusNotFound, "OAuth client not found", common.CodeNotFound, nil)
+	case "invalid scope":
+		common.SendError(c, http.StatusBadRequest, "Scopes can't contain whitespace", common.CodeValidationError, nil)
+	case "invalid redirect uri":
+		common.SendError(c, http.StatusBadRequest, "Redirect URIs can't contain spaces or fragments", common.CodeValidationError, nil)
+	case "redirect uri required":
+		common.SendError(c, http.StatusBadRequest, "The authorization_code grant needs at least one redirect URI", common.CodeValidationError, nil)
+	case "public clients can't use client credentials":
+		common.SendError(c, http.StatusBadRequest, "Public clients can't use the client_credentials grant", common.CodeValidationError, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// oidcStateCookie holds the signed login state while the user is at the provider
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService  *services.OIDCService
+	userService  *services.UserService
+	auditService *services.AuditService
+	config       *config.Config
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, userService *services.UserService, auditService *services.AuditService, cfg *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:  oidcService,
+		userService:  userService,
+		auditService: auditService,
+		config:       cfg,
+	}
+}
+
+// ListProviders returns the configured identity providers for the login page
+// GET /api/auth/oidc/providers
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	common.SendSuccess(c, http.StatusOK, "Identity providers retrieved successfully", h.oidcService.ListProviders())
+}
+
+// Login redirects the browser to the provider's authorization endpoint
+// GET /api/auth/oidc/:provider/login?redirect=/dashboard
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, stateToken, err := h.oidcService.BeginLogin(c.Param("provider"), c.Query("redirect"))
+	if err != nil {
+		switch err.Error() {
+		case "oidc provider not found":
+			common.SendError(c, http.StatusNotFound, "Identity provider not found", common.CodeNotFound, nil)
+		case "oidc provider unavailable":
+			common.SendError(c, http.StatusBadGateway, "Identity provider is unavailable", common.CodeInternalError, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	// Lax so the cookie comes back on the provider's top-level redirect
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, stateToken, int(h.config.OIDCStateTTL.Seconds()), oidcCookiePath, "", false, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the login when the provider redirects back, then sends the
+// browser to the frontend
+// GET /api/auth/oidc/:provider/callback
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", false, true)
+
+	// The user declined, or the provider refused the request
+	if c.Query("error") != "" {
+		h.redirectToLogin(c, "oidc_denied")
+		return
+	}
+
+	result, err := h.oidcService.FinishLogin(c.Request.Context(), c.Param("provider"), c.Query("code"), c.Query("state"), stateToken)
+	if err != nil {
+		middleware.LogLoginAction(c, h.auditService, 0, "", false)
+
+		switch err.Error() {
+		case "oidc email not verified":
+			h.redirectToLogin(c, "email_not_verified")
+		case "oidc account not linked":
+			h.redirectToLogin(c, "account_not_linked")
+		default:
+			h.redirectToLogin(c, "oidc_failed")
+		}
+		return
+	}
+
+	if result.Linked || result.Created {
+		h.logIdentityLinked(c, result)
+	}
+
+	response, err := h.userService.LoginWithExternalIdentity(result.User, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		var lockedErr *services.AccountLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			h.redirectToLogin(c, "account_locked")
+		case err.Error() == "user is not active":
+			h.redirectToLogin(c, "user_not_active")
+		case err.Error() == "session limit reached":
+			h.redirectToLogin(c, "session_limit_reached")
+		default:
+			h.redirectToLogin(c, "oidc_failed")
+		}
+		return
+	}
+
+	// The frontend finishes the second factor with the challenge token. It goes in
+	// the fragment so it stays out of server logs and Referer headers.
+	if response.MFA != nil {
+		fragment := url.Values{}
+		fragment.Set("mfa_token", response.MFA.MFAToken)
+		fragment.Set("enrollment_required", strconv.FormatBool(response.MFA.EnrollmentRequired))
+		fragment.Set("redirect", result.RedirectPath)
+		c.Redirect(http.StatusFound, h.appURL("/login/mfa")+"#"+fragment.Encode())
+		return
+	}
+
+	middleware.LogLoginAction(c, h.auditService, response.User.ID, response.User.Username, true)
+
+	setLoginCookies(c, response.Token)
+	c.Redirect(http.StatusFound, h.appURL(result.RedirectPath))
+}
+
+// redirectToLogin sends the browser back to the frontend login page with an error code
+func (h *OIDCHandler) redirectToLogin(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, h.appURL("/login")+"?error="+url.QueryEscape(code))
+}
+
+// appURL builds a frontend URL from a path
+func (h *OIDCHandler) appURL(path string) string {
+	return strings.TrimRight(h.config.AppBaseURL, "/") + path
+}
+
+// logIdentityLinked audits an external identity being linked to an account
+func (h *OIDCHandler) logIdentityLinked(c *gin.Context, result *services.OIDCLoginResult) {
+	if h.auditService == nil {
+		return
+	}
+
+	userID := result.User.ID
+	username := result.User.Username
+	details := gin.H{"provider": result.Provider, "account_created": result.Created}
+	ipAddress := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+	correlationID := middleware.GetCorrelationID(c)
+
+	go func() {
+		_ = h.auditService.LogWithContext(&userID, username, "OIDC_IDENTITY_LINKED", "user", strconv.FormatUint(uint64(userID), 10),
+			nil, details, ipAddress, userAgent, correlationID)
+	}()
+}
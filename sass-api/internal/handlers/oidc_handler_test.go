@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/oidctest"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// oidcTestServer wires the OIDC routes to a mock provider and an in-memory database
+type oidcTestServer struct {
+	router   *gin.Engine
+	provider *oidctest.Provider
+	db       *gorm.DB
+}
+
+func newOIDCTestServer(t *testing.T) *oidcTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatalf("register tenant plugin: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.Organization{}, &models.Role{}, &models.Users{}, &models.UserRole{},
+		&models.ExternalIdentity{}, &models.Session{}, &models.RefreshToken{}, &models.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	provider := oidctest.NewProvider(t, "sass-api", "client-secret")
+	cfg := &config.Config{
+		JWTSigningAlg:      "HS256",
+		JWTSecret:          "test-secret",
+		JWTExpiry:          15 * time.Minute,
+		RefreshTokenExpiry: time.Hour,
+		AppBaseURL:         "http://app.test",
+		APIBaseURL:         "http://api.test",
+		OIDCStateTTL:       10 * time.Minute,
+		OIDCProviders: []config.OIDCProviderConfig{{
+			Name:         "mock",
+			Issuer:       provider.Issuer(),
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			Scopes:       []string{"openid", "email"},
+			EmailClaim:   "email",
+		}},
+	}
+
+	keyRing, err := services.NewKeyRing(cfg)
+	if err != nil {
+		t.Fatalf("new key ring: %v", err)
+	}
+	auditService := services.NewAuditService(db)
+	tokenService := services.NewTokenService(db, cfg, auditService, keyRing)
+	userService := services.NewUserService(db, cfg, tokenService, nil, nil, nil)
+	h := NewOIDCHandler(services.NewOIDCService(db, cfg, tokenService), userService, auditService, cfg)
+
+	router := gin.New()
+	router.GET("/api/auth/oidc/:provider/login", h.Login)
+	router.GET("/api/auth/oidc/:provider/callback", h.Callback)
+
+	return &oidcTestServer{router: router, provider: provider, db: db}
+}
+
+func (s *oidcTestServer) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// beginLogin starts a login and returns the provider redirect and the state cookie
+func (s *oidcTestServer) beginLogin(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+
+	w := s.do(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/login?redirect=/dashboard", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want 302", w.Code)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			return w.Header().Get("Location"), cookie
+		}
+	}
+	t.Fatal("login did not set the state cookie")
+	return "", nil
+}
+
+// callback follows the provider's redirect back to the API
+func (s *oidcTestServer) callback(code, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{"code": {code}, "state": {state}}
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return s.do(req)
+}
+
+func (s *oidcTestServer) createUser(t *testing.T, username string) models.Users {
+	t.Helper()
+
+	role := models.Role{Name: "user", DisplayName: "User", IsActive: true}
+	s.db.Create(&role)
+	now := time.Now()
+	user := models.Users{
+		Username:        username,
+		Email:           username + "@example.com",
+		Password:        "unused",
+		Name:            username,
+		RoleID:          role.ID,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func hasCookie(w *httptest.ResponseRecorder, name string) bool {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOIDCCallbackSignsInLinkedAccount(t *testing.T) {
+	s := newOIDCTestServer(t)
+	user := s.createUser(t, "alice")
+	s.provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+
+	authURL, cookie := s.beginLogin(t)
+	code, state, err := s.provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	w := s.callback(code, state, cookie)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "http://app.test/dashboard" {
+		t.Fatalf("callback = %d to %q, want redirect to the dashboard", w.Code, w.Header().Get("Location"))
+	}
+	if !hasCookie(w, "access_token") || !hasCookie(w, "refresh_token") {
+		t.Error("callback did not set the session cookies")
+	}
+
+	var identity models.ExternalIdentity
+	if err := s.db.Where("provider = ? AND subject = ?", "mock", "ext-alice").First(&identity).Error; err != nil || identity.UserID != user.ID {
+		t.Errorf("identity = %+v (%v), want linked to user %d", identity, err, user.ID)
+	}
+}
+
+func TestOIDCCallbackRejectsBadLogins(t *testing.T) {
+	tests := []struct {
+		name      string
+		claims    map[string]any
+		state     string // Replaces the state from the provider when set
+		noCookie  bool
+		wantError string
+	}{
+		{name: "state mismatch", state: "forged", wantError: "oidc_failed"},
+		{name: "missing state cookie", noCookie: true, wantError: "oidc_failed"},
+		{name: "wrong issuer", claims: map[string]any{"iss": "https://attacker.example"}, wantError: "oidc_failed"},
+		{name: "wrong audience", claims: map[string]any{"aud": "another-client"}, wantError: "oidc_failed"},
+		{name: "wrong nonce", claims: map[string]any{"nonce": "replayed"}, wantError: "oidc_failed"},
+		{name: "unverified email", claims: map[string]any{"email_verified": false}, wantError: "email_not_verified"},
+		{name: "unknown email", claims: map[string]any{"email": "stranger@example.com"}, wantError: "account_not_linked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOIDCTestServer(t)
+			s.createUser(t, "alice")
+			s.provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+			for name, value := range tt.claims {
+				s.provider.Claims[name] = value
+			}
+
+			authURL, cookie := s.beginLogin(t)
+			code, state, err := s.provider.Authorize(authURL)
+			if err != nil {
+				t.Fatalf("authorize: %v", err)
+			}
+			if tt.state != "" {
+				state = tt.state
+			}
+			if tt.noCookie {
+				cookie = nil
+			}
+
+			w := s.callback(code, state, cookie)
+			want := "http://app.test/login?error=" + tt.wantError
+			if w.Code != http.StatusFound || w.Header().Get("Location") != want {
+				t.Errorf("callback = %d to %q, want redirect to %q", w.Code, w.Header().Get("Location"), want)
+			}
+			if hasCookie(w, "access_token") {
+				t.Error("callback set a session cookie")
+			}
+		})
+	}
+}
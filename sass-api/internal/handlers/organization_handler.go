@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+	validate            *validator.Validate
+}
+
+func NewOrganizationHandler(organizationService *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		validate:            validator.New(),
+	}
+}
+
+// GetAllOrganizations handles GET /api/organizations
+func (h *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
+	var params pagination.QueryParams
+	if err := params.Bind(c); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid query parameters", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	response, err := h.organizationService.GetAllOrganizations(params)
+	if err != nil {
+		common.SendError(c, http.StatusInternalServerError, "Failed to fetch organizations", common.CodeInternalError, err.Error())
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Organizations fetched successfully", response)
+}
+
+// GetOrganizationByID handles GET /api/organization/:id
+func (h *OrganizationHandler) GetOrganizationByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid organization ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	organization, err := h.organizationService.GetOrganizationByID(uint(id))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			common.SendError(c, http.StatusNotFound, "Organization not found", common.CodeNotFound, nil)
+		} else {
+			common.SendError(c, http.StatusInternalServerError, "Failed to fetch organization", common.CodeInternalError, err.Error())
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Organization fetched successfully", organization)
+}
+
+// CreateOrganization handles POST /api/organization/create
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	organization, err := h.organizationService.CreateOrganization(&req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create organization")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusCreated, "Organization created successfully", organization)
+}
+
+// UpdateOrganization handles PUT /api/organization/:id
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid organization ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	var req models.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	organization, err := h.organizationService.UpdateOrganization(uint(id), &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update organization")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Organization updated successfully", organization)
+}
+
+// DeleteOrganization handles DELETE /api/organization/:id
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid organization ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	if err := h.organizationService.DeleteOrganization(uint(id)); err != nil {
+		h.handleError(c, err, "Failed to delete organization")
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Organization deleted successfully", nil)
+}
+
+// handleError maps organization service errors to responses
+func (h *OrganizationHandler) handleError(c *gin.Context, err error, fallback string) {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "organization not found":
+		common.SendError(c, http.StatusNotFound, "Organization not found", common.CodeNotFound, nil)
+	case errMsg == "organization slug already exists":
+		common.SendError(c, http.StatusConflict, "Organization slug already exists", common.CodeConflict, nil)
+	case errMsg == "invalid organization slug":
+		common.SendError(c, http.StatusBadRequest, "Slug may only contain lowercase letters, digits and hyphens", common.CodeValidationError, nil)
+	case errMsg == "is_active is required",
+		errMsg == "cannot rename the default organization",
+		errMsg == "cannot delete the default organization",
+		strings.HasPrefix(errMsg, "cannot delete organization"):
+		common.SendError(c, http.StatusBadRequest, errMsg, common.CodeBadRequest, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, fallback, common.CodeInternalError, errMsg)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordPolicyHandler struct {
+	passwordPolicyService *services.PasswordPolicyService
+}
+
+func NewPasswordPolicyHandler(passwordPolicyService *services.PasswordPolicyService) *PasswordPolicyHandler {
+	return &PasswordPolicyHandler{
+		passwordPolicyService: passwordPolicyService,
+	}
+}
+
+// GetPolicy returns the active password rules so clients can validate as the user types
+// GET /api/auth/password-policy
+func (h *PasswordPolicyHandler) GetPolicy(c *gin.Context) {
+	common.SendSuccess(c, http.StatusOK, "Password policy retrieved successfully", h.passwordPolicyService.GetPolicy())
+}
+
+// sendPasswordPolicyError writes the broken password rules when err is a policy violation
+func sendPasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	common.SendError(c, http.StatusBadRequest, "Password does not meet the password policy", common.CodePasswordPolicy, gin.H{
+		"violations": policyErr.Violations,
+	})
+	return true
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService *services.PasswordResetService
+	validate             *validator.Validate
+}
+
+func NewPasswordResetHandler(passwordResetService *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: passwordResetService,
+		validate:             validator.New(),
+	}
+}
+
+// ForgotPassword emails a password reset link
+// POST /api/auth/forgot-password
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	if err := h.passwordResetService.RequestReset(req.Email, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		return
+	}
+
+	// Same response whether or not the email belongs to an account
+	common.SendSuccess(c, http.StatusOK, "If an account with that email exists, a password reset link has been sent", nil)
+}
+
+// ResetPassword sets a new password using the token from the reset email
+// POST /api/auth/reset-password
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(&req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+
+		switch err.Error() {
+		case "invalid or expired reset token":
+			common.SendError(c, http.StatusBadRequest, "Reset link is invalid or has expired", common.CodeBadRequest, nil)
+		case "new password and confirm password do not match":
+			common.SendError(c, http.StatusBadRequest, "New password and confirm password do not match", common.CodeValidationError, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Password has been reset, please log in again", nil)
+}
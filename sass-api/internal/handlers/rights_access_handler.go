@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Aebroyx/sass-api/internal/common"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
@@ -31,15 +32,41 @@ func (h *RightsAccessHandler) GetUserRightsAccess(c *gin.Context) {
 		return
 	}
 
-	rightsAccess, err := h.rightsAccessService.GetUserRightsAccess(uint(userID))
+	rightsAccess, err := h.rightsAccessService.WithContext(c.Request.Context()).GetUserRightsAccess(uint(userID))
 	if err != nil {
-		common.SendError(c, http.StatusInternalServerError, "Failed to fetch rights access", common.CodeInternalError, err.Error())
+		if err.Error() == "user not found" {
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+		} else {
+			common.SendError(c, http.StatusInternalServerError, "Failed to fetch rights access", common.CodeInternalError, err.Error())
+		}
 		return
 	}
 
 	common.SendSuccess(c, http.StatusOK, "Rights access fetched successfully", rightsAccess)
 }
 
+// GetExpiringGrants handles GET /api/rights-access/expiring. The optional within query
+// parameter (a duration such as 72h) overrides the default look-ahead.
+func (h *RightsAccessHandler) GetExpiringGrants(c *gin.Context) {
+	var within time.Duration
+	if raw := c.Query("within"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			common.SendError(c, http.StatusBadRequest, "Invalid within duration", common.CodeInvalidRequest, nil)
+			return
+		}
+		within = d
+	}
+
+	grants, err := h.rightsAccessService.WithContext(c.Request.Context()).GetExpiringGrants(within)
+	if err != nil {
+		common.SendError(c, http.StatusInternalServerError, "Failed to fetch expiring grants", common.CodeInternalError, err.Error())
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Expiring grants fetched successfully", grants)
+}
+
 // GetUserMenuRightsAccess handles GET /api/rights-access/user/:userId/menu/:menuId
 func (h *RightsAccessHandler) GetUserMenuRightsAccess(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
@@ -54,11 +81,14 @@ func (h *RightsAccessHandler) GetUserMenuRightsAccess(c *gin.Context) {
 		return
 	}
 
-	rightsAccess, err := h.rightsAccessService.GetUserMenuRightsAccess(uint(userID), uint(menuID))
+	rightsAccess, err := h.rightsAccessService.WithContext(c.Request.Context()).GetUserMenuRightsAccess(uint(userID), uint(menuID))
 	if err != nil {
-		if err.Error() == "rights access not found" {
+		switch err.Error() {
+		case "user not found":
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+		case "rights access not found":
 			common.SendError(c, http.StatusNotFound, "Rights access not found", common.CodeNotFound, nil)
-		} else {
+		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to fetch rights access", common.CodeInternalError, err.Error())
 		}
 		return
@@ -80,13 +110,15 @@ func (h *RightsAccessHandler) CreateOrUpdateRightsAccess(c *gin.Context) {
 		return
 	}
 
-	rightsAccess, err := h.rightsAccessService.CreateOrUpdateRightsAccess(&req)
+	rightsAccess, err := h.rightsAccessService.WithContext(c.Request.Context()).CreateOrUpdateRightsAccess(&req)
 	if err != nil {
 		switch err.Error() {
 		case "user not found":
 			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
 		case "menu not found":
 			common.SendError(c, http.StatusNotFound, "Menu not found", common.CodeNotFound, nil)
+		case "valid_until must be after valid_from", "valid_until must be in the future":
+			common.SendError(c, http.StatusBadRequest, err.Error(), common.CodeValidationError, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to create/update rights access", common.CodeInternalError, err.Error())
 		}
@@ -104,7 +136,7 @@ func (h *RightsAccessHandler) DeleteRightsAccess(c *gin.Context) {
 		return
 	}
 
-	if err := h.rightsAccessService.DeleteRightsAccess(uint(id)); err != nil {
+	if err := h.rightsAccessService.WithContext(c.Request.Context()).DeleteRightsAccess(uint(id)); err != nil {
 		if err.Error() == "rights access not found" {
 			common.SendError(c, http.StatusNotFound, "Rights access not found", common.CodeNotFound, nil)
 		} else {
@@ -135,11 +167,14 @@ func (h *RightsAccessHandler) BulkSaveUserRightsAccess(c *gin.Context) {
 		return
 	}
 
-	rightsAccess, err := h.rightsAccessService.BulkSaveUserRightsAccess(uint(userID), &req)
+	rightsAccess, err := h.rightsAccessService.WithContext(c.Request.Context()).BulkSaveUserRightsAccess(uint(userID), &req)
 	if err != nil {
-		if err.Error() == "user not found" {
+		switch err.Error() {
+		case "user not found":
 			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
-		} else {
+		case "valid_until must be after valid_from", "valid_until must be in the future":
+			common.SendError(c, http.StatusBadRequest, err.Error(), common.CodeValidationError, nil)
+		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to save rights access", common.CodeInternalError, err.Error())
 		}
 		return
@@ -156,8 +191,12 @@ func (h *RightsAccessHandler) DeleteAllUserRightsAccess(c *gin.Context) {
 		return
 	}
 
-	if err := h.rightsAccessService.DeleteAllUserRightsAccess(uint(userID)); err != nil {
-		common.SendError(c, http.StatusInternalServerError, "Failed to delete rights access", common.CodeInternalError, err.Error())
+	if err := h.rightsAccessService.WithContext(c.Request.Context()).DeleteAllUserRightsAccess(uint(userID)); err != nil {
+		if err.Error() == "user not found" {
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+		} else {
+			common.SendError(c, http.StatusInternalServerError, "Failed to delete rights access", common.CodeInternalError, err.Error())
+		}
 		return
 	}
 
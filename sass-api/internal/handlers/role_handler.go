@@ -33,7 +33,7 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 		return
 	}
 
-	response, err := h.roleService.GetAllRoles(params)
+	response, err := h.roleService.WithContext(c.Request.Context()).GetAllRoles(params)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch roles", common.CodeInternalError, err.Error())
 		return
@@ -44,7 +44,7 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 
 // GetActiveRoles handles GET /api/roles/active (for dropdowns)
 func (h *RoleHandler) GetActiveRoles(c *gin.Context) {
-	roles, err := h.roleService.GetActiveRoles()
+	roles, err := h.roleService.WithContext(c.Request.Context()).GetActiveRoles()
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch roles", common.CodeInternalError, err.Error())
 		return
@@ -61,7 +61,7 @@ func (h *RoleHandler) GetRoleByID(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.GetRoleByID(uint(id))
+	role, err := h.roleService.WithContext(c.Request.Context()).GetRoleByID(uint(id))
 	if err != nil {
 		if err.Error() == "role not found" {
 			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
@@ -87,11 +87,14 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.CreateRole(&req)
+	role, err := h.roleService.WithContext(c.Request.Context()).CreateRole(&req)
 	if err != nil {
-		if err.Error() == "role name already exists" {
+		switch err.Error() {
+		case "role name already exists":
 			common.SendError(c, http.StatusConflict, "Role name already exists", common.CodeConflict, nil)
-		} else {
+		case "parent role not found", "invalid parent role":
+			common.SendError(c, http.StatusBadRequest, "Invalid parent role", common.CodeValidationError, nil)
+		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to create role", common.CodeInternalError, err.Error())
 		}
 		return
@@ -119,13 +122,19 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.UpdateRole(uint(id), &req)
+	role, err := h.roleService.WithContext(c.Request.Context()).UpdateRole(uint(id), &req)
 	if err != nil {
 		switch err.Error() {
 		case "role not found":
 			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
 		case "role name already exists":
 			common.SendError(c, http.StatusConflict, "Role name already exists", common.CodeConflict, nil)
+		case "cannot modify a shared role":
+			common.SendError(c, http.StatusForbidden, "Shared roles can't be changed by an organization", common.CodeForbidden, nil)
+		case "parent role not found", "invalid parent role":
+			common.SendError(c, http.StatusBadRequest, "Invalid parent role", common.CodeValidationError, nil)
+		case "role hierarchy cannot contain a cycle":
+			common.SendError(c, http.StatusBadRequest, "A role can't inherit from itself or from a role that inherits from it", common.CodeValidationError, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to update role", common.CodeInternalError, err.Error())
 		}
@@ -143,11 +152,13 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.DeleteRole(uint(id)); err != nil {
+	if err := h.roleService.WithContext(c.Request.Context()).DeleteRole(uint(id)); err != nil {
 		errMsg := err.Error()
 		switch {
 		case errMsg == "role not found":
 			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
+		case errMsg == "cannot modify a shared role":
+			common.SendError(c, http.StatusForbidden, "Shared roles can't be changed by an organization", common.CodeForbidden, nil)
 		case errMsg == "cannot delete the default role":
 			common.SendError(c, http.StatusBadRequest, "Cannot delete the default role", common.CodeBadRequest, nil)
 		default:
@@ -172,15 +183,40 @@ func (h *RoleHandler) GetRoleMenus(c *gin.Context) {
 		return
 	}
 
-	menus, err := h.roleService.GetRoleMenus(uint(id))
+	menus, err := h.roleService.WithContext(c.Request.Context()).GetRoleMenus(uint(id))
 	if err != nil {
-		common.SendError(c, http.StatusInternalServerError, "Failed to fetch role menus", common.CodeInternalError, err.Error())
+		if err.Error() == "role not found" {
+			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
+		} else {
+			common.SendError(c, http.StatusInternalServerError, "Failed to fetch role menus", common.CodeInternalError, err.Error())
+		}
 		return
 	}
 
 	common.SendSuccess(c, http.StatusOK, "Role menus fetched successfully", menus)
 }
 
+// GetEffectivePermissions handles GET /api/role/:id/effective-permissions
+func (h *RoleHandler) GetEffectivePermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid role ID", common.CodeInvalidRequest, nil)
+		return
+	}
+
+	permissions, err := h.roleService.WithContext(c.Request.Context()).GetEffectivePermissions(uint(id))
+	if err != nil {
+		if err.Error() == "role not found" {
+			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
+		} else {
+			common.SendError(c, http.StatusInternalServerError, "Failed to fetch effective permissions", common.CodeInternalError, err.Error())
+		}
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Effective permissions fetched successfully", permissions)
+}
+
 // AssignMenusToRole handles POST /api/role/:id/menus
 func (h *RoleHandler) AssignMenusToRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -200,11 +236,14 @@ func (h *RoleHandler) AssignMenusToRole(c *gin.Context) {
 		return
 	}
 
-	menus, err := h.roleService.AssignMenusToRole(uint(id), &req)
+	menus, err := h.roleService.WithContext(c.Request.Context()).AssignMenusToRole(uint(id), &req)
 	if err != nil {
-		if err.Error() == "role not found" {
+		switch err.Error() {
+		case "role not found":
 			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
-		} else {
+		case "cannot modify a shared role":
+			common.SendError(c, http.StatusForbidden, "Shared roles can't be changed by an organization", common.CodeForbidden, nil)
+		default:
 			common.SendError(c, http.StatusBadRequest, err.Error(), common.CodeBadRequest, nil)
 		}
 		return
@@ -227,10 +266,15 @@ func (h *RoleHandler) RemoveMenuFromRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.RemoveMenuFromRole(uint(roleID), uint(menuID)); err != nil {
-		if err.Error() == "menu assignment not found" {
+	if err := h.roleService.WithContext(c.Request.Context()).RemoveMenuFromRole(uint(roleID), uint(menuID)); err != nil {
+		switch err.Error() {
+		case "role not found":
+			common.SendError(c, http.StatusNotFound, "Role not found", common.CodeNotFound, nil)
+		case "menu assignment not found":
 			common.SendError(c, http.StatusNotFound, "Menu assignment not found", common.CodeNotFound, nil)
-		} else {
+		case "cannot modify a shared role":
+			common.SendError(c, http.StatusForbidden, "Shared roles can't be changed by an organization", common.CodeForbidden, nil)
+		default:
 			common.SendError(c, http.StatusInternalServerError, "Failed to remove menu from role", common.CodeInternalError, err.Error())
 		}
 		return
@@ -42,20 +42,20 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 		return
 	}
 
-	roleIDInterface, exists := c.Get("roleID")
+	roleIDsInterface, exists := c.Get("roleIDs")
 	if !exists {
-		common.SendError(c, http.StatusUnauthorized, "Role ID not found in context", common.CodeUnauthorized, nil)
+		common.SendError(c, http.StatusUnauthorized, "Role IDs not found in context", common.CodeUnauthorized, nil)
 		return
 	}
 
-	roleID, ok := roleIDInterface.(uint)
+	roleIDs, ok := roleIDsInterface.([]uint)
 	if !ok {
-		common.SendError(c, http.StatusInternalServerError, "Invalid role ID in context", common.CodeInternalError, nil)
+		common.SendError(c, http.StatusInternalServerError, "Invalid role IDs in context", common.CodeInternalError, nil)
 		return
 	}
 
 	// Perform search with permission filtering
-	results, err := h.searchService.GlobalSearch(query, 5, user.ID, roleID, user.Role.Name)
+	results, err := h.searchService.WithContext(c.Request.Context()).GlobalSearch(query, 5, user.ID, roleIDs, c.GetStringSlice("roles"))
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to perform search", common.CodeInternalError, err.Error())
 		return
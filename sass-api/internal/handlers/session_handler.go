@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	auditService   *services.AuditService
+}
+
+func NewSessionHandler(sessionService *services.SessionService, auditService *services.AuditService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		auditService:   auditService,
+	}
+}
+
+// ListSessions returns the authenticated user's active sessions
+// GET /api/auth/sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	h.sendSessions(c, h.sessionService, userID)
+}
+
+// RevokeSession signs the authenticated user out of one of their sessions
+// DELETE /api/auth/sessions/:id
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("id")
+	if err := h.sessionService.RevokeSession(userID, sessionID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "SESSION_REVOKED", "session", sessionID, nil, nil)
+
+	// Revoking the current session is a logout
+	if sessionID == c.GetString(middleware.SessionIDKey) {
+		c.SetCookie("access_token", "", -1, "/", "", false, true)
+		c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// RevokeOtherSessions signs the authenticated user out of every other session
+// DELETE /api/auth/sessions
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeOtherSessions(userID, c.GetString(middleware.SessionIDKey))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "SESSIONS_REVOKED", "user", strconv.FormatUint(uint64(userID), 10), nil, gin.H{
+		"revoked_sessions": revoked,
+		"kept_session":     c.GetString(middleware.SessionIDKey),
+	})
+
+	common.SendSuccess(c, http.StatusOK, "Other sessions revoked successfully", gin.H{"revoked": revoked})
+}
+
+// ListUserSessions returns any user's active sessions for administrators
+// GET /api/user/:id/sessions
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	h.sendSessions(c, h.sessionService.WithContext(c.Request.Context()), userID)
+}
+
+// RevokeUserSession signs a user out of one of their sessions
+// DELETE /api/user/:id/sessions/:session_id
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := h.sessionService.WithContext(c.Request.Context()).RevokeSession(userID, sessionID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "SESSION_REVOKED", "session", sessionID, nil, gin.H{"user_id": userID})
+
+	common.SendSuccess(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// RevokeUserSessions signs a user out of every session
+// DELETE /api/user/:id/sessions
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.WithContext(c.Request.Context()).RevokeAllSessions(userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "SESSIONS_REVOKED", "user", c.Param("id"), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "All sessions revoked successfully", nil)
+}
+
+// sendSessions responds with the user's active sessions, flagging the caller's own
+func (h *SessionHandler) sendSessions(c *gin.Context, sessionService *services.SessionService, userID uint) {
+	sessions, err := sessionService.ListSessions(userID, c.GetString(middleware.SessionIDKey))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Sessions retrieved successfully", gin.H{
+		"sessions": sessions,
+		"total":    len(sessions),
+	})
+}
+
+// parseUserIDParam reads the :id route parameter as a user ID
+func parseUserIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid user ID", common.CodeBadRequest, nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// handleError maps session service errors to responses
+func (h *SessionHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+	case "session not found":
+		common.SendError(c, http.StatusNotFound, "Session not found", common.CodeNotFound, nil)
+	case "current session unknown":
+		common.SendError(c, http.StatusBadRequest, "The current session could not be determined, sign in again to manage sessions", common.CodeBadRequest, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+	}
+}
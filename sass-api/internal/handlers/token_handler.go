@@ -7,9 +7,9 @@ import (
 	"github.com/Aebroyx/sass-api/internal/common"
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
 	"github.com/Aebroyx/sass-api/internal/services"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
@@ -29,14 +29,20 @@ func NewTokenHandler(tokenService *services.TokenService, userService *services.
 	}
 }
 
-// RefreshToken rotates the refresh token and issues a new access token
+// RefreshToken rotates the refresh token and issues a new access token.
+// Browsers send the refresh token cookie; other clients send it in the JSON body.
 // POST /api/auth/refresh-token
 func (h *TokenHandler) RefreshToken(c *gin.Context) {
-	// Get refresh token from cookie
+	// Get refresh token from cookie, falling back to the request body
 	refreshToken, err := c.Cookie("refresh_token")
-	if err != nil {
-		common.SendError(c, http.StatusUnauthorized, "Refresh token required", common.CodeUnauthorized, nil)
-		return
+	fromCookie := err == nil && refreshToken != ""
+	if !fromCookie {
+		var req models.RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			common.SendError(c, http.StatusUnauthorized, "Refresh token required", common.CodeUnauthorized, nil)
+			return
+		}
+		refreshToken = req.RefreshToken
 	}
 
 	// Validate and rotate refresh token
@@ -52,30 +58,40 @@ func (h *TokenHandler) RefreshToken(c *gin.Context) {
 
 	// Get user from database
 	var user models.Users
-	if err := h.db.Preload("Role").First(&user, newRefreshToken.UserID).Error; err != nil {
+	if err := h.db.Preload("Role").Preload("UserRoles.Role").First(&user, newRefreshToken.UserID).Error; err != nil {
 		common.SendError(c, http.StatusUnauthorized, "User not found", common.CodeUnauthorized, nil)
 		return
 	}
 
 	// Generate new access token
-	accessToken, accessExp, err := h.generateAccessToken(user)
+	accessToken, accessExp, err := h.tokenService.GenerateAccessToken(user, newRefreshToken.FamilyID)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to generate access token", common.CodeInternalError, nil)
 		return
 	}
 
-	// Set cookies
-	h.setAuthCookies(c, accessToken, newRefreshToken.Token, accessExp)
+	// Only cookie clients get cookies back; body clients store the tokens themselves
+	if fromCookie {
+		middleware.SetAuthCookies(c, accessToken, newRefreshToken.Token, accessExp, newRefreshToken.ExpiresAt)
+	}
 
 	// Return tokens
 	common.SendSuccess(c, http.StatusOK, "Token refreshed successfully", gin.H{
-		"access_token":  accessToken,
-		"refresh_token": newRefreshToken.Token,
-		"token_type":    "Bearer",
-		"expires_in":    int64(time.Until(accessExp).Seconds()),
+		"access_token":       accessToken,
+		"refresh_token":      newRefreshToken.Token,
+		"token_type":         "Bearer",
+		"expires_in":         int64(time.Until(accessExp).Seconds()),
+		"refresh_expires_in": int64(time.Until(newRefreshToken.ExpiresAt).Seconds()),
 	})
 }
 
+// JWKS publishes the public keys used to sign access tokens
+// GET /.well-known/jwks.json
+func (h *TokenHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
+
 // RevokeToken revokes a specific refresh token
 // POST /api/auth/revoke-token
 func (h *TokenHandler) RevokeToken(c *gin.Context) {
@@ -123,6 +139,8 @@ func (h *TokenHandler) RevokeAllTokens(c *gin.Context) {
 
 // GetActiveTokens returns all active refresh tokens for the authenticated user
 // GET /api/auth/tokens
+//
+// Deprecated: use GET /api/auth/sessions, which groups tokens by session.
 func (h *TokenHandler) GetActiveTokens(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDValue, exists := c.Get("user_id")
@@ -168,55 +186,3 @@ func (h *TokenHandler) GetActiveTokens(c *gin.Context) {
 		"total":  len(tokenInfos),
 	})
 }
-
-// generateAccessToken generates a JWT access token
-func (h *TokenHandler) generateAccessToken(user models.Users) (string, time.Time, error) {
-	expirationTime := time.Now().Add(h.config.JWTExpiry)
-	claims := &models.Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		RoleID:   user.RoleID,
-		RoleName: user.Role.Name,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "sass-api",
-			Subject:   user.Username,
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(h.config.JWTSecret))
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return tokenString, expirationTime, nil
-}
-
-// setAuthCookies sets the access and refresh token cookies
-func (h *TokenHandler) setAuthCookies(c *gin.Context, accessToken, refreshToken string, accessExp time.Time) {
-	// Set access token cookie (shorter expiry)
-	c.SetCookie(
-		"access_token",
-		accessToken,
-		int(time.Until(accessExp).Seconds()),
-		"/",
-		"",
-		false, // set to true in production with HTTPS
-		true,  // httpOnly
-	)
-
-	// Set refresh token cookie (longer expiry)
-	c.SetCookie(
-		"refresh_token",
-		refreshToken,
-		int(h.config.RefreshTokenExpiry.Seconds()),
-		"/",
-		"",
-		false, // set to true in production with HTTPS
-		true,  // httpOnly
-	)
-}
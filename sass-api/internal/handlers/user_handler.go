@@ -5,6 +5,7 @@ import (
 
 	"github.com/Aebroyx/sass-api/internal/common"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
 	"github.com/Aebroyx/sass-api/internal/pagination"
 	"github.com/Aebroyx/sass-api/internal/services"
 	"github.com/gin-gonic/gin"
@@ -12,14 +13,16 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
-	validate    *validator.Validate
+	userService  *services.UserService
+	auditService *services.AuditService
+	validate     *validator.Validate
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
+func NewUserHandler(userService *services.UserService, auditService *services.AuditService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		validate:    validator.New(),
+		userService:  userService,
+		auditService: auditService,
+		validate:     validator.New(),
 	}
 }
 
@@ -38,7 +41,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	}
 
 	// Get users with pagination, search, and filters
-	response, err := h.userService.GetAllUsers(params)
+	response, err := h.userService.WithContext(c.Request.Context()).GetAllUsers(params)
 	if err != nil {
 		common.SendError(c, http.StatusInternalServerError, "Failed to fetch users", common.CodeInternalError, err.Error())
 		return
@@ -48,7 +51,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 }
 
 func (h *UserHandler) GetUserById(c *gin.Context) {
-	user, err := h.userService.GetUserById(c.Param("id"))
+	user, err := h.userService.WithContext(c.Request.Context()).GetUserById(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
@@ -78,13 +81,19 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	}
 
 	// Create user
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.WithContext(c.Request.Context()).CreateUser(&req)
 	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+
 		switch err.Error() {
 		case "username already exists":
 			common.SendError(c, http.StatusConflict, "Username already exists", common.CodeUsernameExists, nil)
 		case "email already exists":
 			common.SendError(c, http.StatusConflict, "Email already exists", common.CodeEmailExists, nil)
+		case "invalid role":
+			common.SendError(c, http.StatusBadRequest, "Invalid role", common.CodeValidationError, nil)
 		default:
 			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
 		}
@@ -108,8 +117,15 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// Update user
-	user, err := h.userService.UpdateUser(c.Param("id"), &req)
+	user, err := h.userService.WithContext(c.Request.Context()).UpdateUser(c.Param("id"), &req)
 	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+		if err.Error() == "invalid role" {
+			common.SendError(c, http.StatusBadRequest, "Invalid role", common.CodeValidationError, nil)
+			return
+		}
 		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
 		return
 	}
@@ -118,7 +134,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 }
 
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	user, err := h.userService.DeleteUser(c.Param("id"))
+	user, err := h.userService.WithContext(c.Request.Context()).DeleteUser(c.Param("id"))
 	if err != nil {
 		// Check for specific error messages
 		if err.Error() == "cannot delete root user" {
@@ -146,8 +162,12 @@ func (h *UserHandler) ResetUserPassword(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.ResetUserPassword(userID, &req)
+	user, err := h.userService.WithContext(c.Request.Context()).ResetUserPassword(userID, &req)
 	if err != nil {
+		if sendPasswordPolicyError(c, err) {
+			return
+		}
+
 		switch err.Error() {
 		case "invalid current password":
 			common.SendError(c, http.StatusBadRequest, "Invalid current password", common.CodeBadRequest, nil)
@@ -160,3 +180,79 @@ func (h *UserHandler) ResetUserPassword(c *gin.Context) {
 	}
 	common.SendSuccess(c, http.StatusOK, "User password reset successfully", user)
 }
+
+// UnlockUser lifts a lockout caused by failed login attempts
+// POST /api/user/unlock/:id
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	user, err := h.userService.WithContext(c.Request.Context()).UnlockUser(c.Param("id"))
+	if err != nil {
+		if err.Error() == "user not found" {
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+			return
+		}
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "ACCOUNT_UNLOCKED", "user", c.Param("id"), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "User unlocked successfully", user)
+}
+
+// Impersonate starts a short-lived session as another user so support staff can
+// reproduce their issue. The admin's own refresh token is left in place, so the
+// admin is signed back in as themselves once the session ends.
+// POST /api/user/impersonate/:id
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	impersonatorID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return
+	}
+
+	response, err := h.userService.WithContext(c.Request.Context()).Impersonate(impersonatorID, c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+		case "impersonation not allowed":
+			common.SendError(c, http.StatusForbidden, "Only administrators can impersonate users", common.CodeForbidden, nil)
+		case "cannot impersonate yourself":
+			common.SendError(c, http.StatusBadRequest, "You can't impersonate yourself", common.CodeBadRequest, nil)
+		case "cannot impersonate an administrator":
+			common.SendError(c, http.StatusForbidden, "Administrators can't be impersonated", common.CodeForbidden, nil)
+		case "user is not active":
+			common.SendError(c, http.StatusBadRequest, "User is not active", common.CodeBadRequest, nil)
+		default:
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+		}
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "IMPERSONATION_STARTED", "user", c.Param("id"), nil, gin.H{
+		"reason":     req.Reason,
+		"expires_at": response.ExpiresAt,
+	})
+
+	c.SetCookie(
+		"access_token",
+		response.AccessToken,
+		int(response.ExpiresIn),
+		"/",   // path
+		"",    // domain (empty for current domain)
+		false, // secure (set to false for development)
+		true,  // httpOnly
+	)
+
+	common.SendSuccess(c, http.StatusOK, "Impersonation started", response)
+}
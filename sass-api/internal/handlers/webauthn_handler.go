@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/Aebroyx/sass-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type WebAuthnHandler struct {
+	webAuthnService *services.WebAuthnService
+	userService     *services.UserService
+	auditService    *services.AuditService
+	validate        *validator.Validate
+}
+
+func NewWebAuthnHandler(webAuthnService *services.WebAuthnService, userService *services.UserService, auditService *services.AuditService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webAuthnService: webAuthnService,
+		userService:     userService,
+		auditService:    auditService,
+		validate:        validator.New(),
+	}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create
+// POST /api/auth/webauthn/register/begin
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.webAuthnService.BeginRegistration(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Passkey registration started", response)
+}
+
+// FinishRegistration verifies the new credential and stores it
+// POST /api/auth/webauthn/register/finish
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebAuthnFinishRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "PASSKEY_REGISTERED", "webauthn_credential", strconv.FormatUint(uint64(credential.ID), 10), nil, credential)
+
+	common.SendSuccess(c, http.StatusCreated, "Passkey registered successfully", credential)
+}
+
+// BeginLogin returns the options for navigator.credentials.get
+// POST /api/auth/webauthn/login/begin
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	var req models.WebAuthnBeginLoginRequest
+	// The body is optional for discoverable logins
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	response, err := h.webAuthnService.BeginLogin(req.Username)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Passkey login started", response)
+}
+
+// FinishLogin verifies the assertion and signs the user in
+// POST /api/auth/webauthn/login/finish
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req models.WebAuthnFinishRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	response, err := h.userService.LoginWithWebAuthn(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if err.Error() == "invalid passkey" {
+			middleware.LogLoginAction(c, h.auditService, 0, "", false)
+		}
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.LogLoginAction(c, h.auditService, response.User.ID, response.User.Username, true)
+
+	setLoginCookies(c, response.Token)
+
+	// Return user data only (tokens are in cookies)
+	c.JSON(http.StatusOK, gin.H{
+		"user": response.User,
+	})
+}
+
+// ListCredentials returns the authenticated user's passkeys
+// GET /api/auth/webauthn/credentials
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.webAuthnService.ListCredentials(userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	common.SendSuccess(c, http.StatusOK, "Passkeys retrieved successfully", gin.H{
+		"credentials": credentials,
+		"total":       len(credentials),
+	})
+}
+
+// DeleteCredential revokes one of the authenticated user's passkeys
+// DELETE /api/auth/webauthn/credentials/:id
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid passkey ID", common.CodeBadRequest, nil)
+		return
+	}
+
+	if err := h.webAuthnService.DeleteCredential(userID, uint(credentialID)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	middleware.AuditAction(c, h.auditService, "PASSKEY_REVOKED", "webauthn_credential", c.Param("id"), nil, nil)
+
+	common.SendSuccess(c, http.StatusOK, "Passkey revoked successfully", nil)
+}
+
+// bind decodes and validates the request body, writing the error response on failure
+func (h *WebAuthnHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Invalid request body", common.CodeInvalidRequest, err.Error())
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		common.SendError(c, http.StatusBadRequest, "Validation failed", common.CodeValidationError, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// handleError maps WebAuthn service errors to HTTP responses
+func (h *WebAuthnHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		common.SendError(c, http.StatusNotFound, "User not found", common.CodeNotFound, nil)
+	case "passkey not found":
+		common.SendError(c, http.StatusNotFound, "Passkey not found", common.CodeNotFound, nil)
+	case "passkey already registered":
+		common.SendError(c, http.StatusConflict, "Passkey is already registered", common.CodeConflict, nil)
+	case "webauthn session not found":
+		common.SendError(c, http.StatusBadRequest, "Passkey ceremony is invalid or has expired", common.CodeBadRequest, nil)
+	case "invalid passkey":
+		common.SendError(c, http.StatusUnauthorized, "Passkey verification failed", common.CodeUnauthorized, nil)
+	case "user is not active":
+		common.SendError(c, http.StatusForbidden, "User is not active", common.CodeForbidden, nil)
+	case "account is locked":
+		common.SendError(c, http.StatusLocked, "Account is temporarily locked due to too many failed login attempts", common.CodeLocked, nil)
+	case "session limit reached":
+		common.SendError(c, http.StatusConflict, sessionLimitMessage, common.CodeSessionLimit, nil)
+	default:
+		common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+	}
+}
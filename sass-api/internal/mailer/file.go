@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer writes each message to an .eml file, for development and tests
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a file mailer, creating the output directory if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the message to a new file in the mail directory
+func (m *FileMailer) Send(msg Message) error {
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s_%s.eml", time.Now().Format("20060102T150405"), recipient, uuid.New().String()[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o600)
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"github.com/Aebroyx/sass-api/internal/logger"
+)
+
+// LogMailer writes messages to the application log instead of sending them. The body
+// is left out because it carries live reset, verification and sign-in links; use the
+// file driver to read messages during development.
+type LogMailer struct{}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the recipient and subject of the message
+func (m *LogMailer) Send(msg Message) error {
+	logger.InfoWithContext("Email (not sent, MAIL_DRIVER=log)", "", map[string]interface{}{
+		"to":         msg.To,
+		"subject":    msg.Subject,
+		"body_bytes": len(msg.Body),
+	})
+	return nil
+}
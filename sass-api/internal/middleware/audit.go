@@ -80,19 +80,26 @@ func AuditLogger(auditService *services.AuditService) gin.HandlerFunc {
 			}
 		}
 
+		// Record the real actor when an admin is impersonating the user
+		impersonatorID, impersonatorUsername := GetImpersonator(c)
+		organizationID := GetOrganizationID(c)
+
 		// Create audit log entry
 		go func() {
 			_ = auditService.Log(&models.CreateAuditLogRequest{
-				UserID:        userID,
-				Username:      username,
-				Action:        action,
-				ResourceType:  resourceType,
-				ResourceID:    resourceID,
-				OldValues:     "", // Could be populated for UPDATE operations if needed
-				NewValues:     newValues,
-				IPAddress:     ipAddress,
-				UserAgent:     userAgent,
-				CorrelationID: correlationID,
+				UserID:               userID,
+				Username:             username,
+				Action:               action,
+				ResourceType:         resourceType,
+				ResourceID:           resourceID,
+				OldValues:            "", // Could be populated for UPDATE operations if needed
+				NewValues:            newValues,
+				IPAddress:            ipAddress,
+				UserAgent:            userAgent,
+				CorrelationID:        correlationID,
+				ImpersonatorID:       impersonatorID,
+				ImpersonatorUsername: impersonatorUsername,
+				OrganizationID:       organizationID,
 			})
 		}()
 	}
@@ -195,23 +202,23 @@ func AuditAction(c *gin.Context, auditService *services.AuditService, action, re
 		}
 	}
 
-	correlationID := GetCorrelationID(c)
-	ipAddress := c.ClientIP()
-	userAgent := c.Request.UserAgent()
+	impersonatorID, impersonatorUsername := GetImpersonator(c)
+	req := &models.CreateAuditLogRequest{
+		UserID:               userID,
+		Username:             username,
+		Action:               action,
+		ResourceType:         resourceType,
+		ResourceID:           resourceID,
+		IPAddress:            c.ClientIP(),
+		UserAgent:            c.Request.UserAgent(),
+		CorrelationID:        GetCorrelationID(c),
+		ImpersonatorID:       impersonatorID,
+		ImpersonatorUsername: impersonatorUsername,
+		OrganizationID:       GetOrganizationID(c),
+	}
 
 	go func() {
-		_ = auditService.LogWithContext(
-			userID,
-			username,
-			action,
-			resourceType,
-			resourceID,
-			oldValues,
-			newValues,
-			ipAddress,
-			userAgent,
-			correlationID,
-		)
+		_ = auditService.LogEntry(req, oldValues, newValues)
 	}()
 }
 
@@ -255,17 +262,20 @@ func LogLogoutAction(c *gin.Context, auditService *services.AuditService, userID
 	correlationID := GetCorrelationID(c)
 	ipAddress := c.ClientIP()
 	userAgent := c.Request.UserAgent()
+	impersonatorID, impersonatorUsername := GetImpersonator(c)
 
 	go func() {
 		_ = auditService.Log(&models.CreateAuditLogRequest{
-			UserID:        &userID,
-			Username:      username,
-			Action:        "LOGOUT",
-			ResourceType:  "auth",
-			ResourceID:    fmt.Sprintf("%d", userID),
-			IPAddress:     ipAddress,
-			UserAgent:     userAgent,
-			CorrelationID: correlationID,
+			UserID:               &userID,
+			Username:             username,
+			Action:               "LOGOUT",
+			ResourceType:         "auth",
+			ResourceID:           fmt.Sprintf("%d", userID),
+			IPAddress:            ipAddress,
+			UserAgent:            userAgent,
+			CorrelationID:        correlationID,
+			ImpersonatorID:       impersonatorID,
+			ImpersonatorUsername: impersonatorUsername,
 		})
 	}()
 }
@@ -1,46 +1,94 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"log"
 
+	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-func Auth(jwtSecret string, db *gorm.DB) gin.HandlerFunc {
+const (
+	// Context key for the API key that authenticated the request
+	APIKeyKey = "apiKey"
+
+	// Context keys for the session the access token was issued for, and when the user
+	// last authenticated in it
+	SessionIDKey = "session_id"
+	AuthTimeKey  = "auth_time"
+
+	// Context keys for the admin behind an impersonation session
+	ImpersonatorIDKey       = "impersonator_id"
+	ImpersonatorUsernameKey = "impersonator_username"
+
+	// Access token sources accepted in config.AuthTokenSources
+	tokenSourceCookie = "cookie"
+	tokenSourceHeader = "header"
+)
+
+func Auth(cfg *config.Config, db *gorm.DB, tokenService *services.TokenService, apiKeyService *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get access token from cookie
-		accessToken, err := c.Cookie("access_token")
-		if err != nil {
+		// Machine clients authenticate with an API key instead of session cookies
+		if rawKey := apiKeyFromRequest(c); rawKey != "" {
+			authenticateAPIKey(c, db, apiKeyService, rawKey)
+			return
+		}
+
+		// Get access token from the configured sources
+		accessToken, source := accessTokenFromRequest(c, cfg.AuthTokenSources)
+		if accessToken == "" {
 			// If access token is not found, try to refresh using refresh token
+			if !slices.Contains(cfg.AuthTokenSources, tokenSourceCookie) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+				c.Abort()
+				return
+			}
 			if _, err := c.Cookie("refresh_token"); err != nil {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 				c.Abort()
 				return
 			}
 
-			// TODO: Implement token refresh logic
-			// For now, just return unauthorized
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token expired"})
-			c.Abort()
-			return
+			var err error
+			accessToken, err = refreshSession(c, db, tokenService)
+			if err != nil {
+				log.Printf("Auth middleware: token refresh failed: %v", err)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token expired"})
+				c.Abort()
+				return
+			}
+			source = tokenSourceCookie
 		}
 
 		// Parse and validate token
-		claims := &models.Claims{}
-		token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (any, error) {
-			return []byte(jwtSecret), nil
-		})
+		claims, token, err := tokenService.ParseAccessToken(accessToken)
+
+		// An access token that outlived its cookie can still be renewed while the refresh token is valid.
+		// Header clients hold their own refresh token and renew through /auth/refresh-token instead.
+		if errors.Is(err, jwt.ErrTokenExpired) && source == tokenSourceCookie {
+			if _, cookieErr := c.Cookie("refresh_token"); cookieErr == nil {
+				if accessToken, refreshErr := refreshSession(c, db, tokenService); refreshErr == nil {
+					claims, token, err = tokenService.ParseAccessToken(accessToken)
+				} else {
+					log.Printf("Auth middleware: token refresh failed: %v", refreshErr)
+				}
+			}
+		}
 
 		if err != nil {
-			switch err {
-			case jwt.ErrSignatureInvalid:
+			switch {
+			case errors.Is(err, jwt.ErrSignatureInvalid), errors.Is(err, jwt.ErrTokenSignatureInvalid):
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token signature"})
-			case jwt.ErrTokenExpired:
+			case errors.Is(err, jwt.ErrTokenExpired):
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
 			default:
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -55,42 +103,254 @@ func Auth(jwtSecret string, db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Get user from database with Role preloaded
+		// Access tokens last only as long as their session: signing out revokes its refresh
+		// tokens, and an idle timeout or absolute lifetime lets them expire
+		if claims.SessionID != "" {
+			active, err := tokenService.SessionActive(claims.SessionID)
+			if err != nil {
+				log.Printf("Auth middleware: failed to check session %s: %v", claims.SessionID, err)
+			}
+			if !active {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Get user from database with roles preloaded
 		var user models.Users
-		if err := db.Preload("Role").First(&user, claims.UserID).Error; err != nil {
+		if err := db.Preload("Role").Preload("UserRoles.Role").First(&user, claims.UserID).Error; err != nil {
 			log.Printf("Auth middleware: user not found in database for ID %d", claims.UserID)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			c.Abort()
 			return
 		}
 
-		// Create user response object with role details
-		userResponse := models.RegisterResponse{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Name:     user.Name,
-			Role: models.RoleResponse{
-				ID:          user.Role.ID,
-				Name:        user.Role.Name,
-				DisplayName: user.Role.DisplayName,
-				Description: user.Role.Description,
-				IsDefault:   user.Role.IsDefault,
-				IsActive:    user.Role.IsActive,
-				CreatedAt:   user.Role.CreatedAt,
-				UpdatedAt:   user.Role.UpdatedAt,
-			},
+		// A token issued for another organization is stale once the user moved
+		if claims.OrganizationID != 0 && (user.OrganizationID == nil || *user.OrganizationID != claims.OrganizationID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
 		}
 
-		log.Printf("Auth middleware: setting user in context: %+v", userResponse)
+		setUserContext(c, user)
+		if claims.SessionID != "" {
+			c.Set(SessionIDKey, claims.SessionID)
+		}
+		if claims.AuthTime != nil {
+			c.Set(AuthTimeKey, claims.AuthTime.Time)
+		}
 
-		// Set user in context
-		c.Set("user", userResponse)
-		c.Set("user_id", user.ID)
-		c.Set("username", user.Username)
-		c.Set("role", user.Role.Name)
-		c.Set("roleID", user.RoleID)
+		// An impersonation session ends as soon as the admin behind it loses the right to impersonate
+		if claims.ImpersonatorID != 0 {
+			var impersonator models.Users
+			if err := db.Preload("Role").Preload("UserRoles.Role").First(&impersonator, claims.ImpersonatorID).Error; err != nil ||
+				!impersonator.IsActive || !impersonator.HasAnyRole(config.ImpersonatorRoles) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Impersonation session is no longer valid"})
+				c.Abort()
+				return
+			}
+			setImpersonatorContext(c, impersonator)
+		}
 
 		c.Next()
 	}
 }
+
+// GetImpersonator returns the admin acting as the authenticated user, if any
+func GetImpersonator(c *gin.Context) (*uint, string) {
+	id, exists := c.Get(ImpersonatorIDKey)
+	if !exists {
+		return nil, ""
+	}
+	impersonatorID, ok := id.(uint)
+	if !ok {
+		return nil, ""
+	}
+	return &impersonatorID, c.GetString(ImpersonatorUsernameKey)
+}
+
+// authenticateAPIKey authenticates the request as the owner of an API key
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, apiKeyService *services.APIKeyService, rawKey string) {
+	key, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		if err.Error() == "api key expired" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has expired"})
+		} else {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	var user models.Users
+	if err := db.Preload("Role").Preload("UserRoles.Role").First(&user, key.UserID).Error; err != nil {
+		log.Printf("Auth middleware: owner of API key %d not found", key.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	}
+
+	if !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not active"})
+		c.Abort()
+		return
+	}
+
+	setUserContext(c, user)
+	c.Set(APIKeyKey, key)
+
+	c.Next()
+}
+
+// accessTokenFromRequest returns the access token from the first configured source that has one
+func accessTokenFromRequest(c *gin.Context, sources []string) (string, string) {
+	for _, source := range sources {
+		switch source {
+		case tokenSourceCookie:
+			if token, err := c.Cookie("access_token"); err == nil && token != "" {
+				return token, source
+			}
+		case tokenSourceHeader:
+			if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+				return token, source
+			}
+		}
+	}
+	return "", ""
+}
+
+// apiKeyFromRequest returns the API key sent in the X-API-Key header or as a bearer token
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && services.IsAPIKey(token) {
+		return token
+	}
+
+	return ""
+}
+
+// setUserContext stores the authenticated user in the request context
+func setUserContext(c *gin.Context, user models.Users) {
+	// Create user response object with role details
+	userResponse := models.RegisterResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Name:     user.Name,
+		IsActive: user.IsActive,
+		Role: models.RoleResponse{
+			ID:          user.Role.ID,
+			Name:        user.Role.Name,
+			DisplayName: user.Role.DisplayName,
+			Description: user.Role.Description,
+			IsDefault:   user.Role.IsDefault,
+			IsActive:    user.Role.IsActive,
+			RequireMFA:  user.RequiresMFA(),
+			CreatedAt:   user.Role.CreatedAt,
+			UpdatedAt:   user.Role.UpdatedAt,
+		},
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		OrganizationID: user.OrganizationID,
+	}
+	for _, role := range user.Roles() {
+		userResponse.Roles = append(userResponse.Roles, models.RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			IsDefault:   role.IsDefault,
+			IsActive:    role.IsActive,
+			RequireMFA:  role.RequireMFA,
+			CreatedAt:   role.CreatedAt,
+			UpdatedAt:   role.UpdatedAt,
+		})
+	}
+
+	log.Printf("Auth middleware: setting user in context: %+v", userResponse)
+
+	// Set user in context
+	c.Set("user", userResponse)
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role.Name)
+	c.Set("roleID", user.RoleID)
+	c.Set("roleIDs", user.RoleIDs())
+	c.Set("roles", user.RoleNames())
+}
+
+// setImpersonatorContext records the admin behind an impersonation session, so audit
+// logs name the real actor and the client can show who is impersonating
+func setImpersonatorContext(c *gin.Context, impersonator models.Users) {
+	c.Set(ImpersonatorIDKey, impersonator.ID)
+	c.Set(ImpersonatorUsernameKey, impersonator.Username)
+
+	if userVal, exists := c.Get("user"); exists {
+		if userResponse, ok := userVal.(models.RegisterResponse); ok {
+			userResponse.ImpersonatedBy = &models.ImpersonatorResponse{
+				ID:       impersonator.ID,
+				Username: impersonator.Username,
+			}
+			c.Set("user", userResponse)
+		}
+	}
+}
+
+// refreshSession rotates the refresh token cookie, issues a new access token and
+// sets both cookies on the response so the current request can continue
+func refreshSession(c *gin.Context, db *gorm.DB, tokenService *services.TokenService) (string, error) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil {
+		return "", err
+	}
+
+	newRefreshToken, err := tokenService.RotateRefreshToken(refreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return "", err
+	}
+
+	var user models.Users
+	if err := db.Preload("Role").Preload("UserRoles.Role").First(&user, newRefreshToken.UserID).Error; err != nil {
+		return "", errors.New("user not found")
+	}
+
+	if !user.IsActive {
+		return "", errors.New("user is not active")
+	}
+
+	accessToken, accessExp, err := tokenService.GenerateAccessToken(user, newRefreshToken.FamilyID)
+	if err != nil {
+		return "", err
+	}
+
+	SetAuthCookies(c, accessToken, newRefreshToken.Token, accessExp, newRefreshToken.ExpiresAt)
+
+	return accessToken, nil
+}
+
+// SetAuthCookies sets the access and refresh token cookies. Each cookie expires with its
+// token, so the refresh cookie doesn't outlive the session's idle timeout or lifetime.
+func SetAuthCookies(c *gin.Context, accessToken, refreshToken string, accessExp, refreshExp time.Time) {
+	c.SetCookie(
+		"access_token",
+		accessToken,
+		int(time.Until(accessExp).Seconds()),
+		"/",
+		"",
+		false, // set to true in production with HTTPS
+		true,  // httpOnly
+	)
+
+	c.SetCookie(
+		"refresh_token",
+		refreshToken,
+		int(time.Until(refreshExp).Seconds()),
+		"/",
+		"",
+		false, // set to true in production with HTTPS
+		true,  // httpOnly
+	)
+}
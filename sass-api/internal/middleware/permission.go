@@ -27,6 +27,23 @@ func Permission(permService *services.PermissionService) gin.HandlerFunc {
 			return
 		}
 
+		// API keys are scoped to menu permissions and never reach account management routes
+		apiKeyVal, usingAPIKey := c.Get(APIKeyKey)
+		if usingAPIKey && services.IsAPIKeyBlocked(path) {
+			log.Printf("Permission middleware: API key denied access to %s %s", method, path)
+			common.SendError(c, http.StatusForbidden, "API keys cannot be used for this route", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
+		// Impersonation sessions can't change the user's credentials or impersonate further
+		if _, impersonating := c.Get(ImpersonatorIDKey); impersonating && services.IsImpersonationBlocked(path) {
+			log.Printf("Permission middleware: impersonation session denied access to %s %s", method, path)
+			common.SendError(c, http.StatusForbidden, "This action is not available while impersonating a user", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
 		// Check if route is whitelisted
 		if services.IsWhitelisted(method, path) {
 			log.Printf("Permission middleware: route %s %s is whitelisted, skipping check", method, path)
@@ -37,6 +54,14 @@ func Permission(permService *services.PermissionService) gin.HandlerFunc {
 		// Find permission requirement for this route
 		routePerm, found := services.FindRoutePermission(method, path)
 		if !found {
+			// An API key's scopes can't cover a route without a permission mapping
+			if usingAPIKey {
+				log.Printf("Permission middleware: API key denied unmapped route %s %s", method, path)
+				common.SendError(c, http.StatusForbidden, "API keys cannot be used for this route", common.CodeForbidden, nil)
+				c.Abort()
+				return
+			}
+
 			// If no permission mapping exists, allow the request
 			// This handles routes not explicitly configured
 			log.Printf("Permission middleware: no permission mapping for %s %s, allowing", method, path)
@@ -61,18 +86,18 @@ func Permission(permService *services.PermissionService) gin.HandlerFunc {
 			return
 		}
 
-		// Get roleID from context
-		roleIDVal, exists := c.Get("roleID")
+		// Get the user's role IDs from context
+		roleIDsVal, exists := c.Get("roleIDs")
 		if !exists {
-			log.Printf("Permission middleware: roleID not found in context")
+			log.Printf("Permission middleware: roleIDs not found in context")
 			common.SendError(c, http.StatusUnauthorized, "User role not found", common.CodeUnauthorized, nil)
 			c.Abort()
 			return
 		}
 
-		roleID, ok := roleIDVal.(uint)
+		roleIDs, ok := roleIDsVal.([]uint)
 		if !ok {
-			log.Printf("Permission middleware: invalid roleID type in context")
+			log.Printf("Permission middleware: invalid roleIDs type in context")
 			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
 			c.Abort()
 			return
@@ -85,13 +110,17 @@ func Permission(permService *services.PermissionService) gin.HandlerFunc {
 		} else {
 			// Fetch user permissions
 			var err error
-			userPerms, err = permService.GetUserPermissions(user.ID, roleID)
+			userPerms, err = permService.GetUserPermissions(user.ID, roleIDs)
 			if err != nil {
 				log.Printf("Permission middleware: failed to get permissions for user %d: %v", user.ID, err)
 				common.SendError(c, http.StatusInternalServerError, "Failed to check permissions", common.CodeInternalError, nil)
 				c.Abort()
 				return
 			}
+			// Limit the owner's permissions to what the key was granted
+			if usingAPIKey {
+				userPerms = userPerms.RestrictToScopes(services.KeyScopes(apiKeyVal.(*models.APIKey)))
+			}
 			// Cache in context for subsequent checks in same request
 			c.Set(UserPermissionsKey, userPerms)
 		}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Aebroyx/sass-api/internal/common"
 	"github.com/Aebroyx/sass-api/internal/services"
@@ -86,3 +87,20 @@ func RateLimitByUser(rateLimiter *services.RateLimiterService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RateLimitByKey creates a middleware that applies a stricter, endpoint specific
+// limit per IP address on top of the global limits (e.g. for forgot-password)
+func RateLimitByKey(rateLimiter *services.RateLimiterService, scope string, maxRequests int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, resetDuration := rateLimiter.AllowKey(scope+":"+c.ClientIP(), maxRequests, window)
+		if !allowed {
+			c.Writer.Header().Set("Retry-After", fmt.Sprintf("%.0f", resetDuration.Seconds()))
+
+			common.SendError(c, http.StatusTooManyRequests, "Too many requests, please try again later", "RATE_LIMIT_EXCEEDED", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRecentAuth guards sensitive routes: the user must have signed in or
+// re-authenticated (POST /api/auth/reauthenticate) within maxAge. API keys have no
+// interactive session and are governed by their scopes instead.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get(APIKeyKey); isAPIKey {
+			c.Next()
+			return
+		}
+
+		authTime, ok := c.Get(AuthTimeKey)
+		if at, isTime := authTime.(time.Time); !ok || !isTime || time.Since(at) > maxAge {
+			common.SendError(c, http.StatusForbidden, "Please confirm your password to continue", common.CodeReauthRequired, gin.H{
+				"max_age_seconds": int64(maxAge.Seconds()),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
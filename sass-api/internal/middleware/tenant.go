@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Aebroyx/sass-api/internal/common"
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// Context key for the organization the request acts in
+	OrganizationIDKey = "organization_id"
+
+	// Header naming the organization by slug, for clients that don't use subdomains
+	organizationHeader = "X-Organization"
+)
+
+// Tenant resolves the organization a request acts in and scopes the request context to
+// it, so services that query through the context only see that organization's data.
+//
+// Users act in their own organization; naming another one is forbidden. Cross-tenant
+// roles (root) see every organization unless they name one in the X-Organization header
+// or the subdomain.
+func Tenant(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userVal, exists := c.Get("user")
+		if !exists {
+			common.SendError(c, http.StatusUnauthorized, "User not authenticated", common.CodeUnauthorized, nil)
+			c.Abort()
+			return
+		}
+		user, ok := userVal.(models.RegisterResponse)
+		if !ok {
+			common.SendError(c, http.StatusInternalServerError, "Internal server error", common.CodeInternalError, nil)
+			c.Abort()
+			return
+		}
+
+		slug := requestedOrganization(c, cfg.TenantBaseDomain)
+
+		if slices.Contains(config.CrossTenantRoles, user.Role.Name) {
+			if slug == "" {
+				c.Next()
+				return
+			}
+
+			var organization models.Organization
+			if err := db.Where("slug = ?", slug).First(&organization).Error; err != nil {
+				common.SendError(c, http.StatusNotFound, "Organization not found", common.CodeNotFound, nil)
+				c.Abort()
+				return
+			}
+			setOrganizationContext(c, organization.ID)
+			c.Next()
+			return
+		}
+
+		if user.OrganizationID == nil {
+			log.Printf("Tenant middleware: user %d has no organization", user.ID)
+			common.SendError(c, http.StatusForbidden, "You are not a member of any organization", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
+		var organization models.Organization
+		if err := db.First(&organization, *user.OrganizationID).Error; err != nil || !organization.IsActive {
+			common.SendError(c, http.StatusForbidden, "Your organization is not active", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
+		if slug != "" && slug != organization.Slug {
+			log.Printf("Tenant middleware: user %d denied access to organization %q", user.ID, slug)
+			common.SendError(c, http.StatusForbidden, "You are not a member of this organization", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
+		setOrganizationContext(c, organization.ID)
+		c.Next()
+	}
+}
+
+// GetOrganizationID returns the organization the request acts in, if any
+func GetOrganizationID(c *gin.Context) *uint {
+	id, exists := c.Get(OrganizationIDKey)
+	if !exists {
+		return nil
+	}
+	organizationID, ok := id.(uint)
+	if !ok {
+		return nil
+	}
+	return &organizationID
+}
+
+// setOrganizationContext scopes the gin context and the request context to the organization
+func setOrganizationContext(c *gin.Context, organizationID uint) {
+	c.Set(OrganizationIDKey, organizationID)
+	c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), organizationID))
+}
+
+// requestedOrganization returns the organization slug named by the X-Organization
+// header, or by the subdomain of baseDomain the request was sent to
+func requestedOrganization(c *gin.Context, baseDomain string) string {
+	if slug := strings.TrimSpace(c.GetHeader(organizationHeader)); slug != "" {
+		return strings.ToLower(slug)
+	}
+
+	if baseDomain == "" {
+		return ""
+	}
+
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+baseDomain)
+	if !ok || subdomain == "" || strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
+
+// RequireCrossTenant guards platform administration routes, such as managing
+// organizations, that only cross-tenant roles may use
+func RequireCrossTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(config.CrossTenantRoles, c.GetString("role")) {
+			common.SendError(c, http.StatusForbidden, "You do not have permission to perform this action", common.CodeForbidden, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -159,7 +159,8 @@ type Paginator struct {
 	db *gorm.DB
 }
 
-// NewPaginator creates a new paginator instance
+// NewPaginator creates a new paginator instance. Queries run with db's context, so a
+// db bound to an organization (see the tenant package) only pages through its rows.
 func NewPaginator(db *gorm.DB) *Paginator {
 	return &Paginator{db: db}
 }
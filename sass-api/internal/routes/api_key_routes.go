@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIKeyRoutes registers API key self-service routes (all protected)
+func RegisterAPIKeyRoutes(router *gin.RouterGroup, h *handlers.APIKeyHandler) {
+	apiKeys := router.Group("/auth/api-keys")
+	{
+		apiKeys.GET("", h.ListKeys)
+		apiKeys.POST("", h.CreateKey)
+		apiKeys.DELETE("/:id", h.RevokeKey)
+	}
+}
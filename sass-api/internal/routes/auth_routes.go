@@ -18,4 +18,12 @@ func RegisterAuthPublicRoutes(router *gin.RouterGroup, h *handlers.AuthHandler)
 func RegisterAuthProtectedRoutes(router *gin.RouterGroup, h *handlers.AuthHandler) {
 	router.GET("/me", h.GetMe)
 	router.POST("/auth/logout", h.Logout)
+	router.POST("/auth/impersonation/stop", h.StopImpersonation)
+	router.POST("/auth/reauthenticate", h.Reauthenticate)
+}
+
+// RegisterEmailVerificationRoutes registers protected email management routes
+// Note: /verify-email and /resend-verification are registered as public routes in router.go
+func RegisterEmailVerificationRoutes(router *gin.RouterGroup, h *handlers.EmailVerificationHandler) {
+	router.POST("/auth/change-email", h.ChangeEmail)
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterInvitationRoutes registers organization invitation management routes (all protected).
+// Accepting an invitation is a public auth route.
+func RegisterInvitationRoutes(router *gin.RouterGroup, h *handlers.InvitationHandler) {
+	router.GET("/invitations", h.GetPendingInvitations)
+
+	invitation := router.Group("/invitation")
+	{
+		invitation.POST("/create", h.CreateInvitation)
+		invitation.POST("/:id/resend", h.ResendInvitation)
+		invitation.DELETE("/:id", h.RevokeInvitation)
+	}
+}
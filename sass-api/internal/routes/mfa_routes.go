@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMFARoutes registers MFA self-service routes (all protected)
+// Note: /mfa/verify and /mfa/challenge/enroll are registered as public routes in router.go
+func RegisterMFARoutes(router *gin.RouterGroup, h *handlers.MFAHandler) {
+	mfa := router.Group("/auth/mfa")
+	{
+		mfa.GET("", h.GetStatus)
+		mfa.POST("/enroll", h.BeginEnrollment)
+		mfa.POST("/enroll/confirm", h.ConfirmEnrollment)
+		mfa.POST("/disable", h.Disable)
+		mfa.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOAuthRoutes registers the consent and OAuth client management routes (all protected)
+// Note: the token, introspection, revocation and userinfo endpoints are public routes in router.go
+func RegisterOAuthRoutes(router *gin.RouterGroup, h *handlers.OAuthHandler) {
+	oauth := router.Group("/oauth")
+	{
+		oauth.GET("/authorize", h.GetConsent)
+		oauth.POST("/authorize", h.Authorize)
+
+		oauth.GET("/clients", h.ListClients)
+		oauth.POST("/clients", h.CreateClient)
+		oauth.DELETE("/clients/:id", h.DeleteClient)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/Aebroyx/sass-api/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOrganizationRoutes registers organization management routes, which only
+// cross-tenant roles can use. Changing or deleting an organization requires a recent
+// re-authentication.
+func RegisterOrganizationRoutes(router *gin.RouterGroup, h *handlers.OrganizationHandler, reauth gin.HandlerFunc) {
+	crossTenant := middleware.RequireCrossTenant()
+
+	router.GET("/organizations", crossTenant, h.GetAllOrganizations)
+
+	organization := router.Group("/organization")
+	organization.Use(crossTenant)
+	{
+		organization.GET("/:id", h.GetOrganizationByID)
+		organization.POST("/create", h.CreateOrganization)
+		organization.PUT("/:id", reauth, h.UpdateOrganization)
+		organization.DELETE("/:id", reauth, h.DeleteOrganization)
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterRightsAccessRoutes registers permission override routes
-func RegisterRightsAccessRoutes(router *gin.RouterGroup, h *handlers.RightsAccessHandler) {
+// RegisterRightsAccessRoutes registers permission override routes. Editing overrides
+// requires a recent re-authentication.
+func RegisterRightsAccessRoutes(router *gin.RouterGroup, h *handlers.RightsAccessHandler, reauth gin.HandlerFunc) {
 	ra := router.Group("/rights-access")
 	{
+		// List time-bound grants that expire soon
+		ra.GET("/expiring", h.GetExpiringGrants)
+
 		// Get all permission overrides for a user
 		ra.GET("/user/:userId", h.GetUserRightsAccess)
 
@@ -16,15 +20,15 @@ func RegisterRightsAccessRoutes(router *gin.RouterGroup, h *handlers.RightsAcces
 		ra.GET("/user/:userId/menu/:menuId", h.GetUserMenuRightsAccess)
 
 		// Create or update permission override
-		ra.POST("", h.CreateOrUpdateRightsAccess)
+		ra.POST("", reauth, h.CreateOrUpdateRightsAccess)
 
 		// Bulk save permission overrides for a user
-		ra.POST("/user/:userId/bulk", h.BulkSaveUserRightsAccess)
+		ra.POST("/user/:userId/bulk", reauth, h.BulkSaveUserRightsAccess)
 
 		// Delete all permission overrides for a user
-		ra.DELETE("/user/:userId", h.DeleteAllUserRightsAccess)
+		ra.DELETE("/user/:userId", reauth, h.DeleteAllUserRightsAccess)
 
 		// Delete permission override
-		ra.DELETE("/:id", h.DeleteRightsAccess)
+		ra.DELETE("/:id", reauth, h.DeleteRightsAccess)
 	}
 }
@@ -5,8 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterRoleRoutes registers role management routes
-func RegisterRoleRoutes(router *gin.RouterGroup, h *handlers.RoleHandler) {
+// RegisterRoleRoutes registers role management routes. Changing or deleting a role and
+// its menu permissions requires a recent re-authentication.
+func RegisterRoleRoutes(router *gin.RouterGroup, h *handlers.RoleHandler, reauth gin.HandlerFunc) {
 	// List roles
 	router.GET("/roles", h.GetAllRoles)
 	router.GET("/roles/active", h.GetActiveRoles)
@@ -16,12 +17,15 @@ func RegisterRoleRoutes(router *gin.RouterGroup, h *handlers.RoleHandler) {
 	{
 		role.GET("/:id", h.GetRoleByID)
 		role.POST("/create", h.CreateRole)
-		role.PUT("/:id", h.UpdateRole)
-		role.DELETE("/:id", h.DeleteRole)
+		role.PUT("/:id", reauth, h.UpdateRole)
+		role.DELETE("/:id", reauth, h.DeleteRole)
 
 		// Role-Menu assignments
 		role.GET("/:id/menus", h.GetRoleMenus)
-		role.POST("/:id/menus", h.AssignMenusToRole)
-		role.DELETE("/:id/menus/:menuId", h.RemoveMenuFromRole)
+		role.POST("/:id/menus", reauth, h.AssignMenusToRole)
+		role.DELETE("/:id/menus/:menuId", reauth, h.RemoveMenuFromRole)
+
+		// Permissions including those inherited from parent roles
+		role.GET("/:id/effective-permissions", h.GetEffectivePermissions)
 	}
 }
@@ -13,21 +13,35 @@ import (
 
 // Handlers holds all handler instances
 type Handlers struct {
-	Auth         *handlers.AuthHandler
-	User         *handlers.UserHandler
-	Role         *handlers.RoleHandler
-	Menu         *handlers.MenuHandler
-	RightsAccess *handlers.RightsAccessHandler
-	Search       *handlers.SearchHandler
-	Token        *handlers.TokenHandler
-	Audit        *handlers.AuditHandler
+	Auth              *handlers.AuthHandler
+	User              *handlers.UserHandler
+	Role              *handlers.RoleHandler
+	Menu              *handlers.MenuHandler
+	RightsAccess      *handlers.RightsAccessHandler
+	Search            *handlers.SearchHandler
+	Token             *handlers.TokenHandler
+	Audit             *handlers.AuditHandler
+	MFA               *handlers.MFAHandler
+	WebAuthn          *handlers.WebAuthnHandler
+	PasswordReset     *handlers.PasswordResetHandler
+	EmailVerification *handlers.EmailVerificationHandler
+	PasswordPolicy    *handlers.PasswordPolicyHandler
+	APIKey            *handlers.APIKeyHandler
+	OIDC              *handlers.OIDCHandler
+	OAuth             *handlers.OAuthHandler
+	Session           *handlers.SessionHandler
+	MagicLink         *handlers.MagicLinkHandler
+	Organization      *handlers.OrganizationHandler
+	Invitation        *handlers.InvitationHandler
 }
 
 // Services holds all service instances needed by the router
 type Services struct {
+	Token       *services.TokenService
 	Permission  *services.PermissionService
 	Audit       *services.AuditService
 	RateLimiter *services.RateLimiterService
+	APIKey      *services.APIKeyService
 }
 
 // SetupRouter initializes the Gin router with all routes and middleware
@@ -46,19 +60,24 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, h *Handlers, svc *Services) *g
 	// Add CORS middleware
 	router.Use(corsMiddleware(cfg))
 
+	// Public signing keys for services that verify our tokens
+	router.GET("/.well-known/jwks.json", h.Token.JWKS)
+	router.GET("/.well-known/openid-configuration", h.OAuth.Discovery)
+
 	// API group
 	api := router.Group("/api")
 
 	// Register public routes with rate limiting
-	registerPublicRoutes(api, h, svc)
+	registerPublicRoutes(api, cfg, h, svc)
 
 	// Register protected routes
 	protected := api.Group("")
-	protected.Use(middleware.Auth(cfg.JWTSecret, db))
+	protected.Use(middleware.Auth(cfg, db, svc.Token, svc.APIKey))
 	protected.Use(middleware.RateLimitByUser(svc.RateLimiter))
+	protected.Use(middleware.Tenant(cfg, db))
 	protected.Use(middleware.Permission(svc.Permission))
 	protected.Use(middleware.AuditLogger(svc.Audit))
-	registerProtectedRoutes(protected, h)
+	registerProtectedRoutes(protected, cfg, h)
 
 	return router
 }
@@ -79,7 +98,7 @@ func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-API-Key, X-Organization")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 
 		// Handle preflight
@@ -94,7 +113,7 @@ func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 }
 
 // registerPublicRoutes registers all public routes (no authentication required)
-func registerPublicRoutes(router *gin.RouterGroup, h *Handlers, svc *Services) {
+func registerPublicRoutes(router *gin.RouterGroup, cfg *config.Config, h *Handlers, svc *Services) {
 	// Auth routes with rate limiting
 	authGroup := router.Group("/auth")
 	authGroup.Use(middleware.RateLimitByIP(svc.RateLimiter))
@@ -103,17 +122,72 @@ func registerPublicRoutes(router *gin.RouterGroup, h *Handlers, svc *Services) {
 		authGroup.POST("/register", h.Auth.Register)
 		authGroup.POST("/login", h.Auth.Login)
 		authGroup.POST("/refresh-token", h.Token.RefreshToken)
+		authGroup.POST("/mfa/verify", h.Auth.VerifyMFA)
+		authGroup.POST("/mfa/challenge/enroll", h.Auth.BeginMFAChallengeEnrollment)
+		authGroup.POST("/webauthn/login/begin", h.WebAuthn.BeginLogin)
+		authGroup.POST("/webauthn/login/finish", h.WebAuthn.FinishLogin)
+
+		// Sign in with external OpenID Connect providers
+		authGroup.GET("/oidc/providers", h.OIDC.ListProviders)
+		authGroup.GET("/oidc/:provider/login", h.OIDC.Login)
+		authGroup.GET("/oidc/:provider/callback", h.OIDC.Callback)
+
+		// Password reset endpoints get a stricter per-IP limit
+		passwordReset := middleware.RateLimitByKey(svc.RateLimiter, "password-reset", cfg.PasswordResetRateLimit, cfg.PasswordResetRateWindow)
+		authGroup.POST("/forgot-password", passwordReset, h.PasswordReset.ForgotPassword)
+		authGroup.POST("/reset-password", passwordReset, h.PasswordReset.ResetPassword)
+		authGroup.POST("/change-expired-password", passwordReset, h.Auth.ChangeExpiredPassword)
+		authGroup.GET("/password-policy", h.PasswordPolicy.GetPolicy)
+
+		// Passwordless sign-in links get their own stricter per-IP limit
+		if cfg.MagicLinkEnabled {
+			magicLink := middleware.RateLimitByKey(svc.RateLimiter, "magic-link", cfg.MagicLinkRateLimit, cfg.MagicLinkRateWindow)
+			authGroup.POST("/magic-link", magicLink, h.MagicLink.Request)
+			authGroup.POST("/magic-link/verify", magicLink, h.MagicLink.Verify)
+		}
+
+		// Organization invitations are opened from the emailed link before signing in
+		authGroup.POST("/invitation/details", h.Invitation.GetInvitationDetails)
+		authGroup.POST("/invitation/accept", h.Invitation.AcceptInvitation)
+
+		authGroup.POST("/verify-email", h.EmailVerification.VerifyEmail)
+		authGroup.POST("/resend-verification",
+			middleware.RateLimitByKey(svc.RateLimiter, "email-verification", cfg.EmailVerificationRateLimit, cfg.EmailVerificationRateWindow),
+			h.EmailVerification.ResendVerification,
+		)
+	}
+
+	// OAuth2 endpoints called by client applications, which authenticate themselves
+	oauthGroup := router.Group("/oauth")
+	oauthGroup.Use(middleware.RateLimitByIP(svc.RateLimiter))
+	{
+		oauthGroup.POST("/token", h.OAuth.Token)
+		oauthGroup.POST("/introspect", h.OAuth.Introspect)
+		oauthGroup.POST("/revoke", h.OAuth.Revoke)
+		oauthGroup.GET("/userinfo", h.OAuth.UserInfo)
+		oauthGroup.POST("/userinfo", h.OAuth.UserInfo)
 	}
 }
 
 // registerProtectedRoutes registers all protected routes (authentication required)
-func registerProtectedRoutes(router *gin.RouterGroup, h *Handlers) {
+func registerProtectedRoutes(router *gin.RouterGroup, cfg *config.Config, h *Handlers) {
+	// Sensitive operations need a recent sign-in or re-authentication
+	reauth := middleware.RequireRecentAuth(cfg.ReauthMaxAge)
+
 	RegisterAuthProtectedRoutes(router, h.Auth)
 	RegisterTokenRoutes(router, h.Token)
+	RegisterSessionRoutes(router, h.Session)
+	RegisterMFARoutes(router, h.MFA)
+	RegisterWebAuthnRoutes(router, h.WebAuthn)
+	RegisterEmailVerificationRoutes(router, h.EmailVerification)
+	RegisterAPIKeyRoutes(router, h.APIKey)
+	RegisterOAuthRoutes(router, h.OAuth)
 	RegisterAuditRoutes(router, h.Audit)
-	RegisterUserRoutes(router, h.User)
-	RegisterRoleRoutes(router, h.Role)
+	RegisterUserRoutes(router, h.User, reauth)
+	RegisterRoleRoutes(router, h.Role, reauth)
 	RegisterMenuRoutes(router, h.Menu)
-	RegisterRightsAccessRoutes(router, h.RightsAccess)
+	RegisterRightsAccessRoutes(router, h.RightsAccess, reauth)
 	RegisterSearchRoutes(router, h.Search)
+	RegisterOrganizationRoutes(router, h.Organization, reauth)
+	RegisterInvitationRoutes(router, h.Invitation)
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSessionRoutes registers session self-service and administration routes (all protected)
+func RegisterSessionRoutes(router *gin.RouterGroup, h *handlers.SessionHandler) {
+	sessions := router.Group("/auth/sessions")
+	{
+		sessions.GET("", h.ListSessions)
+		sessions.DELETE("", h.RevokeOtherSessions)
+		sessions.DELETE("/:id", h.RevokeSession)
+	}
+
+	// Administrators manage any user's sessions, guarded by users-management permissions
+	userSessions := router.Group("/user/:id/sessions")
+	{
+		userSessions.GET("", h.ListUserSessions)
+		userSessions.DELETE("", h.RevokeUserSessions)
+		userSessions.DELETE("/:session_id", h.RevokeUserSession)
+	}
+}
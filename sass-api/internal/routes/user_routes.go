@@ -5,8 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterUserRoutes registers user management routes
-func RegisterUserRoutes(router *gin.RouterGroup, h *handlers.UserHandler) {
+// RegisterUserRoutes registers user management routes. Editing a user (which can change
+// their role) or deleting them requires a recent re-authentication.
+func RegisterUserRoutes(router *gin.RouterGroup, h *handlers.UserHandler, reauth gin.HandlerFunc) {
 	// List users
 	router.GET("/users", h.GetAllUsers)
 
@@ -15,8 +16,10 @@ func RegisterUserRoutes(router *gin.RouterGroup, h *handlers.UserHandler) {
 	{
 		user.GET("/:id", h.GetUserById)
 		user.POST("/create", h.CreateUser)
-		user.PUT("/:id", h.UpdateUser)
-		user.DELETE("/:id", h.DeleteUser)
+		user.PUT("/:id", reauth, h.UpdateUser)
+		user.DELETE("/:id", reauth, h.DeleteUser)
 		user.POST("/reset-password/:id", h.ResetUserPassword)
+		user.POST("/unlock/:id", h.UnlockUser)
+		user.POST("/impersonate/:id", h.Impersonate)
 	}
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/Aebroyx/sass-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebAuthnRoutes registers passkey management routes (all protected)
+// Note: /webauthn/login/* is registered as public route in router.go
+func RegisterWebAuthnRoutes(router *gin.RouterGroup, h *handlers.WebAuthnHandler) {
+	webauthn := router.Group("/auth/webauthn")
+	{
+		webauthn.POST("/register/begin", h.BeginRegistration)
+		webauthn.POST("/register/finish", h.FinishRegistration)
+		webauthn.GET("/credentials", h.ListCredentials)
+		webauthn.DELETE("/credentials/:id", h.DeleteCredential)
+	}
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyTokenPrefix marks a bearer credential as an API key rather than a JWT
+	apiKeyTokenPrefix = "sk_"
+
+	// apiKeyLastUsedInterval limits how often last-used details are written for a busy key
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKeyService manages user-owned API keys for machine clients
+type APIKeyService struct {
+	db                *gorm.DB
+	config            *config.Config
+	permissionService *PermissionService
+}
+
+func NewAPIKeyService(db *gorm.DB, config *config.Config, permissionService *PermissionService) *APIKeyService {
+	return &APIKeyService{
+		db:                db,
+		config:            config,
+		permissionService: permissionService,
+	}
+}
+
+// IsAPIKey reports whether a credential has the API key format
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyTokenPrefix)
+}
+
+// CreateKey creates an API key for the user. Each scope must be a permission the user currently holds.
+func (s *APIKeyService) CreateKey(userID uint, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	var user models.Users
+	if err := s.db.Preload("UserRoles").First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if s.config.APIKeyMaxPerUser > 0 {
+		var count int64
+		s.db.Model(&models.APIKey{}).Where("user_id = ?", userID).Count(&count)
+		if count >= int64(s.config.APIKeyMaxPerUser) {
+			return nil, errors.New("api key limit reached")
+		}
+	}
+
+	expiresAt, err := s.keyExpiry(req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	userPerms, err := s.permissionService.GetUserPermissions(user.ID, user.RoleIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range req.Scopes {
+		permission, menuPath, ok := parseAPIKeyScope(scope)
+		if !ok {
+			return nil, errors.New("invalid scope")
+		}
+		if !userPerms.CheckPermission(menuPath, permission) {
+			return nil, errors.New("scope not permitted")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := models.APIKey{
+		UserID:     user.ID,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: hashAPIKeySecret(secret),
+		Scopes:     strings.Join(scopes, ","),
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(key),
+		Key:            apiKeyTokenPrefix + prefix + "_" + secret,
+	}, nil
+}
+
+// ListKeys returns the user's API keys
+func (s *APIKeyService) ListKeys(userID uint) ([]models.APIKeyResponse, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, newAPIKeyResponse(key))
+	}
+	return responses, nil
+}
+
+// RevokeKey deletes one of the user's API keys
+func (s *APIKeyService) RevokeKey(userID, keyID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// Authenticate resolves a raw API key to the stored key and records where it was used
+func (s *APIKeyService) Authenticate(rawKey, ipAddress string) (*models.APIKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(rawKey, apiKeyTokenPrefix), "_")
+	if !IsAPIKey(rawKey) || !ok || prefix == "" || secret == "" {
+		return nil, errors.New("invalid api key")
+	}
+
+	var key models.APIKey
+	if err := s.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, errors.New("invalid api key")
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, errors.New("api key expired")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyLastUsedInterval || key.LastUsedIP != ipAddress {
+		s.db.Model(&key).Updates(map[string]any{
+			"last_used_at": now,
+			"last_used_ip": ipAddress,
+		})
+	}
+
+	return &key, nil
+}
+
+// KeyScopes returns the scopes granted to an API key
+func KeyScopes(key *models.APIKey) []string {
+	if key.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(key.Scopes, ",")
+}
+
+// keyExpiry applies the configured maximum lifetime to a requested expiry
+func (s *APIKeyService) keyExpiry(requested *time.Time) (*time.Time, error) {
+	now := time.Now()
+	if requested != nil && !requested.After(now) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	if s.config.APIKeyMaxLifetime <= 0 {
+		return requested, nil
+	}
+
+	latest := now.Add(s.config.APIKeyMaxLifetime)
+	if requested == nil {
+		return &latest, nil
+	}
+	if requested.After(latest) {
+		return nil, errors.New("expiry exceeds maximum lifetime")
+	}
+	return requested, nil
+}
+
+// parseAPIKeyScope splits a "permission:/menu-path" scope
+func parseAPIKeyScope(scope string) (config.PermissionType, string, bool) {
+	permission, menuPath, ok := strings.Cut(scope, ":")
+	if !ok || !strings.HasPrefix(menuPath, "/") {
+		return "", "", false
+	}
+
+	switch config.PermissionType(permission) {
+	case config.PermissionRead, config.PermissionWrite, config.PermissionUpdate, config.PermissionDelete:
+		return config.PermissionType(permission), menuPath, true
+	default:
+		return "", "", false
+	}
+}
+
+// newAPIKeyResponse converts an API key to its public representation
+func newAPIKeyResponse(key models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     apiKeyTokenPrefix + key.Prefix,
+		Scopes:     KeyScopes(&key),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// hashAPIKeySecret hashes an API key secret for storage and lookup
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -19,8 +20,22 @@ func NewAuditService(db *gorm.DB) *AuditService {
 	}
 }
 
-// Log creates a new audit log entry
+// WithContext returns a copy of the service bound to ctx, so audit log queries only
+// return entries of the request's organization
+func (s *AuditService) WithContext(ctx context.Context) *AuditService {
+	return &AuditService{db: s.db.WithContext(ctx)}
+}
+
+// Log creates a new audit log entry. Entries that don't name an organization are
+// filed under the organization of the user who acted.
 func (s *AuditService) Log(req *models.CreateAuditLogRequest) error {
+	if req.OrganizationID == nil && req.UserID != nil {
+		var user models.Users
+		if err := s.db.Select("id", "organization_id").First(&user, *req.UserID).Error; err == nil {
+			req.OrganizationID = user.OrganizationID
+		}
+	}
+
 	auditLog := &models.AuditLog{
 		UserID:        req.UserID,
 		Username:      req.Username,
@@ -33,6 +48,11 @@ func (s *AuditService) Log(req *models.CreateAuditLogRequest) error {
 		UserAgent:     req.UserAgent,
 		CorrelationID: req.CorrelationID,
 		Timestamp:     time.Now(),
+
+		ImpersonatorID:       req.ImpersonatorID,
+		ImpersonatorUsername: req.ImpersonatorUsername,
+
+		OrganizationID: req.OrganizationID,
 	}
 
 	return s.db.Create(auditLog).Error
@@ -51,32 +71,33 @@ func (s *AuditService) LogWithContext(
 	userAgent string,
 	correlationID string,
 ) error {
-	var oldValuesJSON, newValuesJSON string
+	return s.LogEntry(&models.CreateAuditLogRequest{
+		UserID:        userID,
+		Username:      username,
+		Action:        action,
+		ResourceType:  resourceType,
+		ResourceID:    resourceID,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+		CorrelationID: correlationID,
+	}, oldValues, newValues)
+}
 
+// LogEntry creates an audit log from a request, encoding structured old/new values
+func (s *AuditService) LogEntry(req *models.CreateAuditLogRequest, oldValues, newValues interface{}) error {
 	if oldValues != nil {
 		if jsonBytes, err := json.Marshal(oldValues); err == nil {
-			oldValuesJSON = string(jsonBytes)
+			req.OldValues = string(jsonBytes)
 		}
 	}
 
 	if newValues != nil {
 		if jsonBytes, err := json.Marshal(newValues); err == nil {
-			newValuesJSON = string(jsonBytes)
+			req.NewValues = string(jsonBytes)
 		}
 	}
 
-	return s.Log(&models.CreateAuditLogRequest{
-		UserID:        userID,
-		Username:      username,
-		Action:        action,
-		ResourceType:  resourceType,
-		ResourceID:    resourceID,
-		OldValues:     oldValuesJSON,
-		NewValues:     newValuesJSON,
-		IPAddress:     ipAddress,
-		UserAgent:     userAgent,
-		CorrelationID: correlationID,
-	})
+	return s.Log(req)
 }
 
 // GetAuditLogs retrieves audit logs with pagination and filtering
@@ -112,6 +133,10 @@ func (s *AuditService) GetAuditLogs(params *models.AuditLogQueryParams) (*pagina
 		query = query.Where("correlation_id = ?", params.CorrelationID)
 	}
 
+	if params.ImpersonatorID != nil {
+		query = query.Where("impersonator_id = ?", *params.ImpersonatorID)
+	}
+
 	if !params.StartDate.IsZero() {
 		query = query.Where("timestamp >= ?", params.StartDate)
 	}
@@ -177,6 +202,11 @@ func (s *AuditService) GetAuditLogs(params *models.AuditLogQueryParams) (*pagina
 			UserAgent:     log.UserAgent,
 			CorrelationID: log.CorrelationID,
 			Timestamp:     log.Timestamp,
+
+			ImpersonatorID:       log.ImpersonatorID,
+			ImpersonatorUsername: log.ImpersonatorUsername,
+
+			OrganizationID: log.OrganizationID,
 		}
 	}
 
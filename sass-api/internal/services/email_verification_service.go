@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// emailVerificationPurpose marks JWTs that may only be used to verify an email address
+const emailVerificationPurpose = "email_verification"
+
+// EmailVerificationService handles email verification for new accounts and email changes
+type EmailVerificationService struct {
+	db           *gorm.DB
+	config       *config.Config
+	mailer       mailer.Mailer
+	tokenService *TokenService
+	auditService *AuditService
+}
+
+func NewEmailVerificationService(db *gorm.DB, config *config.Config, mailer mailer.Mailer, tokenService *TokenService, auditService *AuditService) *EmailVerificationService {
+	return &EmailVerificationService{
+		db:           db,
+		config:       config,
+		mailer:       mailer,
+		tokenService: tokenService,
+		auditService: auditService,
+	}
+}
+
+// SendVerification emails a verification link for the user's pending email, or for
+// their current email if it has not been verified yet
+func (s *EmailVerificationService) SendVerification(userID uint) error {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.sendVerification(user)
+}
+
+// ResendVerification sends a new verification link to an address awaiting verification.
+// It returns nil whether or not the address is known, so callers cannot use it to probe for emails.
+func (s *EmailVerificationService) ResendVerification(email string) error {
+	email = strings.ToLower(email)
+
+	var user models.Users
+	err := s.db.Where("(LOWER(email) = ? AND email_verified_at IS NULL) OR LOWER(pending_email) = ?", email, email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sendVerification(user)
+}
+
+// VerifyEmail confirms the address in a verification link. For an email change the
+// pending address replaces the current one.
+func (s *EmailVerificationService) VerifyEmail(tokenString, ipAddress, userAgent string) (*models.Users, error) {
+	claims := &models.EmailVerificationClaims{}
+	token, err := s.tokenService.ParseClaims(tokenString, claims, jwt.WithAudience(emailVerificationPurpose))
+	if err != nil || !token.Valid || claims.Purpose != emailVerificationPurpose {
+		return nil, errors.New("invalid verification token")
+	}
+
+	var user models.Users
+	if err := s.db.First(&user, claims.UserID).Error; err != nil {
+		return nil, errors.New("invalid verification token")
+	}
+
+	now := time.Now()
+	switch {
+	case user.PendingEmail != "" && strings.EqualFold(claims.Email, user.PendingEmail):
+		var count int64
+		s.db.Model(&models.Users{}).Where("LOWER(email) = ? AND id <> ?", strings.ToLower(user.PendingEmail), user.ID).Count(&count)
+		if count > 0 {
+			return nil, errors.New("email already exists")
+		}
+
+		oldEmail := user.Email
+		if err := s.db.Model(&user).Updates(map[string]any{
+			"email":             user.PendingEmail,
+			"pending_email":     "",
+			"email_verified_at": now,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		s.audit(user, "EMAIL_CHANGED", map[string]string{"email": oldEmail}, map[string]string{"email": user.Email}, ipAddress, userAgent)
+
+	case strings.EqualFold(claims.Email, user.Email):
+		// Following the same link twice is harmless
+		if user.EmailVerifiedAt == nil {
+			if err := s.db.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return nil, err
+			}
+			s.audit(user, "EMAIL_VERIFIED", nil, map[string]string{"email": user.Email}, ipAddress, userAgent)
+		}
+
+	default:
+		// The address was changed again after this link was sent
+		return nil, errors.New("invalid verification token")
+	}
+
+	return &user, nil
+}
+
+// ChangeEmail starts an email change. The current address stays in use until the
+// new one is verified through the link sent to it.
+func (s *EmailVerificationService) ChangeEmail(userID uint, req *models.ChangeEmailRequest, ipAddress, userAgent string) error {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errors.New("invalid password")
+	}
+
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		return errors.New("new email is the same as the current email")
+	}
+
+	var count int64
+	s.db.Model(&models.Users{}).Where("LOWER(email) = ?", strings.ToLower(req.NewEmail)).Count(&count)
+	if count > 0 {
+		return errors.New("email already exists")
+	}
+
+	if err := s.db.Model(&user).Update("pending_email", req.NewEmail).Error; err != nil {
+		return err
+	}
+
+	s.audit(user, "EMAIL_CHANGE_REQUESTED", map[string]string{"email": user.Email}, map[string]string{"pending_email": req.NewEmail}, ipAddress, userAgent)
+
+	// Let the current owner know in case the account was taken over
+	s.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nA request was made to change the email address of your account to %s. The change takes effect once the new address is verified.\n\nIf you did not request this, please reset your password.\n",
+			user.Name, req.NewEmail,
+		),
+	}, user.ID)
+
+	return s.sendVerification(user)
+}
+
+// sendVerification issues a signed link for the address awaiting verification and emails it
+func (s *EmailVerificationService) sendVerification(user models.Users) error {
+	email := user.PendingEmail
+	if email == "" {
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		email = user.Email
+	}
+
+	expirationTime := time.Now().Add(s.config.EmailVerificationTTL)
+	claims := &models.EmailVerificationClaims{
+		UserID:  user.ID,
+		Email:   email,
+		Purpose: emailVerificationPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "sass-api",
+			Subject:   user.Username,
+			Audience:  jwt.ClaimStrings{emailVerificationPurpose},
+		},
+	}
+
+	tokenString, err := s.tokenService.SignClaims(claims)
+	if err != nil {
+		return err
+	}
+
+	s.send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThis link expires in %s.\n",
+			user.Name,
+			fmt.Sprintf("%s/verify-email?token=%s", strings.TrimRight(s.config.AppBaseURL, "/"), url.QueryEscape(tokenString)),
+			s.config.EmailVerificationTTL,
+		),
+	}, user.ID)
+
+	return nil
+}
+
+// send delivers an email in the background so response time doesn't reveal whether an account exists
+func (s *EmailVerificationService) send(msg mailer.Message, userID uint) {
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			logger.Errorf("Failed to send email to user %d: %v", userID, err)
+		}
+	}()
+}
+
+// audit records an email verification event for the user
+func (s *EmailVerificationService) audit(user models.Users, action string, oldValues, newValues any, ipAddress, userAgent string) {
+	if s.auditService == nil {
+		return
+	}
+	_ = s.auditService.LogWithContext(&user.ID, user.Username, action, "user", fmt.Sprintf("%d", user.ID), oldValues, newValues, ipAddress, userAgent, "")
+}
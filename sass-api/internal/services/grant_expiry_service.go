@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// grantInEffect limits time-bound grants (permission overrides and direct menu
+// assignments) to those whose validity window contains now
+func grantInEffect(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now)
+	}
+}
+
+// GrantExpiryService periodically removes time-bound grants whose validity has ended
+type GrantExpiryService struct {
+	db        *gorm.DB
+	config    *config.Config
+	sweepTick *time.Ticker
+	stopSweep chan bool
+}
+
+// NewGrantExpiryService creates the service and starts the sweeper, unless
+// GRANT_SWEEP_INTERVAL is 0
+func NewGrantExpiryService(db *gorm.DB, config *config.Config) *GrantExpiryService {
+	service := &GrantExpiryService{
+		db:     db,
+		config: config,
+	}
+
+	if config.GrantSweepInterval > 0 {
+		service.sweepTick = time.NewTicker(config.GrantSweepInterval)
+		service.stopSweep = make(chan bool)
+		go service.sweep()
+	}
+
+	return service
+}
+
+// sweep removes expired grants on every tick
+func (s *GrantExpiryService) sweep() {
+	for {
+		select {
+		case <-s.sweepTick.C:
+			if err := s.RemoveExpiredGrants(); err != nil {
+				log.Printf("Failed to remove expired grants: %v", err)
+			}
+		case <-s.stopSweep:
+			return
+		}
+	}
+}
+
+// Stop stops the sweeper goroutine
+func (s *GrantExpiryService) Stop() {
+	if s.sweepTick == nil {
+		return
+	}
+	s.sweepTick.Stop()
+	s.stopSweep <- true
+}
+
+// RemoveExpiredGrants deletes the permission overrides and direct menu assignments whose
+// valid_until has passed. Each removal is audited in the same transaction as the delete,
+// so a grant is never removed without a record of it. A grant that can't be removed is
+// logged and left for the next sweep without holding up the others. Rows are hard
+// deleted because the unique indexes on both tables don't exclude soft-deleted rows.
+func (s *GrantExpiryService) RemoveExpiredGrants() error {
+	now := time.Now()
+
+	var rightsAccess []models.RightsAccess
+	if err := s.db.Where("valid_until <= ?", now).Find(&rightsAccess).Error; err != nil {
+		return err
+	}
+
+	var userMenus []models.UserMenu
+	if err := s.db.Where("valid_until <= ?", now).Find(&userMenus).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, ra := range rightsAccess {
+		if err := s.removeExpiredGrant(&models.RightsAccess{}, "rights_access", ra.ID, ra.UserID, ra); err != nil {
+			log.Printf("Failed to remove expired rights access %d: %v", ra.ID, err)
+			errs = append(errs, fmt.Errorf("rights access %d: %w", ra.ID, err))
+		}
+	}
+	for _, um := range userMenus {
+		if err := s.removeExpiredGrant(&models.UserMenu{}, "user_menu", um.ID, um.UserID, um); err != nil {
+			log.Printf("Failed to remove expired user menu %d: %v", um.ID, err)
+			errs = append(errs, fmt.Errorf("user menu %d: %w", um.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// removeExpiredGrant deletes one expired grant and audits its removal in a transaction
+func (s *GrantExpiryService) removeExpiredGrant(model interface{}, resourceType string, id, userID uint, grant interface{}) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Delete(model, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error // Already removed, e.g. by another instance
+		}
+		return auditExpiredGrant(tx, resourceType, id, userID, grant)
+	})
+}
+
+// auditExpiredGrant records the removal of an expired grant in the organization of the
+// user who held it. Grants left behind by a user that no longer exists are recorded
+// without an organization.
+func auditExpiredGrant(tx *gorm.DB, resourceType string, id, userID uint, grant interface{}) error {
+	var user models.Users
+	if err := tx.Unscoped().Select("id", "organization_id").First(&user, userID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return NewAuditService(tx).LogEntry(&models.CreateAuditLogRequest{
+		Username:       "system",
+		Action:         "GRANT_EXPIRED",
+		ResourceType:   resourceType,
+		ResourceID:     fmt.Sprintf("%d", id),
+		OrganizationID: user.OrganizationID,
+	}, grant, nil)
+}
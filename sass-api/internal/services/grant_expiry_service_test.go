@@ -0,0 +1,89 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+)
+
+func TestRemoveExpiredGrants(t *testing.T) {
+	db := newTestDB(t, &models.Organization{}, &models.Role{}, &models.Users{}, &models.Menu{},
+		&models.RightsAccess{}, &models.UserMenu{}, &models.AuditLog{})
+	s := NewGrantExpiryService(db, &config.Config{})
+	user := createTestUser(t, db, "alice")
+
+	expired := time.Now().Add(-time.Minute)
+	current := time.Now().Add(time.Hour)
+	for i, validUntil := range []*time.Time{&expired, &current, nil} {
+		menu := models.Menu{Name: "menu", Path: "/menu", OrderIndex: i, IsActive: true}
+		db.Create(&menu)
+		db.Create(&models.RightsAccess{UserID: user.ID, MenuID: menu.ID, ValidUntil: validUntil})
+		db.Create(&models.UserMenu{UserID: user.ID, MenuID: menu.ID, ValidUntil: validUntil})
+	}
+
+	countGrants := func() (rightsAccess, userMenus int64) {
+		db.Model(&models.RightsAccess{}).Count(&rightsAccess)
+		db.Model(&models.UserMenu{}).Count(&userMenus)
+		return rightsAccess, userMenus
+	}
+
+	// A grant whose removal can't be audited stays in place, without stopping the sweep
+	db.Migrator().RenameTable(&models.AuditLog{}, "audit_logs_unavailable")
+	err := s.RemoveExpiredGrants()
+	if err == nil {
+		t.Fatal("expired grants removed without an audit entry")
+	}
+	if !strings.Contains(err.Error(), "rights access") || !strings.Contains(err.Error(), "user menu") {
+		t.Errorf("error = %v, want a failure for each expired grant", err)
+	}
+	if rightsAccess, userMenus := countGrants(); rightsAccess != 3 || userMenus != 3 {
+		t.Fatalf("%d rights access and %d user menus remain after a failed audit, want 3 of each", rightsAccess, userMenus)
+	}
+	db.Migrator().RenameTable("audit_logs_unavailable", &models.AuditLog{})
+
+	if err := s.RemoveExpiredGrants(); err != nil {
+		t.Fatalf("remove expired grants: %v", err)
+	}
+	if rightsAccess, userMenus := countGrants(); rightsAccess != 2 || userMenus != 2 {
+		t.Errorf("%d rights access and %d user menus remain, want the 2 of each still in effect", rightsAccess, userMenus)
+	}
+	var audited int64
+	db.Model(&models.AuditLog{}).Where("action = ?", "GRANT_EXPIRED").Count(&audited)
+	if audited != 2 {
+		t.Errorf("%d removals audited, want 2", audited)
+	}
+}
+
+func TestExpiredUserMenuNotGranted(t *testing.T) {
+	db := newTestDB(t, &models.Organization{}, &models.Role{}, &models.Users{}, &models.Menu{},
+		&models.RoleMenu{}, &models.RightsAccess{}, &models.UserMenu{})
+	s := NewMenuService(db, &config.Config{})
+	user := createTestUser(t, db, "alice")
+
+	menu := models.Menu{Name: "reports", Path: "/reports", IsActive: true}
+	db.Create(&menu)
+	validUntil := time.Now().Add(time.Hour)
+	userMenu := models.UserMenu{UserID: user.ID, MenuID: menu.ID, ValidUntil: &validUntil}
+	db.Create(&userMenu)
+
+	menus, err := s.GetUserMenus(user.ID, []uint{user.RoleID})
+	if err != nil {
+		t.Fatalf("get user menus: %v", err)
+	}
+	if len(menus) != 1 || menus[0].ID != menu.ID {
+		t.Fatalf("menus = %+v, want the directly assigned menu", menus)
+	}
+
+	// Not yet removed by the sweeper, but no longer in effect
+	db.Model(&userMenu).Update("valid_until", time.Now().Add(-time.Minute))
+	menus, err = s.GetUserMenus(user.ID, []uint{user.RoleID})
+	if err != nil {
+		t.Fatalf("get user menus: %v", err)
+	}
+	if len(menus) != 0 {
+		t.Errorf("menus = %+v after the assignment expired, want none", menus)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an in-memory database private to the test, with the tenant plugin
+// registered as in production, and migrates the given models
+func newTestDB(t *testing.T, dst ...any) *gorm.DB {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatalf("register tenant plugin: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(dst...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// createTestUser creates an active user with a fresh role
+func createTestUser(t *testing.T, db *gorm.DB, username string) models.Users {
+	t.Helper()
+
+	role := models.Role{Name: username + "-role", DisplayName: username, IsActive: true}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	user := models.Users{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "unused",
+		Name:     username,
+		RoleID:   role.ID,
+		IsActive: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+)
+
+// Impersonate issues a short-lived access token that lets a root or admin user act as
+// another user, e.g. to reproduce an issue they reported
+func (s *UserService) Impersonate(impersonatorID uint, targetID string) (*models.ImpersonationResponse, error) {
+	var impersonator models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").First(&impersonator, impersonatorID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !impersonator.HasAnyRole(config.ImpersonatorRoles) {
+		return nil, errors.New("impersonation not allowed")
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").First(&user, targetID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.ID == impersonator.ID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+	// Acting as another privileged user would let an admin borrow root's access
+	if user.HasAnyRole(config.ImpersonatorRoles) {
+		return nil, errors.New("cannot impersonate an administrator")
+	}
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	accessToken, expiresAt, err := s.tokenService.GenerateImpersonationToken(user, impersonator)
+	if err != nil {
+		return nil, err
+	}
+
+	response := newRegisterResponse(user)
+	response.ImpersonatedBy = &models.ImpersonatorResponse{
+		ID:       impersonator.ID,
+		Username: impersonator.Username,
+	}
+
+	return &models.ImpersonationResponse{
+		User:        response,
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(expiresAt).Seconds()),
+		ExpiresAt:   expiresAt,
+	}, nil
+}
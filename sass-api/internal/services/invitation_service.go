@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
+	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// InvitationService invites people to an organization by email with a role chosen up
+// front. Accepting an invitation creates the account, or moves an existing account into
+// the organization.
+type InvitationService struct {
+	db           *gorm.DB
+	config       *config.Config
+	mailer       mailer.Mailer
+	tokenService *TokenService
+	auditService *AuditService
+	passwords    *PasswordPolicyService
+}
+
+func NewInvitationService(db *gorm.DB, config *config.Config, mailer mailer.Mailer, tokenService *TokenService, auditService *AuditService, passwords *PasswordPolicyService) *InvitationService {
+	return &InvitationService{
+		db:           db,
+		config:       config,
+		mailer:       mailer,
+		tokenService: tokenService,
+		auditService: auditService,
+		passwords:    passwords,
+	}
+}
+
+// WithContext returns a copy of the service bound to ctx, so administrators only manage
+// the invitations of their organization
+func (s *InvitationService) WithContext(ctx context.Context) *InvitationService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// GetPendingInvitations retrieves invitations that haven't been accepted or revoked
+func (s *InvitationService) GetPendingInvitations(params pagination.QueryParams) (*pagination.PaginatedResponse, error) {
+	config := pagination.PaginationConfig{
+		Model: &models.Invitation{},
+		BaseCondition: map[string]interface{}{
+			"status": models.InvitationPending,
+		},
+		SearchFields: []string{"email"},
+		FilterFields: map[string]string{
+			"email":   "email",
+			"role_id": "role_id",
+		},
+		DateFields: map[string]pagination.DateField{
+			"expires_at": {
+				Start: "expires_at",
+				End:   "expires_at",
+			},
+		},
+		SortFields:   []string{"email", "expires_at", "created_at"},
+		DefaultSort:  "created_at",
+		DefaultOrder: "DESC",
+		Relations:    []string{"Role"},
+	}
+
+	paginator := pagination.NewPaginator(s.db)
+	return paginator.Paginate(params, config)
+}
+
+// CreateInvitation invites an email address with the given role and emails the link
+func (s *InvitationService) CreateInvitation(inviterID uint, req *models.CreateInvitationRequest) (*models.Invitation, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Invitations always bring people into an organization, never into a platform role
+	role, organizationID, err := assignableRole(s.db, s.config, req.RoleID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if organizationID == nil {
+		return nil, errors.New("invalid role")
+	}
+
+	var memberCount int64
+	if err := tenant.AllOrganizations(s.db).Model(&models.Users{}).
+		Where("LOWER(email) = ? AND organization_id = ?", email, *organizationID).
+		Count(&memberCount).Error; err != nil {
+		return nil, err
+	}
+	if memberCount > 0 {
+		return nil, errors.New("user is already a member")
+	}
+
+	var pendingCount int64
+	if err := tenant.AllOrganizations(s.db).Model(&models.Invitation{}).
+		Where("email = ? AND organization_id = ? AND status = ? AND expires_at > ?", email, *organizationID, models.InvitationPending, time.Now()).
+		Count(&pendingCount).Error; err != nil {
+		return nil, err
+	}
+	if pendingCount > 0 {
+		return nil, errors.New("invitation already pending")
+	}
+
+	token, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := models.Invitation{
+		OrganizationID: organizationID,
+		Email:          email,
+		RoleID:         role.ID,
+		InvitedByID:    inviterID,
+		TokenHash:      hashInvitationToken(token),
+		Status:         models.InvitationPending,
+		ExpiresAt:      now.Add(s.config.InvitationTTL),
+		SentAt:         now,
+	}
+	if err := s.db.Create(&invitation).Error; err != nil {
+		return nil, err
+	}
+	invitation.Role = *role
+
+	s.sendInvitation(&invitation, token)
+
+	return &invitation, nil
+}
+
+// ResendInvitation emails a pending invitation again with a fresh link and expiry. The
+// previous link stops working.
+func (s *InvitationService) ResendInvitation(id uint) (*models.Invitation, error) {
+	invitation, err := s.getPendingInvitation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation.TokenHash = hashInvitationToken(token)
+	invitation.ExpiresAt = now.Add(s.config.InvitationTTL)
+	invitation.SentAt = now
+	if err := s.db.Model(invitation).Select("token_hash", "expires_at", "sent_at").Updates(invitation).Error; err != nil {
+		return nil, err
+	}
+
+	s.sendInvitation(invitation, token)
+
+	return invitation, nil
+}
+
+// RevokeInvitation cancels a pending invitation
+func (s *InvitationService) RevokeInvitation(id uint) (*models.Invitation, error) {
+	invitation, err := s.getPendingInvitation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation.Status = models.InvitationRevoked
+	invitation.RevokedAt = &now
+	if err := s.db.Model(invitation).Select("status", "revoked_at").Updates(invitation).Error; err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetInvitationDetails describes the invitation behind a token, so the accept page can
+// show what is being joined and whether an account will be created
+func (s *InvitationService) GetInvitationDetails(token string) (*models.InvitationDetailsResponse, error) {
+	invitation, err := s.findByToken(s.db, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var organization models.Organization
+	if err := s.db.First(&organization, invitation.OrganizationID).Error; err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	var inviter models.Users
+	s.db.Select("id", "name").First(&inviter, invitation.InvitedByID)
+
+	var accountCount int64
+	if err := s.db.Model(&models.Users{}).Where("LOWER(email) = ?", invitation.Email).Count(&accountCount).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.InvitationDetailsResponse{
+		Email:            invitation.Email,
+		OrganizationName: organization.Name,
+		RoleName:         invitation.Role.DisplayName,
+		InvitedBy:        inviter.Name,
+		ExpiresAt:        invitation.ExpiresAt,
+		AccountExists:    accountCount > 0,
+	}, nil
+}
+
+// AcceptInvitation redeems an invitation token. Without an account for the invited email
+// one is created from the request, subject to the password policy. An existing account
+// joins the organization with the invited role, as long as it isn't a member of another
+// organization; accounts in the default organization, where self-registered users land,
+// may move. The token proves control of the mailbox, so the email counts as verified.
+func (s *InvitationService) AcceptInvitation(req *models.AcceptInvitationRequest, ipAddress, userAgent string) (*models.RegisterResponse, error) {
+	var user models.Users
+	var created bool
+	var invitation *models.Invitation
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		invitation, err = s.findByToken(tx, req.Token)
+		if err != nil {
+			return err
+		}
+
+		// Mark the invitation accepted first; the condition makes concurrent accepts of the same token fail
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+			Updates(map[string]interface{}{"status": models.InvitationAccepted, "accepted_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired invitation")
+		}
+
+		err = tx.Where("LOWER(email) = ?", invitation.Email).First(&user).Error
+		switch {
+		case err == nil:
+			if err := s.joinOrganization(tx, &user, invitation); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.createMember(tx, &user, invitation, req); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Model(&models.Invitation{}).Where("id = ?", invitation.ID).Update("accepted_user_id", user.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID)
+
+	if s.auditService != nil {
+		_ = s.auditService.LogEntry(&models.CreateAuditLogRequest{
+			UserID:         &user.ID,
+			Username:       user.Username,
+			Action:         "INVITATION_ACCEPTED",
+			ResourceType:   "invitation",
+			ResourceID:     fmt.Sprintf("%d", invitation.ID),
+			IPAddress:      ipAddress,
+			UserAgent:      userAgent,
+			OrganizationID: invitation.OrganizationID,
+		}, nil, map[string]interface{}{
+			"role_id":         invitation.RoleID,
+			"invited_by_id":   invitation.InvitedByID,
+			"account_created": created,
+		})
+	}
+
+	response := newRegisterResponse(user)
+	return &response, nil
+}
+
+// joinOrganization moves an existing account into the invitation's organization
+func (s *InvitationService) joinOrganization(tx *gorm.DB, user *models.Users, invitation *models.Invitation) error {
+	if user.OrganizationID == nil {
+		// Accounts outside any organization hold cross-tenant roles
+		return errors.New("account belongs to another organization")
+	}
+
+	if *user.OrganizationID != *invitation.OrganizationID {
+		organization, err := defaultOrganization(tx, s.config)
+		if err != nil {
+			return err
+		}
+		if *user.OrganizationID != organization.ID {
+			return errors.New("account belongs to another organization")
+		}
+
+		// Grants on the old organization's menus don't carry over
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RightsAccess{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserMenu{}).Error; err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"organization_id": *invitation.OrganizationID,
+		"role_id":         invitation.RoleID,
+	}
+	if user.EmailVerifiedAt == nil {
+		updates["email_verified_at"] = time.Now()
+	}
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// The invited role replaces whatever roles the account held before
+	return setUserRoles(tx, user.ID, invitation.RoleID, nil)
+}
+
+// createMember creates the account for an invited email that has none yet
+func (s *InvitationService) createMember(tx *gorm.DB, user *models.Users, invitation *models.Invitation, req *models.AcceptInvitationRequest) error {
+	if req.Username == "" || req.Name == "" || req.Password == "" {
+		return errors.New("username, name and password are required")
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Users{}).Where("username = ?", req.Username).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return errors.New("username already exists")
+	}
+
+	if err := s.passwords.Validate(req.Password, &models.Users{Username: req.Username, Email: invitation.Email}); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*user = models.Users{
+		Username:          req.Username,
+		Email:             invitation.Email,
+		Password:          string(hashedPassword),
+		Name:              req.Name,
+		RoleID:            invitation.RoleID,
+		OrganizationID:    invitation.OrganizationID,
+		IsActive:          true,
+		EmailVerifiedAt:   &now,
+		PasswordChangedAt: &now,
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	if err := setUserRoles(tx, user.ID, user.RoleID, nil); err != nil {
+		return err
+	}
+	return s.passwords.RecordPassword(tx, user.ID, user.Password)
+}
+
+// getPendingInvitation loads an invitation that can still be resent or revoked
+func (s *InvitationService) getPendingInvitation(id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := s.db.Preload("Role").First(&invitation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+	if invitation.Status != models.InvitationPending {
+		return nil, errors.New("invitation is no longer pending")
+	}
+	return &invitation, nil
+}
+
+// findByToken loads the pending, unexpired invitation an emailed token belongs to
+func (s *InvitationService) findByToken(db *gorm.DB, token string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := db.Preload("Role").Where("token_hash = ?", hashInvitationToken(token)).First(&invitation).Error; err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+	if invitation.Status != models.InvitationPending || !invitation.ExpiresAt.After(time.Now()) || invitation.OrganizationID == nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+	return &invitation, nil
+}
+
+// sendInvitation emails the invitation link in the background
+func (s *InvitationService) sendInvitation(invitation *models.Invitation, token string) {
+	var organization models.Organization
+	s.db.First(&organization, invitation.OrganizationID)
+
+	// The inviter may be root, who belongs to no organization
+	var inviter models.Users
+	tenant.AllOrganizations(s.db).Select("id", "name").First(&inviter, invitation.InvitedByID)
+
+	msg := mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You're invited to join %s", organization.Name),
+		Body: fmt.Sprintf(
+			"Hi,\n\n%s invited you to join %s as %s. Accept the invitation here:\n\n%s\n\nThis link expires on %s. If you weren't expecting this invitation, you can ignore this email.\n",
+			inviter.Name,
+			organization.Name,
+			invitation.Role.DisplayName,
+			fmt.Sprintf("%s/accept-invitation?token=%s", strings.TrimRight(s.config.AppBaseURL, "/"), url.QueryEscape(token)),
+			invitation.ExpiresAt.Format(time.RFC1123),
+		),
+	}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			logger.Errorf("Failed to send invitation %d: %v", invitation.ID, err)
+		}
+	}()
+}
+
+// hashInvitationToken hashes an invitation token for storage and lookup
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
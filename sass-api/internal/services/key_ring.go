@@ -0,0 +1,253 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key in the key ring. Retiring keys only hold the public half.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyRing holds the key new tokens are signed with and the retiring keys that still
+// verify tokens issued before a rotation. Key IDs are derived from the public key,
+// so a key keeps its kid when it moves from active to retiring.
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+
+	// hmacSecret signs tokens in HS256 mode. After switching to asymmetric signing it
+	// only verifies HS256 tokens until legacyHS256Until.
+	hmacSecret       []byte
+	legacyHS256Until time.Time
+}
+
+// NewKeyRing loads the signing keys described by the configuration
+func NewKeyRing(cfg *config.Config) (*KeyRing, error) {
+	ring := &KeyRing{
+		keys: make(map[string]*SigningKey),
+	}
+	if cfg.JWTSecret != "" {
+		ring.hmacSecret = []byte(cfg.JWTSecret)
+	}
+
+	if cfg.JWTSigningAlg == jwt.SigningMethodHS256.Alg() {
+		if ring.hmacSecret == nil {
+			return nil, errors.New("JWT_SECRET is required for HS256 signing")
+		}
+	} else {
+		ring.legacyHS256Until = cfg.JWTAcceptLegacyHS256Until
+		active, err := loadSigningKey(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if active.PrivateKey == nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE must contain a private key")
+		}
+		if active.Method.Alg() != cfg.JWTSigningAlg {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE holds a %s key but JWT_SIGNING_ALG is %s", active.Method.Alg(), cfg.JWTSigningAlg)
+		}
+		ring.active = active
+		ring.keys[active.ID] = active
+	}
+
+	for _, path := range cfg.JWTRetiringKeyFiles {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		// Retiring keys never sign
+		key.PrivateKey = nil
+		if _, exists := ring.keys[key.ID]; !exists {
+			ring.keys[key.ID] = key
+		}
+	}
+
+	return ring, nil
+}
+
+// Sign signs claims with the active key
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	if r.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(r.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.ID
+	return token.SignedString(r.active.PrivateKey)
+}
+
+// SigningAlg returns the algorithm new tokens are signed with
+func (r *KeyRing) SigningAlg() string {
+	if r.active == nil {
+		return jwt.SigningMethodHS256.Alg()
+	}
+	return r.active.Method.Alg()
+}
+
+// Keyfunc resolves the verification key for a token from its kid header
+func (r *KeyRing) Keyfunc(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if !r.acceptsHS256() {
+			return nil, errors.New("symmetric tokens are not accepted")
+		}
+		return r.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	// The algorithm must match the key, so a token can't pick how it is verified
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("signing method does not match key")
+	}
+	return key.PublicKey, nil
+}
+
+// ValidMethods lists the algorithms the key ring can verify
+func (r *KeyRing) ValidMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	if r.acceptsHS256() {
+		seen[jwt.SigningMethodHS256.Alg()] = true
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range r.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// acceptsHS256 reports whether HS256 tokens verify: always in HS256 mode, and only
+// inside the migration window otherwise
+func (r *KeyRing) acceptsHS256() bool {
+	if r.hmacSecret == nil {
+		return false
+	}
+	return r.active == nil || time.Now().Before(r.legacyHS256Until)
+}
+
+// JWKS returns the public keys of the ring. HS256 secrets are never published.
+func (r *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// loadSigningKey reads an RSA or Ed25519 key from a PEM file. The file may hold a
+// private key or, for retiring keys, just the public key.
+func loadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	var key SigningKey
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported signing key type in %s", path)
+		}
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+		}
+		key.PrivateKey = parsed
+		key.PublicKey = parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+		}
+		key.PublicKey = parsed
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("signing key %s must be RSA or Ed25519", path)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:12])
+
+	return &key, nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
+	"gorm.io/gorm"
+)
+
+// MagicLinkService handles passwordless login links sent by email
+type MagicLinkService struct {
+	db           *gorm.DB
+	config       *config.Config
+	mailer       mailer.Mailer
+	tokenService *TokenService
+	auditService *AuditService
+	rateLimiter  *RateLimiterService
+}
+
+func NewMagicLinkService(db *gorm.DB, config *config.Config, mailer mailer.Mailer, tokenService *TokenService, auditService *AuditService, rateLimiter *RateLimiterService) *MagicLinkService {
+	return &MagicLinkService{
+		db:           db,
+		config:       config,
+		mailer:       mailer,
+		tokenService: tokenService,
+		auditService: auditService,
+		rateLimiter:  rateLimiter,
+	}
+}
+
+// RequestLink emails a login link to the account with the given email. The link only
+// works together with the returned browser nonce, which the caller keeps in a cookie in
+// the requesting browser. A nonce is returned whether or not the account exists or may
+// use magic links, and the lookup, token and email all happen in the background, so
+// neither the result nor the response time reveals whether the account exists.
+func (s *MagicLinkService) RequestLink(email, ipAddress, userAgent string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	// Limit per address as well as per IP, so one mailbox can't be flooded from many IPs
+	if allowed, _ := s.rateLimiter.AllowKey("magic-link-email:"+email, s.config.MagicLinkEmailRateLimit, s.config.MagicLinkRateWindow); !allowed {
+		return "", errors.New("too many magic link requests")
+	}
+
+	browserNonce, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		if err := s.sendLink(email, browserNonce, ipAddress, userAgent); err != nil {
+			logger.Errorf("Failed to issue magic link: %v", err)
+		}
+	}()
+
+	return browserNonce, nil
+}
+
+// sendLink issues a login link bound to browserNonce for the account with the given
+// email, if there is one that may use magic links, and emails it
+func (s *MagicLinkService) sendLink(email, browserNonce, ipAddress, userAgent string) error {
+	var user models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").Where("LOWER(email) = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.MagicLinkDisabled() {
+		return nil
+	}
+
+	token, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link works
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.MagicLinkToken{
+			UserID:      user.ID,
+			TokenHash:   hashMagicLinkToken(token),
+			BrowserHash: hashMagicLinkToken(browserNonce),
+			ExpiresAt:   time.Now().Add(s.config.MagicLinkTTL),
+			IPAddress:   ipAddress,
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	if s.auditService != nil {
+		_ = s.auditService.LogWithContext(&user.ID, user.Username, "MAGIC_LINK_REQUESTED", "auth", fmt.Sprintf("%d", user.ID), nil, nil, ipAddress, userAgent, "")
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to sign in:\n\n%s\n\nThis link expires in %s, can only be used once, and only works in the browser you requested it from. If you did not try to sign in, you can ignore this email.\n",
+			user.Name,
+			fmt.Sprintf("%s/login/magic-link?token=%s", strings.TrimRight(s.config.AppBaseURL, "/"), url.QueryEscape(token)),
+			s.config.MagicLinkTTL,
+		),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return fmt.Errorf("failed to send magic link email to user %d: %w", user.ID, err)
+	}
+
+	return nil
+}
+
+// ConsumeLink redeems a magic link token from the browser that requested it and returns
+// the user to sign in. Following the link proves control of the mailbox, so an
+// unverified email address is marked verified.
+func (s *MagicLinkService) ConsumeLink(token, browserNonce string) (*models.Users, error) {
+	var user models.Users
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var linkToken models.MagicLinkToken
+		if err := tx.Where("token_hash = ?", hashMagicLinkToken(token)).First(&linkToken).Error; err != nil {
+			return errors.New("invalid or expired magic link")
+		}
+
+		if browserNonce == "" || subtle.ConstantTimeCompare([]byte(hashMagicLinkToken(browserNonce)), []byte(linkToken.BrowserHash)) != 1 {
+			return errors.New("magic link opened in another browser")
+		}
+
+		// Mark the token used; the condition makes concurrent redemptions of the same token fail
+		now := time.Now()
+		result := tx.Model(&models.MagicLinkToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", linkToken.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired magic link")
+		}
+
+		if err := tx.Preload("Role").Preload("UserRoles.Role").First(&user, linkToken.UserID).Error; err != nil {
+			return errors.New("invalid or expired magic link")
+		}
+
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+			if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The role may have been barred from magic links after the link was sent
+	if user.MagicLinkDisabled() {
+		return nil, errors.New("magic link login disabled")
+	}
+
+	return &user, nil
+}
+
+// hashMagicLinkToken hashes a magic link token or browser nonce for storage and lookup
+func hashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
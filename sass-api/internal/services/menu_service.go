@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
 	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/tenant"
 	"gorm.io/gorm"
 )
 
@@ -22,6 +25,14 @@ func NewMenuService(db *gorm.DB, config *config.Config) *MenuService {
 	}
 }
 
+// WithContext returns a copy of the service bound to ctx. Inside an organization it
+// sees the shared menus and the organization's own.
+func (s *MenuService) WithContext(ctx context.Context) *MenuService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 // GetAllMenus retrieves all menus with pagination
 func (s *MenuService) GetAllMenus(params pagination.QueryParams) (*pagination.PaginatedResponse, error) {
 	config := pagination.PaginationConfig{
@@ -91,23 +102,32 @@ func buildMenuTree(menus []models.Menu, parentID *uint) []models.MenuResponse {
 	return result
 }
 
-// GetUserMenus retrieves menus accessible by a user with effective permissions
-func (s *MenuService) GetUserMenus(userID uint, roleID uint) ([]models.MenuWithPermissions, error) {
-	// Get menus from role
+// GetUserMenus retrieves menus accessible by a user with effective permissions. A
+// permission granted by any of the user's roles, or a role they inherit from, applies.
+func (s *MenuService) GetUserMenus(userID uint, roleIDs []uint) ([]models.MenuWithPermissions, error) {
+	roleIDs, err := inheritedRoleIDs(s.db, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get menus from the user's roles
 	var roleMenus []models.RoleMenu
-	if err := s.db.Preload("Menu").Where("role_id = ?", roleID).Find(&roleMenus).Error; err != nil {
+	if err := s.db.Preload("Menu").Where("role_id IN ?", roleIDs).Find(&roleMenus).Error; err != nil {
 		return nil, err
 	}
 
 	// Get direct user menus
+	// Time-bound grants only count inside their validity window
+	now := time.Now()
+
 	var userMenus []models.UserMenu
-	if err := s.db.Preload("Menu").Where("user_id = ?", userID).Find(&userMenus).Error; err != nil {
+	if err := s.db.Preload("Menu").Scopes(grantInEffect(now)).Where("user_id = ?", userID).Find(&userMenus).Error; err != nil {
 		return nil, err
 	}
 
 	// Get user permission overrides
 	var rightsAccess []models.RightsAccess
-	if err := s.db.Where("user_id = ?", userID).Find(&rightsAccess).Error; err != nil {
+	if err := s.db.Scopes(grantInEffect(now)).Where("user_id = ?", userID).Find(&rightsAccess).Error; err != nil {
 		return nil, err
 	}
 
@@ -120,34 +140,17 @@ func (s *MenuService) GetUserMenus(userID uint, roleID uint) ([]models.MenuWithP
 	// Build menu map with permissions
 	menuMap := make(map[uint]models.MenuWithPermissions)
 
-	// Add menus from role with default permissions
+	// Add menus from roles, uniting the permissions of roles that share a menu
 	for _, rm := range roleMenus {
 		if !rm.Menu.IsActive {
 			continue
 		}
 
-		permissions := models.EffectivePermissions{
-			CanRead:   rm.CanRead,
-			CanWrite:  rm.CanWrite,
-			CanUpdate: rm.CanUpdate,
-			CanDelete: rm.CanDelete,
-		}
-
-		// Apply user overrides if exist
-		if override, ok := overrideMap[rm.MenuID]; ok {
-			if override.CanRead != nil {
-				permissions.CanRead = *override.CanRead
-			}
-			if override.CanWrite != nil {
-				permissions.CanWrite = *override.CanWrite
-			}
-			if override.CanUpdate != nil {
-				permissions.CanUpdate = *override.CanUpdate
-			}
-			if override.CanDelete != nil {
-				permissions.CanDelete = *override.CanDelete
-			}
-		}
+		permissions := menuMap[rm.MenuID].Permissions
+		permissions.CanRead = permissions.CanRead || rm.CanRead
+		permissions.CanWrite = permissions.CanWrite || rm.CanWrite
+		permissions.CanUpdate = permissions.CanUpdate || rm.CanUpdate
+		permissions.CanDelete = permissions.CanDelete || rm.CanDelete
 
 		menuMap[rm.MenuID] = models.MenuWithPermissions{
 			ID:          rm.Menu.ID,
@@ -161,6 +164,27 @@ func (s *MenuService) GetUserMenus(userID uint, roleID uint) ([]models.MenuWithP
 		}
 	}
 
+	// Apply user overrides on top of the role permissions
+	for menuID, menu := range menuMap {
+		override, ok := overrideMap[menuID]
+		if !ok {
+			continue
+		}
+		if override.CanRead != nil {
+			menu.Permissions.CanRead = *override.CanRead
+		}
+		if override.CanWrite != nil {
+			menu.Permissions.CanWrite = *override.CanWrite
+		}
+		if override.CanUpdate != nil {
+			menu.Permissions.CanUpdate = *override.CanUpdate
+		}
+		if override.CanDelete != nil {
+			menu.Permissions.CanDelete = *override.CanDelete
+		}
+		menuMap[menuID] = menu
+	}
+
 	// Add direct user menus (with default read permission if not already in map)
 	for _, um := range userMenus {
 		if !um.Menu.IsActive {
@@ -333,6 +357,9 @@ func (s *MenuService) UpdateMenu(id uint, req *models.UpdateMenuRequest) (*model
 		}
 		return nil, err
 	}
+	if !tenant.CanModify(s.db, menu.OrganizationID) {
+		return nil, errors.New("cannot modify a shared menu")
+	}
 
 	// Validate parent exists if provided and prevent circular reference
 	if req.ParentID != nil {
@@ -376,6 +403,9 @@ func (s *MenuService) DeleteMenu(id uint) error {
 	if err := s.db.First(&menu, id).Error; err != nil {
 		return errors.New("menu not found")
 	}
+	if !tenant.CanModify(s.db, menu.OrganizationID) {
+		return errors.New("cannot modify a shared menu")
+	}
 
 	// Check if menu has children
 	var childCount int64
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// mfaPeriod is the TOTP time step in seconds (RFC 6238 default)
+	mfaPeriod = 30
+	// mfaRecoveryCodeCount is the number of recovery codes issued per enrolment
+	mfaRecoveryCodeCount = 10
+	// mfaChallengePurpose marks JWTs that may only be used to complete an MFA login
+	mfaChallengePurpose = "mfa_challenge"
+)
+
+type MFAService struct {
+	db           *gorm.DB
+	config       *config.Config
+	tokenService *TokenService
+}
+
+func NewMFAService(db *gorm.DB, config *config.Config, tokenService *TokenService) *MFAService {
+	return &MFAService{
+		db:           db,
+		config:       config,
+		tokenService: tokenService,
+	}
+}
+
+// GetStatus returns the MFA state of a user
+func (s *MFAService) GetStatus(userID uint) (*models.MFAStatusResponse, error) {
+	var user models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var remaining int64
+	s.db.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&remaining)
+
+	return &models.MFAStatusResponse{
+		Enabled:                user.MFAEnabled,
+		EnabledAt:              user.MFAEnabledAt,
+		RequiredByRole:         user.RequiresMFA(),
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. The secret stays pending
+// until ConfirmEnrollment verifies a code generated from it.
+func (s *MFAService) BeginEnrollment(userID uint) (*models.MFAEnrollmentResponse, error) {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.config.MFAIssuer,
+		AccountName: user.Email,
+		Period:      mfaPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&user).Updates(map[string]interface{}{
+		"mfa_secret":       key.Secret(),
+		"mfa_last_counter": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	// Render the otpauth URL as a QR code for authenticator apps
+	var qrCode string
+	if img, err := key.Image(200, 200); err == nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err == nil {
+			qrCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     qrCode,
+	}, nil
+}
+
+// ConfirmEnrollment verifies the first code from the authenticator, enables MFA and
+// returns a fresh set of recovery codes
+func (s *MFAService) ConfirmEnrollment(userID uint, code string) (*models.MFARecoveryCodesResponse, error) {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	if user.MFASecret == "" {
+		return nil, errors.New("mfa enrollment not started")
+	}
+
+	if !s.validateTOTP(&user, code) {
+		return nil, errors.New("invalid mfa code")
+	}
+
+	var codes []string
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"mfa_enabled":    true,
+			"mfa_enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns off MFA after re-checking the password and a current code
+func (s *MFAService) Disable(userID uint, req *models.MFADisableRequest) error {
+	var user models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if !user.MFAEnabled {
+		return errors.New("mfa not enabled")
+	}
+
+	if user.RequiresMFA() {
+		return errors.New("mfa required by role")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errors.New("invalid password")
+	}
+
+	if err := s.VerifyCode(&user, req.Code); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"mfa_enabled":      false,
+			"mfa_enabled_at":   nil,
+			"mfa_secret":       "",
+			"mfa_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and issues new ones
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) (*models.MFARecoveryCodesResponse, error) {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.MFAEnabled {
+		return nil, errors.New("mfa not enabled")
+	}
+
+	if !s.validateTOTP(&user, code) {
+		return nil, errors.New("invalid mfa code")
+	}
+
+	var codes []string
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyCode checks a TOTP code, falling back to a single-use recovery code
+func (s *MFAService) VerifyCode(user *models.Users, code string) error {
+	if s.validateTOTP(user, code) {
+		return nil
+	}
+
+	// Recovery codes are consumed atomically so they can only be used once
+	result := s.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid mfa code")
+	}
+
+	return nil
+}
+
+// validateTOTP checks a code against the user's secret, allowing one step of clock
+// skew, and records the accepted time step so the same code can't be replayed
+func (s *MFAService) validateTOTP(user *models.Users, code string) bool {
+	code = strings.TrimSpace(code)
+	if user.MFASecret == "" || len(code) != int(otp.DigitsSix) {
+		return false
+	}
+
+	now := time.Now()
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*mfaPeriod) * time.Second)
+		counter := t.Unix() / mfaPeriod
+		if counter <= user.MFALastCounter {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(user.MFASecret, t, totp.ValidateOpts{
+			Period:    mfaPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			// Guard against a concurrent request accepting the same step
+			result := s.db.Model(&models.Users{}).
+				Where("id = ? AND mfa_last_counter < ?", user.ID, counter).
+				Update("mfa_last_counter", counter)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return false
+			}
+			user.MFALastCounter = counter
+			return true
+		}
+	}
+
+	return false
+}
+
+// IssueChallenge creates the short-lived token that links the password step of login to the MFA step
+func (s *MFAService) IssueChallenge(user models.Users) (*models.MFAChallengeResponse, error) {
+	expirationTime := time.Now().Add(s.config.MFAChallengeTTL)
+	claims := &models.MFAChallengeClaims{
+		UserID:  user.ID,
+		Purpose: mfaChallengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "sass-api",
+			Subject:   user.Username,
+			Audience:  jwt.ClaimStrings{mfaChallengePurpose},
+		},
+	}
+
+	tokenString, err := s.tokenService.SignClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallengeResponse{
+		MFAToken:           tokenString,
+		EnrollmentRequired: !user.MFAEnabled,
+		ExpiresIn:          int64(s.config.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// ParseChallenge validates an MFA challenge token and returns the user ID it was issued for
+func (s *MFAService) ParseChallenge(tokenString string) (uint, error) {
+	claims := &models.MFAChallengeClaims{}
+	token, err := s.tokenService.ParseClaims(tokenString, claims, jwt.WithAudience(mfaChallengePurpose))
+	if err != nil || !token.Valid || claims.Purpose != mfaChallengePurpose {
+		return 0, errors.New("invalid mfa token")
+	}
+
+	return claims.UserID, nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores new hashed ones,
+// returning the plaintext codes
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, mfaRecoveryCodeCount)
+	records := make([]models.MFARecoveryCode, mfaRecoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = models.MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// hashRecoveryCode normalizes and hashes a recovery code for storage and lookup
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
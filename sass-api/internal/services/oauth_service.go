@@ -0,0 +1,779 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scopes understood by the authorization server. Clients may also be registered with
+// custom scopes, which are passed through in access tokens for their own APIs.
+const (
+	oauthScopeOpenID  = "openid"
+	oauthScopeProfile = "profile"
+	oauthScopeEmail   = "email"
+	oauthScopeRoles   = "roles"
+)
+
+// oauthScopeDescriptions are shown on the consent screen
+var oauthScopeDescriptions = map[string]string{
+	oauthScopeOpenID:  "Sign you in with your account",
+	oauthScopeProfile: "See your name and username",
+	oauthScopeEmail:   "See your email address",
+	oauthScopeRoles:   "See your role and permissions",
+}
+
+// OAuthError is an error response as defined in RFC 6749 section 5.2
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthService lets other applications use this API as their identity provider,
+// as an OAuth2 authorization server and OpenID Connect provider
+type OAuthService struct {
+	db                *gorm.DB
+	config            *config.Config
+	tokenService      *TokenService
+	permissionService *PermissionService
+}
+
+func NewOAuthService(db *gorm.DB, config *config.Config, tokenService *TokenService, permissionService *PermissionService) *OAuthService {
+	return &OAuthService{
+		db:                db,
+		config:            config,
+		tokenService:      tokenService,
+		permissionService: permissionService,
+	}
+}
+
+// WithContext returns a copy of the service bound to ctx, so administrators only manage
+// the clients of their organization
+func (s *OAuthService) WithContext(ctx context.Context) *OAuthService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// CreateClient registers an OAuth client. Confidential clients get a secret, which is
+// only returned here.
+func (s *OAuthService) CreateClient(createdBy uint, req *models.CreateOAuthClientRequest) (*models.CreateOAuthClientResponse, error) {
+	grantTypes := uniqueStrings(req.GrantTypes)
+	scopes := uniqueStrings(req.Scopes)
+	redirectURIs := uniqueStrings(req.RedirectURIs)
+
+	for _, scope := range scopes {
+		if strings.ContainsAny(scope, " \t\r\n") {
+			return nil, errors.New("invalid scope")
+		}
+	}
+	for _, redirectURI := range redirectURIs {
+		if strings.ContainsAny(redirectURI, " #") {
+			return nil, errors.New("invalid redirect uri")
+		}
+	}
+	if containsString(grantTypes, models.OAuthGrantAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, errors.New("redirect uri required")
+	}
+	if req.Type == models.OAuthClientPublic && containsString(grantTypes, models.OAuthGrantClientCredentials) {
+		return nil, errors.New("public clients can't use client credentials")
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	client := models.OAuthClient{
+		ClientID:     clientID,
+		Name:         req.Name,
+		Type:         req.Type,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		IsActive:     true,
+		CreatedBy:    createdBy,
+	}
+
+	// New clients belong to the organization of the request
+	if organizationID, scoped := tenant.FromDB(s.db); scoped {
+		client.OrganizationID = &organizationID
+	}
+
+	var secret string
+	if req.Type == models.OAuthClientConfidential {
+		secret, err = randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash = hashOAuthToken(secret)
+	}
+
+	if err := s.db.Create(&client).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.CreateOAuthClientResponse{
+		OAuthClientResponse: newOAuthClientResponse(client),
+		ClientSecret:        secret,
+	}, nil
+}
+
+// ListClients returns the registered OAuth clients
+func (s *OAuthService) ListClients() ([]models.OAuthClientResponse, error) {
+	var clients []models.OAuthClient
+	if err := s.db.Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		responses = append(responses, newOAuthClientResponse(client))
+	}
+	return responses, nil
+}
+
+// DeleteClient removes a client along with its codes, tokens and consents
+func (s *OAuthService) DeleteClient(id uint) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&client, id).Error; err != nil {
+			return errors.New("oauth client not found")
+		}
+		if err := tx.Where("client_id = ?", client.ClientID).Delete(&models.OAuthToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ClientID).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ClientID).Delete(&models.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// AuthenticateClient checks the credentials a client presents at the token,
+// introspection and revocation endpoints. Public clients only identify themselves.
+func (s *OAuthService) AuthenticateClient(clientID, clientSecret string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if clientID == "" || s.db.Where("client_id = ? AND is_active = ?", clientID, true).First(&client).Error != nil {
+		return nil, oauthError("invalid_client", "Client authentication failed")
+	}
+
+	if client.Type == models.OAuthClientConfidential {
+		if subtle.ConstantTimeCompare([]byte(hashOAuthToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, oauthError("invalid_client", "Client authentication failed")
+		}
+	}
+
+	return &client, nil
+}
+
+// GetConsent validates an authorization request and returns what the consent screen
+// should show
+func (s *OAuthService) GetConsent(userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthConsentResponse, error) {
+	client, scopes, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make([]models.OAuthScopeDescription, 0, len(scopes))
+	for _, scope := range scopes {
+		description, ok := oauthScopeDescriptions[scope]
+		if !ok {
+			description = "Access " + scope + " on your behalf"
+		}
+		descriptions = append(descriptions, models.OAuthScopeDescription{Name: scope, Description: description})
+	}
+
+	var consent models.OAuthConsent
+	granted := s.db.Where("user_id = ? AND client_id = ?", userID, client.ClientID).First(&consent).Error == nil &&
+		coversScopes(strings.Fields(consent.Scope), scopes)
+
+	return &models.OAuthConsentResponse{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		Scopes:          descriptions,
+		ConsentRequired: !granted,
+	}, nil
+}
+
+// Authorize records the user's decision on an authorization request and returns the
+// client redirect carrying either an authorization code or an access_denied error
+func (s *OAuthService) Authorize(userID uint, req *models.OAuthAuthorizeRequest) (string, error) {
+	client, scopes, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("iss", s.issuer())
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", "access_denied")
+		params.Set("error_description", "The user denied the request")
+		return withQueryParams(req.RedirectURI, params), nil
+	}
+
+	code, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var consent models.OAuthConsent
+		err := tx.Where("user_id = ? AND client_id = ?", userID, client.ClientID).First(&consent).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		consent.UserID = userID
+		consent.ClientID = client.ClientID
+		consent.Scope = strings.Join(uniqueStrings(append(strings.Fields(consent.Scope), scopes...)), " ")
+		if err := tx.Save(&consent).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.OAuthAuthorizationCode{
+			CodeHash:      hashOAuthToken(code),
+			ClientID:      client.ClientID,
+			UserID:        userID,
+			RedirectURI:   req.RedirectURI,
+			Scope:         strings.Join(scopes, " "),
+			Nonce:         req.Nonce,
+			CodeChallenge: req.CodeChallenge,
+			ExpiresAt:     time.Now().Add(s.config.OAuthCodeTTL),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	params.Set("code", code)
+	return withQueryParams(req.RedirectURI, params), nil
+}
+
+// ExchangeCode redeems an authorization code for tokens. Replaying a code revokes
+// every token issued for it.
+func (s *OAuthService) ExchangeCode(client *models.OAuthClient, code, redirectURI, codeVerifier string) (*models.OAuthTokenResponse, error) {
+	if !clientAllowsGrant(client, models.OAuthGrantAuthorizationCode) {
+		return nil, oauthError("unauthorized_client", "The client may not use this grant type")
+	}
+
+	var authCode models.OAuthAuthorizationCode
+	if err := s.db.Where("code_hash = ?", hashOAuthToken(code)).First(&authCode).Error; err != nil || authCode.ClientID != client.ClientID {
+		return nil, oauthError("invalid_grant", "Invalid authorization code")
+	}
+
+	if authCode.UsedAt != nil {
+		if authCode.FamilyID != "" {
+			s.revokeFamily(authCode.FamilyID)
+		}
+		return nil, oauthError("invalid_grant", "Authorization code was already used")
+	}
+	if time.Now().After(authCode.ExpiresAt) {
+		return nil, oauthError("invalid_grant", "Authorization code expired")
+	}
+	if authCode.RedirectURI != redirectURI {
+		return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+
+	if authCode.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(authCode.CodeChallenge)) != 1 {
+			return nil, oauthError("invalid_grant", "Invalid code_verifier")
+		}
+	} else if codeVerifier != "" {
+		return nil, oauthError("invalid_grant", "code_verifier sent without a code_challenge")
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").First(&user, authCode.UserID).Error; err != nil || !user.IsActive {
+		return nil, oauthError("invalid_grant", "The user is no longer active")
+	}
+
+	// Claim the code atomically so concurrent redemptions can't both succeed
+	familyID := uuid.New().String()
+	result := s.db.Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", authCode.ID).
+		Updates(map[string]any{"used_at": time.Now(), "family_id": familyID})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, oauthError("invalid_grant", "Authorization code was already used")
+	}
+
+	scopes := strings.Fields(authCode.Scope)
+	var refreshScopes []string
+	if clientAllowsGrant(client, models.OAuthGrantRefreshToken) {
+		refreshScopes = scopes
+	}
+
+	return s.issueTokens(client, &user, scopes, refreshScopes, authCode.Nonce, familyID)
+}
+
+// RefreshToken rotates a refresh token. A revoked refresh token being presented
+// again means it leaked, so its whole family is revoked.
+func (s *OAuthService) RefreshToken(client *models.OAuthClient, refreshToken, scope string) (*models.OAuthTokenResponse, error) {
+	if !clientAllowsGrant(client, models.OAuthGrantRefreshToken) {
+		return nil, oauthError("unauthorized_client", "The client may not use this grant type")
+	}
+
+	var token models.OAuthToken
+	err := s.db.Where("token_hash = ? AND token_type = ?", hashOAuthToken(refreshToken), models.OAuthTokenRefresh).First(&token).Error
+	if err != nil || token.ClientID != client.ClientID || token.UserID == nil {
+		return nil, oauthError("invalid_grant", "Invalid refresh token")
+	}
+
+	if token.RevokedAt != nil {
+		s.revokeFamily(token.FamilyID)
+		return nil, oauthError("invalid_grant", "Invalid refresh token")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, oauthError("invalid_grant", "Refresh token expired")
+	}
+
+	// A narrower scope may be requested for the new access token
+	grantedScopes := strings.Fields(token.Scope)
+	scopes := grantedScopes
+	if scope != "" {
+		scopes = uniqueStrings(strings.Fields(scope))
+		if !coversScopes(grantedScopes, scopes) {
+			return nil, oauthError("invalid_scope", "The requested scope exceeds the granted scope")
+		}
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").First(&user, *token.UserID).Error; err != nil || !user.IsActive {
+		return nil, oauthError("invalid_grant", "The user is no longer active")
+	}
+
+	result := s.db.Model(&models.OAuthToken{}).
+		Where("id = ? AND revoked_at IS NULL", token.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, oauthError("invalid_grant", "Invalid refresh token")
+	}
+
+	return s.issueTokens(client, &user, scopes, grantedScopes, "", token.FamilyID)
+}
+
+// ClientCredentials issues an access token to a confidential client acting on its own
+// behalf. Only the client's custom scopes can be requested, as there is no user.
+func (s *OAuthService) ClientCredentials(client *models.OAuthClient, scope string) (*models.OAuthTokenResponse, error) {
+	if client.Type != models.OAuthClientConfidential || !clientAllowsGrant(client, models.OAuthGrantClientCredentials) {
+		return nil, oauthError("unauthorized_client", "The client may not use this grant type")
+	}
+
+	var scopes []string
+	if scope == "" {
+		for _, clientScope := range strings.Fields(client.Scopes) {
+			if _, userScope := oauthScopeDescriptions[clientScope]; !userScope {
+				scopes = append(scopes, clientScope)
+			}
+		}
+	} else {
+		scopes = uniqueStrings(strings.Fields(scope))
+		for _, requested := range scopes {
+			if _, userScope := oauthScopeDescriptions[requested]; userScope || !containsString(strings.Fields(client.Scopes), requested) {
+				return nil, oauthError("invalid_scope", "The client may not request scope "+requested)
+			}
+		}
+	}
+
+	return s.issueTokens(client, nil, scopes, nil, "", uuid.New().String())
+}
+
+// Introspect reports whether a token is active and what it grants (RFC 7662)
+func (s *OAuthService) Introspect(tokenString string) *models.OAuthIntrospectionResponse {
+	token, err := s.lookupToken(tokenString)
+	if err != nil || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return &models.OAuthIntrospectionResponse{Active: false}
+	}
+
+	response := &models.OAuthIntrospectionResponse{
+		Active:   true,
+		Scope:    token.Scope,
+		ClientID: token.ClientID,
+		Exp:      token.ExpiresAt.Unix(),
+		Iat:      token.CreatedAt.Unix(),
+		Sub:      token.ClientID,
+		Iss:      s.issuer(),
+	}
+	if token.TokenType == models.OAuthTokenAccess {
+		response.TokenType = "Bearer"
+	}
+
+	if token.UserID != nil {
+		var user models.Users
+		if err := s.db.First(&user, *token.UserID).Error; err != nil || !user.IsActive {
+			return &models.OAuthIntrospectionResponse{Active: false}
+		}
+		response.Sub = strconv.FormatUint(uint64(user.ID), 10)
+		response.Username = user.Username
+	}
+
+	return response
+}
+
+// Revoke revokes a token issued to the client (RFC 7009). Revoking a refresh token
+// also revokes the access tokens issued with it. Unknown tokens are ignored.
+func (s *OAuthService) Revoke(client *models.OAuthClient, tokenString string) error {
+	token, err := s.lookupToken(tokenString)
+	if err != nil || token.ClientID != client.ClientID {
+		return nil
+	}
+
+	if token.TokenType == models.OAuthTokenRefresh {
+		return s.revokeFamily(token.FamilyID)
+	}
+	return s.db.Model(token).Where("revoked_at IS NULL").Update("revoked_at", time.Now()).Error
+}
+
+// UserInfo returns the claims about the user an access token was issued for
+func (s *OAuthService) UserInfo(accessToken string) (map[string]any, error) {
+	token, err := s.lookupToken(accessToken)
+	if err != nil || token.TokenType != models.OAuthTokenAccess || token.UserID == nil ||
+		token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errors.New("invalid token")
+	}
+
+	scopes := strings.Fields(token.Scope)
+	if !containsString(scopes, oauthScopeOpenID) {
+		return nil, errors.New("insufficient scope")
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").First(&user, *token.UserID).Error; err != nil || !user.IsActive {
+		return nil, errors.New("invalid token")
+	}
+
+	return s.userClaims(&user, scopes)
+}
+
+// Discovery returns the OpenID Connect discovery document
+func (s *OAuthService) Discovery() models.OIDCDiscoveryDocument {
+	issuer := s.issuer()
+	return models.OIDCDiscoveryDocument{
+		Issuer: issuer,
+		// The consent screen is part of the frontend
+		AuthorizationEndpoint:             strings.TrimRight(s.config.AppBaseURL, "/") + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/api/oauth/token",
+		UserinfoEndpoint:                  issuer + "/api/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/api/oauth/introspect",
+		RevocationEndpoint:                issuer + "/api/oauth/revoke",
+		ScopesSupported:                   []string{oauthScopeOpenID, oauthScopeProfile, oauthScopeEmail, oauthScopeRoles},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{models.OAuthGrantAuthorizationCode, models.OAuthGrantRefreshToken, models.OAuthGrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.tokenService.SigningAlg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "nonce",
+			"name", "preferred_username", "updated_at", "email", "email_verified", "role", "roles", "permissions",
+		},
+	}
+}
+
+// validateAuthorizeRequest checks an authorization request against the client registration
+func (s *OAuthService) validateAuthorizeRequest(req *models.OAuthAuthorizeRequest) (*models.OAuthClient, []string, error) {
+	var client models.OAuthClient
+	if req.ClientID == "" || s.db.Where("client_id = ? AND is_active = ?", req.ClientID, true).First(&client).Error != nil {
+		return nil, nil, oauthError("invalid_client", "Unknown client")
+	}
+
+	if !containsString(strings.Fields(client.RedirectURIs), req.RedirectURI) {
+		return nil, nil, oauthError("invalid_request", "redirect_uri is not registered for the client")
+	}
+	if req.ResponseType != "code" {
+		return nil, nil, oauthError("unsupported_response_type", "Only the code response type is supported")
+	}
+	if !clientAllowsGrant(&client, models.OAuthGrantAuthorizationCode) {
+		return nil, nil, oauthError("unauthorized_client", "The client may not use the authorization code grant")
+	}
+
+	scopes := uniqueStrings(strings.Fields(req.Scope))
+	if len(scopes) == 0 {
+		return nil, nil, oauthError("invalid_scope", "scope is required")
+	}
+	if !coversScopes(strings.Fields(client.Scopes), scopes) {
+		return nil, nil, oauthError("invalid_scope", "The client may not request the given scope")
+	}
+
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return nil, nil, oauthError("invalid_request", "code_challenge_method must be S256")
+	}
+	if client.Type == models.OAuthClientPublic && req.CodeChallenge == "" {
+		return nil, nil, oauthError("invalid_request", "PKCE is required for public clients")
+	}
+
+	return &client, scopes, nil
+}
+
+// issueTokens signs an access token, and an ID token when openid was granted, and
+// records them for introspection and revocation. refreshScopes is nil when no
+// refresh token should be issued.
+func (s *OAuthService) issueTokens(client *models.OAuthClient, user *models.Users, scopes, refreshScopes []string, nonce, familyID string) (*models.OAuthTokenResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.config.OAuthAccessTokenTTL)
+	jti := uuid.New().String()
+
+	subject := client.ClientID
+	var userID *uint
+	if user != nil {
+		subject = strconv.FormatUint(uint64(user.ID), 10)
+		userID = &user.ID
+	}
+
+	scope := strings.Join(scopes, " ")
+	accessToken, err := s.tokenService.SignClaims(models.OAuthAccessClaims{
+		Scope:    scope,
+		ClientID: client.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer(),
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.OAuthAccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}
+	records := []models.OAuthToken{{
+		TokenHash: hashOAuthToken(jti),
+		TokenType: models.OAuthTokenAccess,
+		ClientID:  client.ClientID,
+		UserID:    userID,
+		Scope:     scope,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+	}}
+
+	if refreshScopes != nil {
+		refreshToken, err := s.tokenService.GenerateSecureToken()
+		if err != nil {
+			return nil, err
+		}
+		response.RefreshToken = refreshToken
+		records = append(records, models.OAuthToken{
+			TokenHash: hashOAuthToken(refreshToken),
+			TokenType: models.OAuthTokenRefresh,
+			ClientID:  client.ClientID,
+			UserID:    userID,
+			Scope:     strings.Join(refreshScopes, " "),
+			FamilyID:  familyID,
+			ExpiresAt: now.Add(s.config.OAuthRefreshTokenTTL),
+		})
+	}
+
+	if user != nil && containsString(scopes, oauthScopeOpenID) {
+		claims, err := s.userClaims(user, scopes)
+		if err != nil {
+			return nil, err
+		}
+		claims["iss"] = s.issuer()
+		claims["aud"] = client.ClientID
+		claims["exp"] = expiresAt.Unix()
+		claims["iat"] = now.Unix()
+		if nonce != "" {
+			claims["nonce"] = nonce
+		}
+
+		response.IDToken, err = s.tokenService.SignClaims(jwt.MapClaims(claims))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// userClaims returns the OpenID Connect claims about a user allowed by the scopes
+func (s *OAuthService) userClaims(user *models.Users, scopes []string) (map[string]any, error) {
+	claims := map[string]any{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+
+	if containsString(scopes, oauthScopeProfile) {
+		claims["name"] = user.Name
+		claims["preferred_username"] = user.Username
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+
+	if containsString(scopes, oauthScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+
+	if containsString(scopes, oauthScopeRoles) {
+		if err := loadUserRoles(s.db, user); err != nil {
+			return nil, err
+		}
+		userPerms, err := s.permissionService.GetUserPermissions(user.ID, user.RoleIDs())
+		if err != nil {
+			return nil, err
+		}
+		claims["role"] = user.Role.Name
+		claims["roles"] = user.RoleNames()
+		claims["permissions"] = userPerms.Scopes()
+	}
+
+	return claims, nil
+}
+
+// lookupToken finds the stored record for an access token (a JWT) or a refresh token
+func (s *OAuthService) lookupToken(tokenString string) (*models.OAuthToken, error) {
+	tokenHash := hashOAuthToken(tokenString)
+	tokenType := models.OAuthTokenRefresh
+
+	if strings.Count(tokenString, ".") == 2 {
+		claims := &models.OAuthAccessClaims{}
+		token, err := s.tokenService.ParseClaims(tokenString, claims, jwt.WithIssuer(s.issuer()))
+		if err != nil || !token.Valid || claims.ID == "" {
+			return nil, errors.New("invalid token")
+		}
+		tokenHash = hashOAuthToken(claims.ID)
+		tokenType = models.OAuthTokenAccess
+	}
+
+	var token models.OAuthToken
+	if err := s.db.Where("token_hash = ? AND token_type = ?", tokenHash, tokenType).First(&token).Error; err != nil {
+		return nil, errors.New("invalid token")
+	}
+	return &token, nil
+}
+
+// revokeFamily revokes every token issued from the same grant
+func (s *OAuthService) revokeFamily(familyID string) error {
+	return s.db.Model(&models.OAuthToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// issuer is the OAuth issuer identifier, the public URL of this API
+func (s *OAuthService) issuer() string {
+	return strings.TrimRight(s.config.APIBaseURL, "/")
+}
+
+// newOAuthClientResponse converts a client to its public representation
+func newOAuthClientResponse(client models.OAuthClient) models.OAuthClientResponse {
+	return models.OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Type:         client.Type,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		Scopes:       strings.Fields(client.Scopes),
+		GrantTypes:   strings.Fields(client.GrantTypes),
+		IsActive:     client.IsActive,
+		CreatedAt:    client.CreatedAt,
+
+		OrganizationID: client.OrganizationID,
+	}
+}
+
+// clientAllowsGrant reports whether the client is registered for a grant type
+func clientAllowsGrant(client *models.OAuthClient, grantType string) bool {
+	return containsString(strings.Fields(client.GrantTypes), grantType)
+}
+
+// coversScopes reports whether every requested scope is in granted
+func coversScopes(granted, requested []string) bool {
+	for _, scope := range requested {
+		if !containsString(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// withQueryParams adds parameters to a redirect URI, keeping any query it already has
+func withQueryParams(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// hashOAuthToken hashes client secrets, codes and tokens for storage
+func hashOAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrings removes duplicates, keeping the first occurrence
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
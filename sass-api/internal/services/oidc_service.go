@@ -0,0 +1,473 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcStatePurpose marks JWTs that carry the state of a login in progress at a provider
+	oidcStatePurpose = "oidc_state"
+
+	// oidcHTTPTimeout bounds discovery, token and userinfo requests to a provider
+	oidcHTTPTimeout = 10 * time.Second
+)
+
+// OIDCLoginResult is the local user an external identity resolved to
+type OIDCLoginResult struct {
+	User         *models.Users
+	Provider     string
+	RedirectPath string
+	Linked       bool // The identity was linked to an existing account on this login
+	Created      bool // The account was created on this login
+}
+
+// oidcProvider is a provider whose discovery document has been fetched
+type oidcProvider struct {
+	config   config.OIDCProviderConfig
+	provider *oidc.Provider
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// OIDCService signs users in with external OpenID Connect providers using the
+// authorization code flow with PKCE
+type OIDCService struct {
+	db           *gorm.DB
+	config       *config.Config
+	tokenService *TokenService
+	httpClient   *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*oidcProvider
+}
+
+func NewOIDCService(db *gorm.DB, config *config.Config, tokenService *TokenService) *OIDCService {
+	return &OIDCService{
+		db:           db,
+		config:       config,
+		tokenService: tokenService,
+		httpClient:   &http.Client{Timeout: oidcHTTPTimeout},
+		providers:    make(map[string]*oidcProvider),
+	}
+}
+
+// ListProviders returns the providers users can sign in with
+func (s *OIDCService) ListProviders() []models.OIDCProviderResponse {
+	providers := make([]models.OIDCProviderResponse, 0, len(s.config.OIDCProviders))
+	for _, p := range s.config.OIDCProviders {
+		providers = append(providers, models.OIDCProviderResponse{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+			LoginURL:    "/api/auth/oidc/" + p.Name + "/login",
+		})
+	}
+	return providers
+}
+
+// BeginLogin returns the provider's authorization URL and the signed state to keep in
+// a cookie until the provider redirects back
+func (s *OIDCService) BeginLogin(providerName, redirectPath string) (string, string, error) {
+	p, err := s.provider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	claims := models.OIDCStateClaims{
+		Provider:     p.config.Name,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectPath: safeRedirectPath(redirectPath),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.OIDCStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "sass-api",
+			Audience:  jwt.ClaimStrings{oidcStatePurpose},
+		},
+	}
+	stateToken, err := s.tokenService.SignClaims(claims)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL := p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return authURL, stateToken, nil
+}
+
+// FinishLogin exchanges the authorization code, verifies the ID token and resolves
+// the local user for the identity, linking or creating the account when needed
+func (s *OIDCService) FinishLogin(ctx context.Context, providerName, code, state, stateToken string) (*OIDCLoginResult, error) {
+	claims := &models.OIDCStateClaims{}
+	token, err := s.tokenService.ParseClaims(stateToken, claims, jwt.WithAudience(oidcStatePurpose))
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid oidc state")
+	}
+	if claims.Provider != providerName || subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		return nil, errors.New("invalid oidc state")
+	}
+
+	p, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, s.httpClient)
+	oauthToken, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(claims.CodeVerifier))
+	if err != nil {
+		logger.Warnf("OIDC code exchange with %s failed: %v", providerName, err)
+		return nil, errors.New("oidc login failed")
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc login failed")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		logger.Warnf("OIDC ID token from %s rejected: %v", providerName, err)
+		return nil, errors.New("oidc login failed")
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(claims.Nonce)) != 1 {
+		return nil, errors.New("oidc login failed")
+	}
+
+	identityClaims := make(map[string]any)
+	if err := idToken.Claims(&identityClaims); err != nil {
+		return nil, errors.New("oidc login failed")
+	}
+
+	// Some providers only put profile and group claims in the userinfo response
+	if p.provider.UserInfoEndpoint() != "" {
+		if userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(oauthToken)); err == nil && userInfo.Subject == idToken.Subject {
+			userInfoClaims := make(map[string]any)
+			if err := userInfo.Claims(&userInfoClaims); err == nil {
+				for key, value := range userInfoClaims {
+					if _, exists := identityClaims[key]; !exists {
+						identityClaims[key] = value
+					}
+				}
+			}
+		}
+	}
+
+	result, err := s.resolveUser(p.config, idToken.Subject, identityClaims)
+	if err != nil {
+		return nil, err
+	}
+	result.Provider = p.config.Name
+	result.RedirectPath = claims.RedirectPath
+	return result, nil
+}
+
+// resolveUser finds the user linked to an external identity. Unlinked identities are
+// linked by verified email, or get a new account when the provider allows signup.
+func (s *OIDCService) resolveUser(p config.OIDCProviderConfig, subject string, claims map[string]any) (*OIDCLoginResult, error) {
+	result := &OIDCLoginResult{}
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.ExternalIdentity
+		err := tx.Where("provider = ? AND subject = ?", p.Name, subject).First(&identity).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var user models.Users
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return errors.New("oidc account not linked")
+			}
+		} else {
+			// Only a verified address proves the identity owns the local account
+			email := stringClaim(claims, p.EmailClaim)
+			if email == "" || !boolClaim(claims, "email_verified") {
+				return errors.New("oidc email not verified")
+			}
+
+			err := tx.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+			switch {
+			case err == nil:
+				result.Linked = true
+			case errors.Is(err, gorm.ErrRecordNotFound) && p.AllowSignup:
+				if err := s.createExternalUser(tx, p, email, claims, &user); err != nil {
+					return err
+				}
+				result.Created = true
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return errors.New("oidc account not linked")
+			default:
+				return err
+			}
+
+			identity = models.ExternalIdentity{
+				UserID:   user.ID,
+				Provider: p.Name,
+				Subject:  subject,
+			}
+		}
+
+		identity.Email = stringClaim(claims, p.EmailClaim)
+		identity.LastLoginAt = &now
+		if err := tx.Save(&identity).Error; err != nil {
+			return err
+		}
+
+		if role, ok, err := mappedRole(tx, p, claims); err != nil {
+			return err
+		} else if ok && role.ID != user.RoleID {
+			if err := replacePrimaryRole(tx, &user, role.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID).Error; err != nil {
+			return err
+		}
+		result.User = &user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// createExternalUser creates an account for an identity that has no local user.
+// The password is random, so the account can only sign in through the provider
+// until the user resets it.
+func (s *OIDCService) createExternalUser(tx *gorm.DB, p config.OIDCProviderConfig, email string, claims map[string]any, user *models.Users) error {
+	// New accounts join the default organization, and roles are resolved there
+	organization, err := defaultOrganization(tx, s.config)
+	if err != nil {
+		return err
+	}
+	organizationID := &organization.ID
+
+	role, ok, err := mappedRole(tenant.Scoped(tx, organization.ID), p, claims)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fallback, err := defaultRole(tx, organization.ID)
+		if err != nil {
+			return err
+		}
+		role = *fallback
+	} else if role.OrganizationID == nil && slices.Contains(config.CrossTenantRoles, role.Name) {
+		organizationID = nil
+	}
+
+	username, err := s.uniqueUsername(tx, stringClaim(claims, p.UsernameClaim), email)
+	if err != nil {
+		return err
+	}
+
+	name := stringClaim(claims, p.NameClaim)
+	if name == "" {
+		name = username
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+
+	password, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*user = models.Users{
+		Username:          username,
+		Email:             email,
+		Password:          string(hashedPassword),
+		Name:              name,
+		RoleID:            role.ID,
+		OrganizationID:    organizationID,
+		IsActive:          true,
+		EmailVerifiedAt:   &now,
+		PasswordChangedAt: &now,
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	return setUserRoles(tx, user.ID, user.RoleID, nil)
+}
+
+// uniqueUsername derives a free username from the provider's username claim, falling
+// back to the local part of the email
+func (s *OIDCService) uniqueUsername(tx *gorm.DB, preferred, email string) (string, error) {
+	base := preferred
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Users{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", errors.New("username already exists")
+}
+
+// provider returns the discovered provider, fetching its discovery document on first use
+func (s *OIDCService) provider(name string) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.providers[name]; ok {
+		return p, nil
+	}
+
+	var cfg *config.OIDCProviderConfig
+	for i := range s.config.OIDCProviders {
+		if s.config.OIDCProviders[i].Name == name {
+			cfg = &s.config.OIDCProviders[i]
+			break
+		}
+	}
+	if cfg == nil {
+		return nil, errors.New("oidc provider not found")
+	}
+
+	// The provider keeps this context to refresh its signing keys, so it must not be request scoped
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), s.httpClient), cfg.Issuer)
+	if err != nil {
+		logger.Errorf("OIDC discovery for %s failed: %v", name, err)
+		return nil, errors.New("oidc provider unavailable")
+	}
+
+	p := &oidcProvider{
+		config:   *cfg,
+		provider: provider,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  strings.TrimRight(s.config.APIBaseURL, "/") + "/api/auth/oidc/" + cfg.Name + "/callback",
+			Scopes:       cfg.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	s.providers[name] = p
+	return p, nil
+}
+
+// mappedRole returns the role for the first role mapping whose group is in the groups claim
+func mappedRole(tx *gorm.DB, p config.OIDCProviderConfig, claims map[string]any) (models.Role, bool, error) {
+	groups := make(map[string]bool)
+	for _, group := range stringsClaim(claims, p.GroupsClaim) {
+		groups[group] = true
+	}
+
+	for _, mapping := range p.RoleMappings {
+		if !groups[mapping.Group] {
+			continue
+		}
+
+		var role models.Role
+		err := tx.Where("name = ?", mapping.Role).Order("organization_id IS NULL").First(&role).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warnf("OIDC role mapping for %s refers to unknown role %q", p.Name, mapping.Role)
+			continue
+		}
+		if err != nil {
+			return models.Role{}, false, err
+		}
+		return role, true, nil
+	}
+	return models.Role{}, false, nil
+}
+
+// safeRedirectPath only allows paths on the frontend, so the login can't be used as an open redirect
+func safeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, "\\") {
+		return "/"
+	}
+	return path
+}
+
+// stringClaim reads a string claim, returning "" when it is missing or not a string
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim. Some providers send booleans as strings.
+func boolClaim(claims map[string]any, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+// stringsClaim reads a claim holding a list of strings, or a single string
+func stringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/oidctest"
+)
+
+func newTestOIDCService(t *testing.T, allowSignup bool) (*OIDCService, *oidctest.Provider) {
+	t.Helper()
+
+	provider := oidctest.NewProvider(t, "sass-api", "client-secret")
+	db := newTestDB(t, &models.Organization{}, &models.Role{}, &models.Users{}, &models.UserRole{}, &models.ExternalIdentity{})
+
+	cfg := &config.Config{
+		JWTSigningAlg:       "HS256",
+		JWTSecret:           "test-secret",
+		APIBaseURL:          "http://api.test",
+		OIDCStateTTL:        10 * time.Minute,
+		DefaultOrganization: "default",
+		OIDCProviders: []config.OIDCProviderConfig{
+			{
+				Name:          "mock",
+				Issuer:        provider.Issuer(),
+				ClientID:      provider.ClientID,
+				ClientSecret:  provider.ClientSecret,
+				Scopes:        []string{"openid", "email", "profile"},
+				UsernameClaim: "preferred_username",
+				NameClaim:     "name",
+				EmailClaim:    "email",
+				GroupsClaim:   "groups",
+				AllowSignup:   allowSignup,
+			},
+			{
+				Name:         "other",
+				Issuer:       provider.Issuer(),
+				ClientID:     provider.ClientID,
+				ClientSecret: provider.ClientSecret,
+				Scopes:       []string{"openid"},
+				EmailClaim:   "email",
+			},
+		},
+	}
+
+	keyRing, err := NewKeyRing(cfg)
+	if err != nil {
+		t.Fatalf("new key ring: %v", err)
+	}
+	tokenService := NewTokenService(db, cfg, NewAuditService(db), keyRing)
+
+	return NewOIDCService(db, cfg, tokenService), provider
+}
+
+// oidcLogin runs a login at the mock provider and finishes it with the callback values
+func oidcLogin(t *testing.T, s *OIDCService, provider *oidctest.Provider, providerName string) (*OIDCLoginResult, error) {
+	t.Helper()
+
+	authURL, stateToken, err := s.BeginLogin(providerName, "/dashboard")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	code, state, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return s.FinishLogin(context.Background(), providerName, code, state, stateToken)
+}
+
+func TestOIDCLoginLinksAccountByVerifiedEmail(t *testing.T) {
+	s, provider := newTestOIDCService(t, false)
+	user := createTestUser(t, s.db, "alice")
+	provider.Claims = map[string]any{"sub": "ext-alice", "email": "ALICE@example.com", "email_verified": true}
+
+	result, err := oidcLogin(t, s, provider, "mock")
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if result.User.ID != user.ID || !result.Linked || result.Created {
+		t.Errorf("first login = user %d linked %v created %v, want user %d linked", result.User.ID, result.Linked, result.Created, user.ID)
+	}
+	if result.RedirectPath != "/dashboard" {
+		t.Errorf("redirect path = %q, want /dashboard", result.RedirectPath)
+	}
+
+	// The linked identity signs in without relinking, even after the email changes
+	provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@elsewhere.example", "email_verified": false}
+	result, err = oidcLogin(t, s, provider, "mock")
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if result.User.ID != user.ID || result.Linked {
+		t.Errorf("second login = user %d linked %v, want user %d not relinked", result.User.ID, result.Linked, user.ID)
+	}
+
+	var identities []models.ExternalIdentity
+	s.db.Find(&identities)
+	if len(identities) != 1 || identities[0].Subject != "ext-alice" || identities[0].Email != "alice@elsewhere.example" {
+		t.Errorf("identities = %+v, want one for ext-alice with the latest email", identities)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	s, provider := newTestOIDCService(t, true)
+	createTestUser(t, s.db, "alice")
+	provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@example.com", "email_verified": false}
+
+	if _, err := oidcLogin(t, s, provider, "mock"); err == nil || err.Error() != "oidc email not verified" {
+		t.Errorf("error = %v, want oidc email not verified", err)
+	}
+
+	var count int64
+	s.db.Model(&models.ExternalIdentity{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d identities linked, want 0", count)
+	}
+}
+
+func TestOIDCLoginUnknownEmailWithoutSignup(t *testing.T) {
+	s, provider := newTestOIDCService(t, false)
+	provider.Claims = map[string]any{"sub": "ext-new", "email": "new@example.com", "email_verified": true}
+
+	if _, err := oidcLogin(t, s, provider, "mock"); err == nil || err.Error() != "oidc account not linked" {
+		t.Errorf("error = %v, want oidc account not linked", err)
+	}
+}
+
+func TestOIDCLoginCreatesAccount(t *testing.T) {
+	s, provider := newTestOIDCService(t, true)
+	organization := models.Organization{Name: "Default", Slug: "default", IsActive: true}
+	s.db.Create(&organization)
+	s.db.Create(&models.Role{Name: "user", DisplayName: "User", IsActive: true})
+	provider.Claims = map[string]any{
+		"sub":                "ext-new",
+		"email":              "new@example.com",
+		"email_verified":     true,
+		"preferred_username": "newbie",
+		"name":               "New User",
+	}
+
+	result, err := oidcLogin(t, s, provider, "mock")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !result.Created {
+		t.Error("account not reported as created")
+	}
+	user := result.User
+	if user.Username != "newbie" || user.Name != "New User" || user.Email != "new@example.com" {
+		t.Errorf("created user = %s / %s / %s", user.Username, user.Name, user.Email)
+	}
+	if user.OrganizationID == nil || *user.OrganizationID != organization.ID {
+		t.Errorf("organization = %v, want %d", user.OrganizationID, organization.ID)
+	}
+	if user.Role.Name != "user" || user.EmailVerifiedAt == nil {
+		t.Errorf("role = %q, email verified at %v", user.Role.Name, user.EmailVerifiedAt)
+	}
+}
+
+func TestOIDCLoginChecksState(t *testing.T) {
+	s, provider := newTestOIDCService(t, false)
+	createTestUser(t, s.db, "alice")
+	provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+
+	authURL, stateToken, err := s.BeginLogin("mock", "/")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	_, otherStateToken, err := s.BeginLogin("other", "/")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		provider   string
+		state      func(string) string
+		stateToken string
+	}{
+		{"state mismatch", "mock", func(string) string { return "forged" }, stateToken},
+		{"missing cookie", "mock", func(state string) string { return state }, ""},
+		{"tampered cookie", "mock", func(state string) string { return state }, stateToken + "x"},
+		{"cookie of another login", "mock", func(state string) string { return state }, otherStateToken},
+		{"callback of another provider", "other", func(state string) string { return state }, stateToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, state, err := provider.Authorize(authURL)
+			if err != nil {
+				t.Fatalf("authorize: %v", err)
+			}
+			_, err = s.FinishLogin(context.Background(), tt.provider, code, tt.state(state), tt.stateToken)
+			if err == nil || err.Error() != "invalid oidc state" {
+				t.Errorf("error = %v, want invalid oidc state", err)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginVerifiesIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{"wrong issuer", map[string]any{"iss": "https://attacker.example"}},
+		{"wrong audience", map[string]any{"aud": "another-client"}},
+		{"wrong nonce", map[string]any{"nonce": "replayed"}},
+		{"expired", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, provider := newTestOIDCService(t, true)
+			createTestUser(t, s.db, "alice")
+
+			provider.Claims = map[string]any{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+			for name, value := range tt.claims {
+				provider.Claims[name] = value
+			}
+
+			if _, err := oidcLogin(t, s, provider, "mock"); err == nil || err.Error() != "oidc login failed" {
+				t.Errorf("error = %v, want oidc login failed", err)
+			}
+
+			var count int64
+			s.db.Model(&models.ExternalIdentity{}).Count(&count)
+			if count != 0 {
+				t.Errorf("%d identities linked, want 0", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// organizationSlugPattern keeps slugs usable as a DNS label
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// OrganizationService manages the organizations (tenants) of the platform. It is only
+// reachable by cross-tenant roles.
+type OrganizationService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewOrganizationService(db *gorm.DB, config *config.Config) *OrganizationService {
+	return &OrganizationService{
+		db:     db,
+		config: config,
+	}
+}
+
+// GetAllOrganizations retrieves organizations with pagination
+func (s *OrganizationService) GetAllOrganizations(params pagination.QueryParams) (*pagination.PaginatedResponse, error) {
+	config := pagination.PaginationConfig{
+		Model:         &models.Organization{},
+		BaseCondition: map[string]interface{}{},
+		SearchFields:  []string{"name", "slug"},
+		FilterFields: map[string]string{
+			"name":      "name",
+			"slug":      "slug",
+			"is_active": "is_active",
+		},
+		SortFields:   []string{"name", "slug", "created_at"},
+		DefaultSort:  "created_at",
+		DefaultOrder: "DESC",
+	}
+
+	paginator := pagination.NewPaginator(s.db)
+	return paginator.Paginate(params, config)
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (s *OrganizationService) GetOrganizationByID(id uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := s.db.First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// CreateOrganization creates a new organization
+func (s *OrganizationService) CreateOrganization(req *models.CreateOrganizationRequest) (*models.Organization, error) {
+	slug, err := s.checkSlug(req.Slug, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	organization := models.Organization{
+		Name:     req.Name,
+		Slug:     slug,
+		IsActive: true,
+	}
+	if err := s.db.Create(&organization).Error; err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// UpdateOrganization updates an existing organization. Deactivating it locks its users
+// out until it is activated again.
+func (s *OrganizationService) UpdateOrganization(id uint, req *models.UpdateOrganizationRequest) (*models.Organization, error) {
+	organization, err := s.GetOrganizationByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	slug, err := s.checkSlug(req.Slug, id)
+	if err != nil {
+		return nil, err
+	}
+	if slug != organization.Slug && organization.Slug == s.config.DefaultOrganization {
+		return nil, errors.New("cannot rename the default organization")
+	}
+
+	if req.IsActive == nil {
+		return nil, errors.New("is_active is required")
+	}
+
+	organization.Name = req.Name
+	organization.Slug = slug
+	organization.IsActive = *req.IsActive
+
+	// Select is_active explicitly so deactivating isn't skipped as a zero value
+	if err := s.db.Model(organization).Select("name", "slug", "is_active").Updates(organization).Error; err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// DeleteOrganization deletes an organization (soft delete). Organizations that still
+// have users can't be deleted.
+func (s *OrganizationService) DeleteOrganization(id uint) error {
+	organization, err := s.GetOrganizationByID(id)
+	if err != nil {
+		return err
+	}
+
+	if organization.Slug == s.config.DefaultOrganization {
+		return errors.New("cannot delete the default organization")
+	}
+
+	var userCount int64
+	if err := s.db.Model(&models.Users{}).Where("organization_id = ?", id).Count(&userCount).Error; err != nil {
+		return err
+	}
+	if userCount > 0 {
+		return fmt.Errorf("cannot delete organization: %d users belong to it", userCount)
+	}
+
+	return s.db.Delete(organization).Error
+}
+
+// checkSlug normalizes a slug and makes sure no other organization uses it
+func (s *OrganizationService) checkSlug(slug string, id uint) (string, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !organizationSlugPattern.MatchString(slug) {
+		return "", errors.New("invalid organization slug")
+	}
+
+	var existing models.Organization
+	if err := s.db.Unscoped().Where("slug = ? AND id != ?", slug, id).First(&existing).Error; err == nil {
+		return "", errors.New("organization slug already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	return slug, nil
+}
+
+// defaultOrganization returns the organization users join when nothing else places them,
+// e.g. self-registration
+func defaultOrganization(db *gorm.DB, cfg *config.Config) (*models.Organization, error) {
+	var organization models.Organization
+	if err := db.Where("slug = ?", cfg.DefaultOrganization).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("default organization not found")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// defaultRole returns the role new members of an organization get: its own default
+// role if it has one, otherwise the shared default role, falling back to "user"
+func defaultRole(db *gorm.DB, organizationID uint) (*models.Role, error) {
+	scoped := tenant.Scoped(db, organizationID)
+
+	var role models.Role
+	if err := scoped.Where("is_default = ?", true).Order("organization_id IS NULL").First(&role).Error; err == nil {
+		return &role, nil
+	}
+	if err := scoped.Where("name = ?", "user").Order("organization_id IS NULL").First(&role).Error; err != nil {
+		return nil, errors.New("default role not found")
+	}
+	return &role, nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordPolicyError is returned when a password breaks one or more policy rules
+type PasswordPolicyError struct {
+	Violations []models.PasswordPolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet policy"
+}
+
+// PasswordPolicyService validates new passwords against the configured policy
+// and keeps the password history used to prevent reuse
+type PasswordPolicyService struct {
+	db       *gorm.DB
+	config   *config.Config
+	denylist map[string]struct{}
+}
+
+func NewPasswordPolicyService(db *gorm.DB, config *config.Config) (*PasswordPolicyService, error) {
+	s := &PasswordPolicyService{
+		db:     db,
+		config: config,
+	}
+
+	if config.PasswordDenylistFile != "" {
+		denylist, err := loadPasswordDenylist(config.PasswordDenylistFile)
+		if err != nil {
+			return nil, err
+		}
+		s.denylist = denylist
+	}
+
+	return s, nil
+}
+
+// GetPolicy returns the active password rules
+func (s *PasswordPolicyService) GetPolicy() models.PasswordPolicyResponse {
+	return models.PasswordPolicyResponse{
+		MinLength:        s.config.PasswordMinLength,
+		MaxLength:        s.config.PasswordMaxLength,
+		RequireUppercase: s.config.PasswordRequireUpper,
+		RequireLowercase: s.config.PasswordRequireLower,
+		RequireDigit:     s.config.PasswordRequireDigit,
+		RequireSymbol:    s.config.PasswordRequireSymbol,
+		DisallowUserInfo: s.config.PasswordDisallowUserInfo,
+		DenylistEnabled:  len(s.denylist) > 0,
+		HistorySize:      s.config.PasswordHistorySize,
+		MaxAgeDays:       int(s.config.PasswordMaxAge.Hours() / 24),
+	}
+}
+
+// Validate checks a new password for the given user and returns a PasswordPolicyError
+// listing every rule it breaks. The user needs no ID when the account doesn't exist yet.
+func (s *PasswordPolicyService) Validate(password string, user *models.Users) error {
+	var violations []models.PasswordPolicyViolation
+	add := func(rule, message string) {
+		violations = append(violations, models.PasswordPolicyViolation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < s.config.PasswordMinLength {
+		add("min_length", fmt.Sprintf("Must be at least %d characters long", s.config.PasswordMinLength))
+	}
+	if len(password) > s.config.PasswordMaxLength {
+		add("max_length", fmt.Sprintf("Must be at most %d characters long", s.config.PasswordMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if s.config.PasswordRequireUpper && !hasUpper {
+		add("uppercase", "Must contain an uppercase letter")
+	}
+	if s.config.PasswordRequireLower && !hasLower {
+		add("lowercase", "Must contain a lowercase letter")
+	}
+	if s.config.PasswordRequireDigit && !hasDigit {
+		add("digit", "Must contain a number")
+	}
+	if s.config.PasswordRequireSymbol && !hasSymbol {
+		add("symbol", "Must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if s.config.PasswordDisallowUserInfo && user != nil && containsUserInfo(lowered, user) {
+		add("user_info", "Must not contain your username or email")
+	}
+
+	if _, found := s.denylist[lowered]; found {
+		add("denylist", "Is too common, choose a less predictable password")
+	}
+
+	// Comparing hashes is slow, so only do it for otherwise acceptable passwords
+	if len(violations) == 0 && user != nil && user.ID != 0 && s.isReused(password, user) {
+		add("history", fmt.Sprintf("Must not match any of your last %d passwords", s.config.PasswordHistorySize))
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// SetPassword hashes and stores a new password for the user and records it in the history.
+// The password must already have passed Validate.
+func (s *PasswordPolicyService) SetPassword(tx *gorm.DB, userID uint, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Users{}).Where("id = ?", userID).Updates(map[string]any{
+		"password":            string(hashedPassword),
+		"password_changed_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	return s.RecordPassword(tx, userID, string(hashedPassword))
+}
+
+// RecordPassword adds a password hash to the user's history and drops entries past the history size
+func (s *PasswordPolicyService) RecordPassword(tx *gorm.DB, userID uint, hashedPassword string) error {
+	if s.config.PasswordHistorySize <= 0 {
+		return nil
+	}
+
+	if err := tx.Create(&models.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: hashedPassword,
+	}).Error; err != nil {
+		return err
+	}
+
+	keep := tx.Model(&models.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(s.config.PasswordHistorySize)
+
+	return tx.Where("user_id = ? AND id NOT IN (?)", userID, keep).Delete(&models.PasswordHistory{}).Error
+}
+
+// IsExpired reports whether the user's password is older than the configured maximum age
+func (s *PasswordPolicyService) IsExpired(user *models.Users) bool {
+	if s.config.PasswordMaxAge <= 0 {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > s.config.PasswordMaxAge
+}
+
+// isReused reports whether the password matches the current one or one in the recent history
+func (s *PasswordPolicyService) isReused(password string, user *models.Users) bool {
+	if s.config.PasswordHistorySize <= 0 {
+		return false
+	}
+
+	var hashes []string
+	s.db.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", user.ID).
+		Order("id DESC").
+		Limit(s.config.PasswordHistorySize).
+		Pluck("password_hash", &hashes)
+
+	// Accounts created before history tracking only have their current password
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// containsUserInfo reports whether a lowercased password contains the username or the name part of the email
+func containsUserInfo(password string, user *models.Users) bool {
+	candidates := []string{strings.ToLower(user.Username)}
+	if at := strings.Index(user.Email, "@"); at > 0 {
+		candidates = append(candidates, strings.ToLower(user.Email[:at]))
+	}
+
+	for _, candidate := range candidates {
+		// Very short names would reject too many passwords
+		if len(candidate) >= 3 && strings.Contains(password, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPasswordDenylist reads a file of common passwords, one per line. Blank lines and # comments are ignored.
+func loadPasswordDenylist(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password denylist: %w", err)
+	}
+	defer file.Close()
+
+	denylist := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password denylist: %w", err)
+	}
+
+	return denylist, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
+	"gorm.io/gorm"
+)
+
+// passwordResetCooldown is the minimum time between two reset emails to the same account
+const passwordResetCooldown = time.Minute
+
+// PasswordResetService handles the self-service forgot-password flow
+type PasswordResetService struct {
+	db           *gorm.DB
+	config       *config.Config
+	mailer       mailer.Mailer
+	tokenService *TokenService
+	auditService *AuditService
+	passwords    *PasswordPolicyService
+}
+
+func NewPasswordResetService(db *gorm.DB, config *config.Config, mailer mailer.Mailer, tokenService *TokenService, auditService *AuditService, passwords *PasswordPolicyService) *PasswordResetService {
+	return &PasswordResetService{
+		db:           db,
+		config:       config,
+		mailer:       mailer,
+		tokenService: tokenService,
+		auditService: auditService,
+		passwords:    passwords,
+	}
+}
+
+// RequestReset emails a reset link to the account with the given email. The lookup,
+// token and email all happen in the background, so neither the result nor the response
+// time reveals whether the account exists.
+func (s *PasswordResetService) RequestReset(email, ipAddress, userAgent string) error {
+	go func() {
+		if err := s.sendResetLink(email, ipAddress, userAgent); err != nil {
+			logger.Errorf("Failed to issue password reset link: %v", err)
+		}
+	}()
+	return nil
+}
+
+// sendResetLink issues a reset token for the account with the given email, if there is
+// one, and emails it
+func (s *PasswordResetService) sendResetLink(email, ipAddress, userAgent string) error {
+	var user models.Users
+	if err := s.db.Where("LOWER(email) = ? AND is_active = ?", strings.ToLower(email), true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Don't flood a mailbox with reset emails
+	var recent int64
+	s.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-passwordResetCooldown)).
+		Count(&recent)
+	if recent > 0 {
+		return nil
+	}
+
+	token, err := s.tokenService.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link works
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashResetToken(token),
+			ExpiresAt: time.Now().Add(s.config.PasswordResetTTL),
+			IPAddress: ipAddress,
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	if s.auditService != nil {
+		_ = s.auditService.LogWithContext(&user.ID, user.Username, "PASSWORD_RESET_REQUESTED", "auth", fmt.Sprintf("%d", user.ID), nil, nil, ipAddress, userAgent, "")
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to reset your password. Use the link below to choose a new one:\n\n%s\n\nThis link expires in %s and can only be used once. If you did not request a password reset, you can ignore this email.\n",
+			user.Name,
+			fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(s.config.AppBaseURL, "/"), url.QueryEscape(token)),
+			s.config.PasswordResetTTL,
+		),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return fmt.Errorf("failed to send password reset email to user %d: %w", user.ID, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere
+func (s *PasswordResetService) ResetPassword(req *models.ResetPasswordRequest, ipAddress, userAgent string) error {
+	if req.NewPassword != req.ConfirmPassword {
+		return errors.New("new password and confirm password do not match")
+	}
+
+	var user models.Users
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ?", hashResetToken(req.Token)).First(&resetToken).Error; err != nil {
+			return errors.New("invalid or expired reset token")
+		}
+
+		// Mark the token used; the condition makes concurrent redemptions of the same token fail
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", resetToken.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired reset token")
+		}
+
+		if err := tx.Where("id = ? AND is_active = ?", resetToken.UserID, true).First(&user).Error; err != nil {
+			return errors.New("invalid or expired reset token")
+		}
+
+		// Rolling back on a policy violation leaves the link usable for another attempt
+		if err := s.passwords.Validate(req.NewPassword, &user); err != nil {
+			return err
+		}
+
+		if err := s.passwords.SetPassword(tx, user.ID, req.NewPassword); err != nil {
+			return err
+		}
+
+		// Proving ownership of the mailbox also lifts any lockout
+		return tx.Model(&user).Updates(map[string]any{
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+			"lockout_count":         0,
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	// Sessions created with the old password are no longer trusted
+	if err := s.tokenService.RevokeAllUserTokens(user.ID); err != nil {
+		logger.Errorf("Failed to revoke tokens for user %d after password reset: %v", user.ID, err)
+	}
+
+	if s.auditService != nil {
+		_ = s.auditService.LogWithContext(&user.ID, user.Username, "PASSWORD_RESET_COMPLETED", "auth", fmt.Sprintf("%d", user.ID), nil, nil, ipAddress, userAgent, "")
+	}
+
+	return nil
+}
+
+// hashResetToken hashes a reset token for storage and lookup
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/Aebroyx/sass-api/internal/config"
@@ -18,7 +19,7 @@ type PermissionService struct {
 // UserPermissions holds the effective permissions for a user
 type UserPermissions struct {
 	UserID      uint
-	RoleID      uint
+	RoleIDs     []uint
 	Permissions map[string]models.EffectivePermissions // key: menu path
 }
 
@@ -33,11 +34,11 @@ func NewPermissionService(db *gorm.DB, cfg *config.Config, menuService *MenuServ
 
 // GetUserPermissions fetches all effective permissions for a user
 // This uses menuService.GetUserMenus() which handles:
-// 1. Role permissions (role_menus table)
-// 2. User direct menus (user_menus table)
-// 3. User overrides (rights_access table) where nil = inherit, true/false = explicit override
-func (s *PermissionService) GetUserPermissions(userID, roleID uint) (*UserPermissions, error) {
-	menus, err := s.menuService.GetUserMenus(userID, roleID)
+// 1. Role permissions (role_menus table), united across all of the user's roles
+// 2. User direct menus (user_menus table) inside their validity window
+// 3. User overrides (rights_access table) inside their validity window, where nil = inherit, true/false = explicit override
+func (s *PermissionService) GetUserPermissions(userID uint, roleIDs []uint) (*UserPermissions, error) {
+	menus, err := s.menuService.GetUserMenus(userID, roleIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +50,7 @@ func (s *PermissionService) GetUserPermissions(userID, roleID uint) (*UserPermis
 
 	return &UserPermissions{
 		UserID:      userID,
-		RoleID:      roleID,
+		RoleIDs:     roleIDs,
 		Permissions: permissions,
 	}, nil
 }
@@ -87,6 +88,60 @@ func (up *UserPermissions) CheckPermission(menuPath string, permType config.Perm
 	}
 }
 
+// RestrictToScopes returns the permissions the user holds that are also granted by the
+// given API key scopes, so a key can never do more than its owner currently can
+func (up *UserPermissions) RestrictToScopes(scopes []string) *UserPermissions {
+	restricted := &UserPermissions{
+		UserID:      up.UserID,
+		RoleIDs:     up.RoleIDs,
+		Permissions: make(map[string]models.EffectivePermissions),
+	}
+
+	for _, scope := range scopes {
+		permission, menuPath, ok := parseAPIKeyScope(scope)
+		if !ok || !up.CheckPermission(menuPath, permission) {
+			continue
+		}
+
+		perms := restricted.Permissions[menuPath]
+		switch permission {
+		case config.PermissionRead:
+			perms.CanRead = true
+		case config.PermissionWrite:
+			perms.CanWrite = true
+		case config.PermissionUpdate:
+			perms.CanUpdate = true
+		case config.PermissionDelete:
+			perms.CanDelete = true
+		}
+		restricted.Permissions[menuPath] = perms
+	}
+
+	return restricted
+}
+
+// Scopes lists the permissions as "permission:/menu-path" entries, the format used by
+// API key scopes and OAuth permission claims
+func (up *UserPermissions) Scopes() []string {
+	scopes := []string{}
+	for menuPath, perms := range up.Permissions {
+		if perms.CanRead {
+			scopes = append(scopes, string(config.PermissionRead)+":"+menuPath)
+		}
+		if perms.CanWrite {
+			scopes = append(scopes, string(config.PermissionWrite)+":"+menuPath)
+		}
+		if perms.CanUpdate {
+			scopes = append(scopes, string(config.PermissionUpdate)+":"+menuPath)
+		}
+		if perms.CanDelete {
+			scopes = append(scopes, string(config.PermissionDelete)+":"+menuPath)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
 // FindRoutePermission finds the permission requirement for a given method and path
 func FindRoutePermission(method, path string) (*config.RoutePermission, bool) {
 	// Try exact match first
@@ -146,3 +201,23 @@ func IsWhitelisted(method, path string) bool {
 
 	return false
 }
+
+// IsAPIKeyBlocked checks if a route can't be reached with an API key
+func IsAPIKeyBlocked(path string) bool {
+	for _, prefix := range config.APIKeyBlockedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImpersonationBlocked checks if a route can't be reached while impersonating a user
+func IsImpersonationBlocked(path string) bool {
+	for _, prefix := range config.ImpersonationBlockedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
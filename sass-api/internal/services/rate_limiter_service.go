@@ -13,6 +13,7 @@ type RateLimiterService struct {
 	Config       *config.Config
 	ipLimiters   map[string]*limiter
 	userLimiters map[uint]*limiter
+	keyLimiters  map[string]*limiter // Endpoint specific limits with their own window
 	mu           sync.RWMutex
 	cleanupTick  *time.Ticker
 	stopCleanup  chan bool
@@ -22,6 +23,7 @@ type RateLimiterService struct {
 type limiter struct {
 	count      int
 	windowStart time.Time
+	window     time.Duration // Only set for key limiters
 	mu         sync.Mutex
 }
 
@@ -31,6 +33,7 @@ func NewRateLimiterService(config *config.Config) *RateLimiterService {
 		Config:       config,
 		ipLimiters:   make(map[string]*limiter),
 		userLimiters: make(map[uint]*limiter),
+		keyLimiters:  make(map[string]*limiter),
 		cleanupTick:  time.NewTicker(10 * time.Minute),
 		stopCleanup:  make(chan bool),
 	}
@@ -67,6 +70,15 @@ func (s *RateLimiterService) cleanup() {
 				lim.mu.Unlock()
 			}
 
+			// Clean key limiters
+			for key, lim := range s.keyLimiters {
+				lim.mu.Lock()
+				if now.Sub(lim.windowStart) > lim.window*2 {
+					delete(s.keyLimiters, key)
+				}
+				lim.mu.Unlock()
+			}
+
 			s.mu.Unlock()
 		case <-s.stopCleanup:
 			return
@@ -202,6 +214,43 @@ func (s *RateLimiterService) RecordUserRequest(userID uint) {
 	s.recordRequest(lim)
 }
 
+// AllowKey checks and records a request against a named limit with its own
+// window, e.g. "forgot-password:<ip>". It returns the time until the window resets when denied.
+func (s *RateLimiterService) AllowKey(key string, maxRequests int, window time.Duration) (bool, time.Duration) {
+	if !s.Config.RateLimitEnabled {
+		return true, 0
+	}
+
+	s.mu.Lock()
+	lim, exists := s.keyLimiters[key]
+	if !exists {
+		lim = &limiter{
+			windowStart: time.Now(),
+			window:      window,
+		}
+		s.keyLimiters[key] = lim
+	}
+	s.mu.Unlock()
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	now := time.Now()
+
+	// Reset window if expired
+	if now.Sub(lim.windowStart) > lim.window {
+		lim.count = 0
+		lim.windowStart = now
+	}
+
+	if lim.count >= maxRequests {
+		return false, lim.windowStart.Add(lim.window).Sub(now)
+	}
+
+	lim.count++
+	return true, 0
+}
+
 // GetIPStats returns the current stats for an IP address
 func (s *RateLimiterService) GetIPStats(ip string) (count int, resetTime time.Time) {
 	s.mu.RLock()
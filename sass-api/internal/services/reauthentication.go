@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reauthenticate confirms the identity of a signed-in user with their password or an
+// MFA code and issues an access token stamped with a fresh auth_time for the session.
+// Failed attempts count towards the account lockout like failed logins.
+func (s *UserService) Reauthenticate(userID uint, sessionID string, req *models.ReauthenticateRequest) (*models.ReauthenticateResponse, error) {
+	if sessionID == "" {
+		return nil, errors.New("session not found")
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := s.checkAccountLocked(&user); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.Password != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			if err := s.recordFailedLogin(user.ID); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("invalid credentials")
+		}
+	case req.Code != "" && user.MFAEnabled && s.mfaService != nil:
+		if err := s.mfaService.VerifyCode(&user, req.Code); err != nil {
+			if err.Error() != "invalid mfa code" {
+				return nil, err
+			}
+			if err := s.recordFailedLogin(user.ID); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("invalid credentials")
+		}
+	default:
+		return nil, errors.New("password or mfa code required")
+	}
+	s.clearFailedLogins(&user)
+
+	authTime, err := s.tokenService.MarkSessionAuthenticated(user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, accessExp, err := s.tokenService.GenerateAccessToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReauthenticateResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(accessExp).Seconds()),
+		AuthTime:    authTime,
+	}, nil
+}
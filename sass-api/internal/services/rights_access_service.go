@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
@@ -20,8 +23,46 @@ func NewRightsAccessService(db *gorm.DB, config *config.Config) *RightsAccessSer
 	}
 }
 
+// WithContext returns a copy of the service bound to ctx. Overrides have no organization
+// of their own; inside an organization only its users' overrides can be reached.
+func (s *RightsAccessService) WithContext(ctx context.Context) *RightsAccessService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// checkUser makes sure the user exists and is visible to the caller
+func (s *RightsAccessService) checkUser(userID uint) error {
+	var user models.Users
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// checkValidity validates the optional window of a time-bound grant
+func checkValidity(validFrom, validUntil *time.Time) error {
+	if validUntil == nil {
+		return nil
+	}
+	if validFrom != nil && !validUntil.After(*validFrom) {
+		return errors.New("valid_until must be after valid_from")
+	}
+	if !validUntil.After(time.Now()) {
+		return errors.New("valid_until must be in the future")
+	}
+	return nil
+}
+
 // GetUserRightsAccess retrieves all permission overrides for a user
 func (s *RightsAccessService) GetUserRightsAccess(userID uint) ([]models.RightsAccessResponse, error) {
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+
 	var rightsAccess []models.RightsAccess
 	if err := s.db.Preload("Menu").Where("user_id = ?", userID).Find(&rightsAccess).Error; err != nil {
 		return nil, err
@@ -50,6 +91,9 @@ func (s *RightsAccessService) GetUserRightsAccess(userID uint) ([]models.RightsA
 			},
 			CreatedAt: ra.CreatedAt,
 			UpdatedAt: ra.UpdatedAt,
+
+			ValidFrom:  ra.ValidFrom,
+			ValidUntil: ra.ValidUntil,
 		}
 	}
 
@@ -58,6 +102,10 @@ func (s *RightsAccessService) GetUserRightsAccess(userID uint) ([]models.RightsA
 
 // GetUserMenuRightsAccess retrieves permission override for a specific user-menu combination
 func (s *RightsAccessService) GetUserMenuRightsAccess(userID uint, menuID uint) (*models.RightsAccessResponse, error) {
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+
 	var ra models.RightsAccess
 	if err := s.db.Preload("Menu").Where("user_id = ? AND menu_id = ?", userID, menuID).First(&ra).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -87,11 +135,18 @@ func (s *RightsAccessService) GetUserMenuRightsAccess(userID uint, menuID uint)
 		},
 		CreatedAt: ra.CreatedAt,
 		UpdatedAt: ra.UpdatedAt,
+
+		ValidFrom:  ra.ValidFrom,
+		ValidUntil: ra.ValidUntil,
 	}, nil
 }
 
 // CreateOrUpdateRightsAccess creates or updates a permission override
 func (s *RightsAccessService) CreateOrUpdateRightsAccess(req *models.CreateRightsAccessRequest) (*models.RightsAccessResponse, error) {
+	if err := checkValidity(req.ValidFrom, req.ValidUntil); err != nil {
+		return nil, err
+	}
+
 	// Verify user exists
 	var user models.Users
 	if err := s.db.First(&user, req.UserID).Error; err != nil {
@@ -117,6 +172,9 @@ func (s *RightsAccessService) CreateOrUpdateRightsAccess(req *models.CreateRight
 			CanWrite:  req.CanWrite,
 			CanUpdate: req.CanUpdate,
 			CanDelete: req.CanDelete,
+
+			ValidFrom:  req.ValidFrom,
+			ValidUntil: req.ValidUntil,
 		}
 		if err := s.db.Create(&ra).Error; err != nil {
 			return nil, err
@@ -146,6 +204,9 @@ func (s *RightsAccessService) CreateOrUpdateRightsAccess(req *models.CreateRight
 			},
 			CreatedAt: ra.CreatedAt,
 			UpdatedAt: ra.UpdatedAt,
+
+			ValidFrom:  ra.ValidFrom,
+			ValidUntil: ra.ValidUntil,
 		}, nil
 	}
 
@@ -154,6 +215,8 @@ func (s *RightsAccessService) CreateOrUpdateRightsAccess(req *models.CreateRight
 	existing.CanWrite = req.CanWrite
 	existing.CanUpdate = req.CanUpdate
 	existing.CanDelete = req.CanDelete
+	existing.ValidFrom = req.ValidFrom
+	existing.ValidUntil = req.ValidUntil
 
 	if err := s.db.Save(&existing).Error; err != nil {
 		return nil, err
@@ -183,11 +246,22 @@ func (s *RightsAccessService) CreateOrUpdateRightsAccess(req *models.CreateRight
 		},
 		CreatedAt: existing.CreatedAt,
 		UpdatedAt: existing.UpdatedAt,
+
+		ValidFrom:  existing.ValidFrom,
+		ValidUntil: existing.ValidUntil,
 	}, nil
 }
 
 // DeleteRightsAccess deletes a permission override by ID
 func (s *RightsAccessService) DeleteRightsAccess(id uint) error {
+	var ra models.RightsAccess
+	if err := s.db.First(&ra, id).Error; err != nil {
+		return errors.New("rights access not found")
+	}
+	if err := s.checkUser(ra.UserID); err != nil {
+		return errors.New("rights access not found")
+	}
+
 	result := s.db.Delete(&models.RightsAccess{}, id)
 	if result.RowsAffected == 0 {
 		return errors.New("rights access not found")
@@ -207,6 +281,9 @@ func (s *RightsAccessService) BulkSaveUserRightsAccess(userID uint, req *models.
 	// This prevents duplicate key violations if frontend sends duplicates
 	uniquePerms := make(map[uint]models.UserMenuPermission)
 	for _, perm := range req.Permissions {
+		if err := checkValidity(perm.ValidFrom, perm.ValidUntil); err != nil {
+			return nil, err
+		}
 		uniquePerms[perm.MenuID] = perm
 	}
 
@@ -250,6 +327,9 @@ func (s *RightsAccessService) BulkSaveUserRightsAccess(userID uint, req *models.
 			CanWrite:  perm.CanWrite,
 			CanUpdate: perm.CanUpdate,
 			CanDelete: perm.CanDelete,
+
+			ValidFrom:  perm.ValidFrom,
+			ValidUntil: perm.ValidUntil,
 		}
 		if err := tx.Create(&ra).Error; err != nil {
 			tx.Rollback()
@@ -267,5 +347,76 @@ func (s *RightsAccessService) BulkSaveUserRightsAccess(userID uint, req *models.
 
 // DeleteAllUserRightsAccess deletes all permission overrides for a user
 func (s *RightsAccessService) DeleteAllUserRightsAccess(userID uint) error {
+	if err := s.checkUser(userID); err != nil {
+		return err
+	}
+
 	return s.db.Where("user_id = ?", userID).Delete(&models.RightsAccess{}).Error
 }
+
+// GetExpiringGrants lists the permission overrides and direct menu assignments that
+// expire within the given duration, soonest first. A duration of 0 uses
+// GRANT_EXPIRING_WINDOW.
+func (s *RightsAccessService) GetExpiringGrants(within time.Duration) ([]models.ExpiringGrantResponse, error) {
+	if within <= 0 {
+		within = s.config.GrantExpiringWindow
+	}
+
+	now := time.Now()
+	window := func(db *gorm.DB) *gorm.DB {
+		return db.Where("valid_until > ? AND valid_until <= ?", now, now.Add(within))
+	}
+
+	// Users are loaded through the organization scope, so grants of users outside
+	// the caller's organization come back without one and are skipped
+	var rightsAccess []models.RightsAccess
+	if err := s.db.Scopes(window).Preload("User").Preload("Menu").Find(&rightsAccess).Error; err != nil {
+		return nil, err
+	}
+
+	var userMenus []models.UserMenu
+	if err := s.db.Scopes(window).Preload("User").Preload("Menu").Find(&userMenus).Error; err != nil {
+		return nil, err
+	}
+
+	grants := make([]models.ExpiringGrantResponse, 0, len(rightsAccess)+len(userMenus))
+	for _, ra := range rightsAccess {
+		if ra.User.ID == 0 {
+			continue
+		}
+		grants = append(grants, newExpiringGrant("rights_access", ra.ID, ra.User, ra.Menu, *ra.ValidUntil))
+	}
+	for _, um := range userMenus {
+		if um.User.ID == 0 {
+			continue
+		}
+		grants = append(grants, newExpiringGrant("user_menu", um.ID, um.User, um.Menu, *um.ValidUntil))
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].ValidUntil.Before(grants[j].ValidUntil)
+	})
+
+	return grants, nil
+}
+
+func newExpiringGrant(grantType string, id uint, user models.Users, menu models.Menu, validUntil time.Time) models.ExpiringGrantResponse {
+	return models.ExpiringGrantResponse{
+		Type:     grantType,
+		ID:       id,
+		UserID:   user.ID,
+		Username: user.Username,
+		Menu: models.MenuResponse{
+			ID:         menu.ID,
+			Name:       menu.Name,
+			Path:       menu.Path,
+			Icon:       menu.Icon,
+			OrderIndex: menu.OrderIndex,
+			ParentID:   menu.ParentID,
+			IsActive:   menu.IsActive,
+			CreatedAt:  menu.CreatedAt,
+			UpdatedAt:  menu.UpdatedAt,
+		},
+		ValidUntil: validUntil,
+	}
+}
@@ -1,13 +1,17 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
 	"github.com/Aebroyx/sass-api/internal/pagination"
+	"github.com/Aebroyx/sass-api/internal/tenant"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +27,14 @@ func NewRoleService(db *gorm.DB, config *config.Config) *RoleService {
 	}
 }
 
+// WithContext returns a copy of the service whose queries run in ctx, which scopes
+// them to the organization of the request
+func (s *RoleService) WithContext(ctx context.Context) *RoleService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 // GetAllRoles retrieves all roles with pagination
 func (s *RoleService) GetAllRoles(params pagination.QueryParams) (*pagination.PaginatedResponse, error) {
 	config := pagination.PaginationConfig{
@@ -88,6 +100,15 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.RoleRes
 		return nil, errors.New("role name already exists")
 	}
 
+	// New roles belong to the organization of the request
+	var organizationID *uint
+	if id, scoped := tenant.FromDB(s.db); scoped {
+		organizationID = &id
+	}
+	if err := checkParentRole(s.db, 0, req.ParentID, organizationID); err != nil {
+		return nil, err
+	}
+
 	// If this role is set as default, unset other defaults
 	if req.IsDefault {
 		s.db.Model(&models.Role{}).Where("is_default = ?", true).Update("is_default", false)
@@ -99,6 +120,13 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.RoleRes
 		Description: req.Description,
 		IsDefault:   req.IsDefault,
 		IsActive:    true,
+		RequireMFA:  req.RequireMFA,
+
+		SessionIdleTimeoutMinutes: req.SessionIdleTimeoutMinutes,
+		SessionMaxLifetimeMinutes: req.SessionMaxLifetimeMinutes,
+		MaxSessions:               req.MaxSessions,
+		MagicLinkDisabled:         req.MagicLinkDisabled,
+		ParentID:                  req.ParentID,
 	}
 
 	if err := s.db.Create(&role).Error; err != nil {
@@ -112,8 +140,16 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.RoleRes
 		Description: role.Description,
 		IsDefault:   role.IsDefault,
 		IsActive:    role.IsActive,
+		RequireMFA:  role.RequireMFA,
 		CreatedAt:   role.CreatedAt,
 		UpdatedAt:   role.UpdatedAt,
+
+		SessionIdleTimeoutMinutes: role.SessionIdleTimeoutMinutes,
+		SessionMaxLifetimeMinutes: role.SessionMaxLifetimeMinutes,
+		MaxSessions:               role.MaxSessions,
+		MagicLinkDisabled:         role.MagicLinkDisabled,
+		OrganizationID:            role.OrganizationID,
+		ParentID:                  role.ParentID,
 	}, nil
 }
 
@@ -126,6 +162,9 @@ func (s *RoleService) UpdateRole(id uint, req *models.UpdateRoleRequest) (*model
 		}
 		return nil, err
 	}
+	if !tenant.CanModify(s.db, role.OrganizationID) {
+		return nil, errors.New("cannot modify a shared role")
+	}
 
 	// Check if new name conflicts with existing role
 	if req.Name != role.Name {
@@ -135,6 +174,10 @@ func (s *RoleService) UpdateRole(id uint, req *models.UpdateRoleRequest) (*model
 		}
 	}
 
+	if err := checkParentRole(s.db, role.ID, req.ParentID, role.OrganizationID); err != nil {
+		return nil, err
+	}
+
 	// If this role is set as default, unset other defaults
 	if req.IsDefault && !role.IsDefault {
 		s.db.Model(&models.Role{}).Where("is_default = ? AND id != ?", true, id).Update("is_default", false)
@@ -146,6 +189,12 @@ func (s *RoleService) UpdateRole(id uint, req *models.UpdateRoleRequest) (*model
 	role.Description = req.Description
 	role.IsDefault = req.IsDefault
 	role.IsActive = req.IsActive
+	role.RequireMFA = req.RequireMFA
+	role.SessionIdleTimeoutMinutes = req.SessionIdleTimeoutMinutes
+	role.SessionMaxLifetimeMinutes = req.SessionMaxLifetimeMinutes
+	role.MaxSessions = req.MaxSessions
+	role.MagicLinkDisabled = req.MagicLinkDisabled
+	role.ParentID = req.ParentID
 
 	if err := s.db.Save(&role).Error; err != nil {
 		return nil, err
@@ -158,8 +207,16 @@ func (s *RoleService) UpdateRole(id uint, req *models.UpdateRoleRequest) (*model
 		Description: role.Description,
 		IsDefault:   role.IsDefault,
 		IsActive:    role.IsActive,
+		RequireMFA:  role.RequireMFA,
 		CreatedAt:   role.CreatedAt,
 		UpdatedAt:   role.UpdatedAt,
+
+		SessionIdleTimeoutMinutes: role.SessionIdleTimeoutMinutes,
+		SessionMaxLifetimeMinutes: role.SessionMaxLifetimeMinutes,
+		MaxSessions:               role.MaxSessions,
+		MagicLinkDisabled:         role.MagicLinkDisabled,
+		OrganizationID:            role.OrganizationID,
+		ParentID:                  role.ParentID,
 	}, nil
 }
 
@@ -169,6 +226,9 @@ func (s *RoleService) DeleteRole(id uint) error {
 	if err := s.db.First(&role, id).Error; err != nil {
 		return errors.New("role not found")
 	}
+	if !tenant.CanModify(s.db, role.OrganizationID) {
+		return errors.New("cannot modify a shared role")
+	}
 
 	// Prevent deletion of protected roles
 	protectedRoles := []string{"root", "admin", "user"}
@@ -181,13 +241,20 @@ func (s *RoleService) DeleteRole(id uint) error {
 		}
 	}
 
-	// Prevent deletion of roles that have users assigned
+	// Prevent deletion of roles that have users assigned, as primary or additional role
 	var userCount int64
-	s.db.Model(&models.Users{}).Where("role_id = ?", id).Count(&userCount)
+	s.db.Model(&models.Users{}).Where("role_id = ? OR id IN (SELECT user_id FROM user_roles WHERE role_id = ?)", id, id).Count(&userCount)
 	if userCount > 0 {
 		return fmt.Errorf("cannot delete role: %d users are assigned to this role", userCount)
 	}
 
+	// Prevent deletion of roles others inherit from
+	var childCount int64
+	s.db.Model(&models.Role{}).Where("parent_id = ?", id).Count(&childCount)
+	if childCount > 0 {
+		return fmt.Errorf("cannot delete role: %d roles inherit from it", childCount)
+	}
+
 	// Prevent deletion of default role
 	if role.IsDefault {
 		return errors.New("cannot delete the default role")
@@ -212,8 +279,16 @@ func (s *RoleService) GetActiveRoles() ([]models.RoleResponse, error) {
 			Description: role.Description,
 			IsDefault:   role.IsDefault,
 			IsActive:    role.IsActive,
+			RequireMFA:  role.RequireMFA,
 			CreatedAt:   role.CreatedAt,
 			UpdatedAt:   role.UpdatedAt,
+
+			SessionIdleTimeoutMinutes: role.SessionIdleTimeoutMinutes,
+			SessionMaxLifetimeMinutes: role.SessionMaxLifetimeMinutes,
+			MaxSessions:               role.MaxSessions,
+			MagicLinkDisabled:         role.MagicLinkDisabled,
+			OrganizationID:            role.OrganizationID,
+			ParentID:                  role.ParentID,
 		}
 	}
 
@@ -222,6 +297,10 @@ func (s *RoleService) GetActiveRoles() ([]models.RoleResponse, error) {
 
 // GetRoleMenus retrieves all menus assigned to a role with permissions
 func (s *RoleService) GetRoleMenus(roleID uint) ([]models.RoleMenuResponse, error) {
+	if _, err := s.GetRoleByID(roleID); err != nil {
+		return nil, err
+	}
+
 	var roleMenus []models.RoleMenu
 	if err := s.db.Preload("Menu").Where("role_id = ?", roleID).Find(&roleMenus).Error; err != nil {
 		return nil, err
@@ -263,6 +342,9 @@ func (s *RoleService) AssignMenusToRole(roleID uint, req *models.BulkAssignMenus
 	if err := s.db.First(&role, roleID).Error; err != nil {
 		return nil, errors.New("role not found")
 	}
+	if !tenant.CanModify(s.db, role.OrganizationID) {
+		return nil, errors.New("cannot modify a shared role")
+	}
 
 	var createdMenus []models.RoleMenu
 
@@ -343,9 +425,222 @@ func (s *RoleService) AssignMenusToRole(roleID uint, req *models.BulkAssignMenus
 
 // RemoveMenuFromRole removes a menu assignment from a role
 func (s *RoleService) RemoveMenuFromRole(roleID uint, menuID uint) error {
+	role, err := s.GetRoleByID(roleID)
+	if err != nil {
+		return err
+	}
+	if !tenant.CanModify(s.db, role.OrganizationID) {
+		return errors.New("cannot modify a shared role")
+	}
+
 	result := s.db.Where("role_id = ? AND menu_id = ?", roleID, menuID).Delete(&models.RoleMenu{})
 	if result.RowsAffected == 0 {
 		return errors.New("menu assignment not found")
 	}
 	return result.Error
 }
+
+// GetEffectivePermissions lists what a role can do on each menu once the permissions it
+// inherits are included, and which role in the hierarchy grants each permission
+func (s *RoleService) GetEffectivePermissions(roleID uint) ([]models.RoleEffectivePermissionResponse, error) {
+	if _, err := s.GetRoleByID(roleID); err != nil {
+		return nil, err
+	}
+
+	ancestry, err := roleAncestry(s.db, roleID, true)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]uint, len(ancestry))
+	for i, role := range ancestry {
+		roleIDs[i] = role.ID
+	}
+
+	var roleMenus []models.RoleMenu
+	if err := s.db.Preload("Menu").Where("role_id IN ?", roleIDs).Find(&roleMenus).Error; err != nil {
+		return nil, err
+	}
+	menusByRole := make(map[uint][]models.RoleMenu)
+	for _, rm := range roleMenus {
+		menusByRole[rm.RoleID] = append(menusByRole[rm.RoleID], rm)
+	}
+
+	// Walk from the role up to its root, so the nearest role granting a permission is its source
+	byMenu := make(map[uint]*models.RoleEffectivePermissionResponse)
+	for depth, role := range ancestry {
+		for _, rm := range menusByRole[role.ID] {
+			if !rm.Menu.IsActive {
+				continue
+			}
+
+			entry, ok := byMenu[rm.MenuID]
+			if !ok {
+				entry = &models.RoleEffectivePermissionResponse{
+					Menu: models.MenuResponse{
+						ID:         rm.Menu.ID,
+						Name:       rm.Menu.Name,
+						Path:       rm.Menu.Path,
+						Icon:       rm.Menu.Icon,
+						OrderIndex: rm.Menu.OrderIndex,
+						ParentID:   rm.Menu.ParentID,
+						IsActive:   rm.Menu.IsActive,
+						CreatedAt:  rm.Menu.CreatedAt,
+						UpdatedAt:  rm.Menu.UpdatedAt,
+					},
+					Grants: []models.PermissionGrant{},
+				}
+				byMenu[rm.MenuID] = entry
+			}
+
+			grant := func(permission config.PermissionType, granted bool, held *bool) {
+				if !granted || *held {
+					return
+				}
+				*held = true
+				entry.Grants = append(entry.Grants, models.PermissionGrant{
+					Permission: string(permission),
+					RoleID:     role.ID,
+					RoleName:   role.Name,
+					Inherited:  depth > 0,
+				})
+			}
+			grant(config.PermissionRead, rm.CanRead, &entry.Permissions.CanRead)
+			grant(config.PermissionWrite, rm.CanWrite, &entry.Permissions.CanWrite)
+			grant(config.PermissionUpdate, rm.CanUpdate, &entry.Permissions.CanUpdate)
+			grant(config.PermissionDelete, rm.CanDelete, &entry.Permissions.CanDelete)
+		}
+	}
+
+	response := make([]models.RoleEffectivePermissionResponse, 0, len(byMenu))
+	for _, entry := range byMenu {
+		if len(entry.Grants) > 0 {
+			response = append(response, *entry)
+		}
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].Menu.OrderIndex != response[j].Menu.OrderIndex {
+			return response[i].Menu.OrderIndex < response[j].Menu.OrderIndex
+		}
+		return response[i].Menu.ID < response[j].Menu.ID
+	})
+
+	return response, nil
+}
+
+// checkParentRole makes sure a role in the given organization can inherit from parentID.
+// The parent must be a shared role or one of the organization's own, can't be a
+// cross-tenant role, and must not already inherit from the role, which would make the
+// hierarchy loop. roleID is 0 for a role that doesn't exist yet.
+func checkParentRole(db *gorm.DB, roleID uint, parentID *uint, organizationID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == roleID {
+		return errors.New("role hierarchy cannot contain a cycle")
+	}
+
+	var parent models.Role
+	if err := db.First(&parent, *parentID).Error; err != nil {
+		return errors.New("parent role not found")
+	}
+	if parent.OrganizationID != nil && (organizationID == nil || *parent.OrganizationID != *organizationID) {
+		return errors.New("parent role not found")
+	}
+	if parent.OrganizationID == nil && slices.Contains(config.CrossTenantRoles, parent.Name) {
+		return errors.New("invalid parent role")
+	}
+
+	if roleID == 0 {
+		return nil
+	}
+	// Inactive roles still count, so reactivating one can't close a loop
+	ancestry, err := roleAncestry(db, parent.ID, false)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestry {
+		if ancestor.ID == roleID {
+			return errors.New("role hierarchy cannot contain a cycle")
+		}
+	}
+	return nil
+}
+
+// roleHierarchyMaxDepth bounds the ancestry query should the hierarchy ever loop
+const roleHierarchyMaxDepth = 32
+
+// ancestorRole is a row of the ancestry query: a role inherited by rootID, depth levels up
+type ancestorRole struct {
+	models.Role
+	RootID uint
+	Depth  int
+}
+
+// roleAncestries returns each of the given roles followed by the roles it inherits from,
+// nearest first, with one recursive query. With activeOnly, inheritance stops at an
+// inactive ancestor, which passes on neither its own permissions nor those it inherits.
+// The query is raw SQL and so not tenant scoped; callers pass roles they may see.
+func roleAncestries(db *gorm.DB, roleIDs []uint, activeOnly bool) (map[uint][]models.Role, error) {
+	ancestries := make(map[uint][]models.Role, len(roleIDs))
+	if len(roleIDs) == 0 {
+		return ancestries, nil
+	}
+
+	activeCondition := ""
+	if activeOnly {
+		activeCondition = "AND roles.is_active = true"
+	}
+
+	var rows []ancestorRole
+	if err := db.Raw(`
+		WITH RECURSIVE ancestry (root_id, role_id, parent_id, depth) AS (
+			SELECT id, id, parent_id, 0 FROM roles
+			WHERE id IN ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT ancestry.root_id, roles.id, roles.parent_id, ancestry.depth + 1
+			FROM ancestry JOIN roles ON roles.id = ancestry.parent_id
+			WHERE roles.deleted_at IS NULL AND ancestry.depth < ? `+activeCondition+`
+		)
+		SELECT roles.*, ancestry.root_id, ancestry.depth
+		FROM ancestry JOIN roles ON roles.id = ancestry.role_id
+		ORDER BY ancestry.root_id, ancestry.depth`, roleIDs, roleHierarchyMaxDepth).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		ancestry := ancestries[row.RootID]
+		if slices.ContainsFunc(ancestry, func(role models.Role) bool { return role.ID == row.ID }) {
+			continue
+		}
+		ancestries[row.RootID] = append(ancestry, row.Role)
+	}
+	return ancestries, nil
+}
+
+// roleAncestry returns a role followed by the roles it inherits from, nearest first
+func roleAncestry(db *gorm.DB, roleID uint, activeOnly bool) ([]models.Role, error) {
+	ancestries, err := roleAncestries(db, []uint{roleID}, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	return ancestries[roleID], nil
+}
+
+// inheritedRoleIDs returns the given roles together with every active role they inherit from
+func inheritedRoleIDs(db *gorm.DB, roleIDs []uint) ([]uint, error) {
+	ancestries, err := roleAncestries(db, roleIDs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []uint
+	for _, roleID := range roleIDs {
+		for _, role := range ancestries[roleID] {
+			if !slices.Contains(result, role.ID) {
+				result = append(result, role.ID)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// createRoleChain creates roles where each inherits from the one after it
+func createRoleChain(t *testing.T, db *gorm.DB, names ...string) []models.Role {
+	t.Helper()
+
+	roles := make([]models.Role, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		roles[i] = models.Role{Name: names[i], DisplayName: names[i], IsActive: true}
+		if i < len(names)-1 {
+			roles[i].ParentID = &roles[i+1].ID
+		}
+		if err := db.Create(&roles[i]).Error; err != nil {
+			t.Fatalf("create role: %v", err)
+		}
+	}
+	return roles
+}
+
+func roleIDsOf(roles []models.Role) []uint {
+	result := make([]uint, len(roles))
+	for i, role := range roles {
+		result[i] = role.ID
+	}
+	return result
+}
+
+func TestRoleAncestry(t *testing.T) {
+	db := newTestDB(t, &models.Role{})
+	chain := createRoleChain(t, db, "editor", "viewer", "guest")
+	other := createRoleChain(t, db, "auditor", "guest-reader")
+
+	ancestry, err := roleAncestry(db, chain[0].ID, true)
+	if err != nil {
+		t.Fatalf("role ancestry: %v", err)
+	}
+	if got, want := roleIDsOf(ancestry), roleIDsOf(chain); !slices.Equal(got, want) {
+		t.Errorf("ancestry = %v, want %v nearest first", got, want)
+	}
+
+	inherited, err := inheritedRoleIDs(db, []uint{chain[1].ID, other[0].ID})
+	if err != nil {
+		t.Fatalf("inherited role ids: %v", err)
+	}
+	if want := []uint{chain[1].ID, chain[2].ID, other[0].ID, other[1].ID}; !slices.Equal(inherited, want) {
+		t.Errorf("inherited = %v, want %v", inherited, want)
+	}
+
+	// An inactive ancestor passes on nothing, but still counts when checking for loops
+	db.Model(&chain[1]).Update("is_active", false)
+	if ancestry, _ := roleAncestry(db, chain[0].ID, true); !slices.Equal(roleIDsOf(ancestry), []uint{chain[0].ID}) {
+		t.Errorf("active ancestry = %v, want only the role itself", roleIDsOf(ancestry))
+	}
+	if err := checkParentRole(db, chain[2].ID, &chain[0].ID, nil); err == nil || err.Error() != "role hierarchy cannot contain a cycle" {
+		t.Errorf("error = %v, want role hierarchy cannot contain a cycle", err)
+	}
+
+	// Looping data ends the walk instead of repeating roles
+	db.Model(&chain[2]).Update("parent_id", chain[0].ID)
+	ancestry, err = roleAncestry(db, chain[0].ID, false)
+	if err != nil {
+		t.Fatalf("role ancestry: %v", err)
+	}
+	if got, want := roleIDsOf(ancestry), roleIDsOf(chain); !slices.Equal(got, want) {
+		t.Errorf("looping ancestry = %v, want %v", got, want)
+	}
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"slices"
 	"sync"
 
 	"github.com/Aebroyx/sass-api/internal/config"
@@ -22,22 +24,30 @@ func NewSearchService(db *gorm.DB, config *config.Config, permissionService *Per
 	}
 }
 
+// WithContext returns a copy of the service bound to ctx; results are limited to the
+// request's organization
+func (s *SearchService) WithContext(ctx context.Context) *SearchService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 // GlobalSearch searches across users, roles, and menus with permission filtering
-func (s *SearchService) GlobalSearch(query string, limit int, userID uint, roleID uint, roleName string) (*models.GlobalSearchResponse, error) {
+func (s *SearchService) GlobalSearch(query string, limit int, userID uint, roleIDs []uint, roleNames []string) (*models.GlobalSearchResponse, error) {
 	if limit <= 0 {
 		limit = 5 // Default limit per category
 	}
 
 	var canReadUsers, canReadRoles, canReadMenus bool
 
-	// Bypass permission checks for root and admin users
-	if roleName == "root" || roleName == "admin" {
+	// Bypass permission checks for users holding the root or admin role
+	if slices.Contains(roleNames, "root") || slices.Contains(roleNames, "admin") {
 		canReadUsers = true
 		canReadRoles = true
 		canReadMenus = true
 	} else {
 		// Get user permissions for non-admin users
-		userPermissions, err := s.permissionService.GetUserPermissions(userID, roleID)
+		userPermissions, err := s.permissionService.GetUserPermissions(userID, roleIDs)
 		if err != nil {
 			return nil, err
 		}
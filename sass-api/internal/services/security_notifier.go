@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/Aebroyx/sass-api/internal/mailer"
+)
+
+// SecurityNotifier delivers security alerts to the affected user
+type SecurityNotifier interface {
+	NotifyTokenReuse(user models.Users, ipAddress, userAgent string) error
+}
+
+// LogSecurityNotifier writes security alerts to the application log
+type LogSecurityNotifier struct{}
+
+// NotifyTokenReuse logs that a rotated refresh token was replayed for the user
+func (LogSecurityNotifier) NotifyTokenReuse(user models.Users, ipAddress, userAgent string) error {
+	logger.WarnWithContext(fmt.Sprintf("Refresh token reuse detected for user %s", user.Username), "", map[string]interface{}{
+		"user_id":    user.ID,
+		"email":      user.Email,
+		"ip_address": ipAddress,
+		"user_agent": userAgent,
+	})
+	return nil
+}
+
+// MailSecurityNotifier emails security alerts to the affected user
+type MailSecurityNotifier struct {
+	Mailer mailer.Mailer
+}
+
+// NotifyTokenReuse emails the user that their session was used from an unexpected device
+func (n MailSecurityNotifier) NotifyTokenReuse(user models.Users, ipAddress, userAgent string) error {
+	return n.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Security alert: you have been signed out",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nAn old session token of your account was used again, which can mean it was stolen. As a precaution we signed out the affected session.\n\nIP address: %s\nDevice: %s\n\nIf this wasn't you, please change your password.\n",
+			user.Name, ipAddress, userAgent,
+		),
+	})
+}
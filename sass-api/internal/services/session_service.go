@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// SessionService lists and revokes the signed-in sessions of a user. A session is a
+// refresh token family, so revoking one revokes every token rotated from that login.
+type SessionService struct {
+	db           *gorm.DB
+	tokenService *TokenService
+}
+
+func NewSessionService(db *gorm.DB, tokenService *TokenService) *SessionService {
+	return &SessionService{
+		db:           db,
+		tokenService: tokenService,
+	}
+}
+
+// WithContext returns a copy of the service bound to ctx, so administrators only reach
+// the sessions of users in their organization
+func (s *SessionService) WithContext(ctx context.Context) *SessionService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+// The session matching currentSessionID is flagged as the current one.
+func (s *SessionService) ListSessions(userID uint, currentSessionID string) ([]models.SessionResponse, error) {
+	if err := s.db.First(&models.Users{}, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// A session lasts as long as the live refresh token of its family
+	var tokens []models.RefreshToken
+	if err := s.db.Select("family_id", "expires_at").
+		Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	expiresAt := make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		if token.ExpiresAt.After(expiresAt[token.FamilyID]) {
+			expiresAt[token.FamilyID] = token.ExpiresAt
+		}
+	}
+
+	responses := []models.SessionResponse{}
+	if len(expiresAt) == 0 {
+		return responses, nil
+	}
+
+	familyIDs := make([]string, 0, len(expiresAt))
+	for familyID := range expiresAt {
+		familyIDs = append(familyIDs, familyID)
+	}
+
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND id IN ?", userID, familyIDs).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		device, browser, os := parseUserAgent(session.UserAgent)
+		responses = append(responses, models.SessionResponse{
+			ID:         session.ID,
+			Device:     device,
+			Browser:    browser,
+			OS:         os,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  expiresAt[session.ID],
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return responses, nil
+}
+
+// RevokeSession signs the user out of one session
+func (s *SessionService) RevokeSession(userID uint, sessionID string) error {
+	if err := s.db.First(&models.Users{}, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	var session models.Session
+	if err := s.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return errors.New("session not found")
+	}
+
+	revoked, err := s.tokenService.RevokeTokenFamily(session.ID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session.
+// It returns the number of sessions that were revoked.
+func (s *SessionService) RevokeOtherSessions(userID uint, currentSessionID string) (int64, error) {
+	if currentSessionID == "" {
+		return 0, errors.New("current session unknown")
+	}
+
+	var revoked int64
+	defer s.tokenService.forgetSessions(userID, "")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND family_id <> ? AND is_revoked = ?", userID, currentSessionID, false).
+			Distinct("family_id").
+			Count(&revoked).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND family_id <> ? AND is_revoked = ?", userID, currentSessionID, false).
+			Updates(map[string]interface{}{
+				"is_revoked": true,
+				"revoked_at": time.Now(),
+			}).Error
+	})
+
+	return revoked, err
+}
+
+// RevokeAllSessions signs the user out of every session
+func (s *SessionService) RevokeAllSessions(userID uint) error {
+	if err := s.db.First(&models.Users{}, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.tokenService.RevokeAllUserTokens(userID)
+}
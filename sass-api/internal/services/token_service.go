@@ -2,27 +2,198 @@ package services
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/Aebroyx/sass-api/internal/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// refreshTokenPrefixLength is how many leading characters of a refresh token are stored
+// in plaintext to find its row. The rest is only ever compared against the stored hash.
+const refreshTokenPrefixLength = 12
+
+// liveSessionTTL is how long a session found live is trusted without asking the database
+// again. Revocations made through this instance take effect at once; those made by
+// another instance within this window.
+const liveSessionTTL = 30 * time.Second
+
 type TokenService struct {
-	db     *gorm.DB
-	config *config.Config
+	db           *gorm.DB
+	config       *config.Config
+	auditService *AuditService
+	notifier     SecurityNotifier
+	keyRing      *KeyRing
+
+	// liveSessions caches the sessions access tokens were recently checked against.
+	// sessionGeneration is bumped on every revocation so a lookup racing one isn't cached.
+	sessionMu         sync.Mutex
+	liveSessions      map[string]liveSession
+	sessionGeneration uint64
 }
 
-func NewTokenService(db *gorm.DB, config *config.Config) *TokenService {
+// liveSession is a session whose refresh token family was live when last checked
+type liveSession struct {
+	userID    uint
+	expiresAt time.Time
+	checkedAt time.Time
+}
+
+func NewTokenService(db *gorm.DB, config *config.Config, auditService *AuditService, keyRing *KeyRing) *TokenService {
 	return &TokenService{
-		db:     db,
-		config: config,
+		db:           db,
+		config:       config,
+		auditService: auditService,
+		keyRing:      keyRing,
+		liveSessions: make(map[string]liveSession),
 	}
 }
 
+// SignClaims signs a JWT with the active key. Every token the API issues is signed here.
+func (s *TokenService) SignClaims(claims jwt.Claims) (string, error) {
+	return s.keyRing.Sign(claims)
+}
+
+// ParseClaims verifies a JWT against the key ring and decodes it into claims
+func (s *TokenService) ParseClaims(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	options = append([]jwt.ParserOption{jwt.WithValidMethods(s.keyRing.ValidMethods())}, options...)
+	return jwt.ParseWithClaims(tokenString, claims, s.keyRing.Keyfunc, options...)
+}
+
+// ParseAccessToken parses and validates a JWT access token
+func (s *TokenService) ParseAccessToken(tokenString string) (*models.Claims, *jwt.Token, error) {
+	claims := &models.Claims{}
+	token, err := s.ParseClaims(tokenString, claims)
+	// Access tokens carry no audience; anything else (e.g. an MFA challenge) is not a session
+	if err == nil && len(claims.Audience) > 0 {
+		return claims, token, jwt.ErrTokenInvalidAudience
+	}
+	return claims, token, err
+}
+
+// JWKS returns the public signing keys for token verification by other services
+func (s *TokenService) JWKS() JWKSet {
+	return s.keyRing.JWKS()
+}
+
+// SigningAlg returns the algorithm new tokens are signed with
+func (s *TokenService) SigningAlg() string {
+	return s.keyRing.SigningAlg()
+}
+
+// SetSecurityNotifier sets the notifier used to alert users about suspicious token activity
+func (s *TokenService) SetSecurityNotifier(notifier SecurityNotifier) {
+	s.notifier = notifier
+}
+
+// GenerateAccessToken generates a signed JWT access token for the user's session,
+// carrying the time the user last authenticated in it. The token never outlives the
+// session, so an idle timeout or absolute lifetime shorter than JWT_EXPIRY still applies.
+func (s *TokenService) GenerateAccessToken(user models.Users, sessionID string) (string, time.Time, error) {
+	sessionToken, err := s.liveSessionToken(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", time.Time{}, errors.New("session not found")
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var authTime *jwt.NumericDate
+	var session models.Session
+	if err := s.db.Where("id = ?", sessionID).First(&session).Error; err == nil && session.AuthenticatedAt != nil {
+		authTime = jwt.NewNumericDate(*session.AuthenticatedAt)
+	}
+
+	if err := loadUserRoles(s.db, &user); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expirationTime := time.Now().Add(s.config.JWTExpiry)
+	if sessionToken.ExpiresAt.Before(expirationTime) {
+		expirationTime = sessionToken.ExpiresAt
+	}
+	claims := &models.Claims{
+		UserID:         user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		RoleID:         user.RoleID,
+		RoleName:       user.Role.Name,
+		RoleIDs:        user.RoleIDs(),
+		RoleNames:      user.RoleNames(),
+		SessionID:      sessionID,
+		AuthTime:       authTime,
+		OrganizationID: organizationClaim(user),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "sass-api",
+			Subject:   user.Username,
+		},
+	}
+
+	tokenString, err := s.SignClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// GenerateImpersonationToken generates a short-lived access token that acts as user on
+// behalf of impersonator. No refresh token is issued, so the session ends when it expires.
+func (s *TokenService) GenerateImpersonationToken(user, impersonator models.Users) (string, time.Time, error) {
+	if err := loadUserRoles(s.db, &user); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expirationTime := time.Now().Add(s.config.ImpersonationTTL)
+	claims := &models.Claims{
+		UserID:               user.ID,
+		Username:             user.Username,
+		Email:                user.Email,
+		RoleID:               user.RoleID,
+		RoleName:             user.Role.Name,
+		RoleIDs:              user.RoleIDs(),
+		RoleNames:            user.RoleNames(),
+		ImpersonatorID:       impersonator.ID,
+		ImpersonatorUsername: impersonator.Username,
+		OrganizationID:       organizationClaim(user),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "sass-api",
+			Subject:   user.Username,
+		},
+	}
+
+	tokenString, err := s.SignClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// organizationClaim returns the org_id claim for user, zero for users outside any organization
+func organizationClaim(user models.Users) uint {
+	if user.OrganizationID == nil {
+		return 0
+	}
+	return *user.OrganizationID
+}
+
 // GenerateSecureToken generates a cryptographically secure random token
 func (s *TokenService) GenerateSecureToken() (string, error) {
 	b := make([]byte, 32)
@@ -32,87 +203,412 @@ func (s *TokenService) GenerateSecureToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// CreateRefreshToken creates a new refresh token for a user
+// CreateRefreshToken creates a new refresh token for a user, starting a new token family
 func (s *TokenService) CreateRefreshToken(userID uint, ipAddress, userAgent string) (*models.RefreshToken, error) {
+	return s.createRefreshToken(s.db, userID, uuid.New().String(), time.Now(), ipAddress, userAgent)
+}
+
+// createRefreshToken creates a new refresh token within the given token family. The token
+// expires when the session would go idle, and never after the session's absolute lifetime.
+func (s *TokenService) createRefreshToken(db *gorm.DB, userID uint, familyID string, sessionStart time.Time, ipAddress, userAgent string) (*models.RefreshToken, error) {
+	expiresAt, err := s.sessionExpiry(db, userID, sessionStart)
+	if err != nil {
+		return nil, err
+	}
+
 	token, err := s.GenerateSecureToken()
 	if err != nil {
 		return nil, err
 	}
 
 	refreshToken := &models.RefreshToken{
-		Token:     token,
-		UserID:    userID,
-		ExpiresAt: time.Now().Add(s.config.RefreshTokenExpiry),
-		IsRevoked: false,
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
+		Token:       token,
+		TokenPrefix: token[:refreshTokenPrefixLength],
+		TokenHash:   hashRefreshToken(token),
+		UserID:      userID,
+		ExpiresAt:   expiresAt,
+		IsRevoked:   false,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		FamilyID:    familyID,
 	}
 
-	if err := s.db.Create(refreshToken).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(refreshToken).Error; err != nil {
+			return err
+		}
+		return touchSession(tx, familyID, userID, ipAddress, userAgent)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return refreshToken, nil
 }
 
-// ValidateRefreshToken validates a refresh token and returns it if valid
-func (s *TokenService) ValidateRefreshToken(token string) (*models.RefreshToken, error) {
-	var refreshToken models.RefreshToken
+// MarkSessionAuthenticated records that the user just proved their identity in the session
+func (s *TokenService) MarkSessionAuthenticated(userID uint, sessionID string) (time.Time, error) {
+	now := time.Now()
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Update("authenticated_at", now)
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return time.Time{}, errors.New("session not found")
+	}
+
+	return now, nil
+}
+
+// sessionExpiry returns when a refresh token issued now must expire, applying the shortest
+// idle timeout and absolute lifetime of the user's roles, or the global ones if none sets one
+func (s *TokenService) sessionExpiry(db *gorm.DB, userID uint, sessionStart time.Time) (time.Time, error) {
+	var user models.Users
+	if err := db.Preload("Role").Preload("UserRoles.Role").First(&user, userID).Error; err != nil {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	idleTimeout := s.config.SessionIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = s.config.RefreshTokenExpiry
+	}
+	if roleTimeout := user.SessionIdleTimeout(); roleTimeout > 0 {
+		idleTimeout = roleTimeout
+	}
+
+	maxLifetime := s.config.SessionMaxLifetime
+	if roleLifetime := user.SessionMaxLifetime(); roleLifetime > 0 {
+		maxLifetime = roleLifetime
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(idleTimeout)
+	if maxLifetime > 0 {
+		sessionEnd := sessionStart.Add(maxLifetime)
+		if !now.Before(sessionEnd) {
+			return time.Time{}, errors.New("session has expired")
+		}
+		if sessionEnd.Before(expiresAt) {
+			expiresAt = sessionEnd
+		}
+	}
+
+	return expiresAt, nil
+}
+
+// touchSession records the session a refresh token belongs to, creating it on login
+// and refreshing its device details and last-seen time on every rotation
+func touchSession(tx *gorm.DB, sessionID string, userID uint, ipAddress, userAgent string) error {
+	now := time.Now()
+	session := models.Session{
+		ID:              sessionID,
+		UserID:          userID,
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		LastSeenAt:      now,
+		AuthenticatedAt: &now, // Only kept for new sessions; rotation doesn't re-authenticate
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ip_address", "user_agent", "last_seen_at", "updated_at"}),
+	}).Create(&session).Error
+}
 
-	if err := s.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+// ValidateRefreshToken validates a refresh token and returns it if valid.
+// Presenting a token that was already rotated is treated as theft: every token
+// in its family is revoked and a TOKEN_REUSE_DETECTED audit event is recorded.
+func (s *TokenService) ValidateRefreshToken(token string, ipAddress, userAgent string) (*models.RefreshToken, error) {
+	refreshToken, err := findRefreshToken(s.db, token)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid refresh token")
 		}
 		return nil, err
 	}
 
-	// Check if token is revoked
+	if err := s.checkRefreshToken(s.db, refreshToken, ipAddress, userAgent); err != nil {
+		if err.Error() == "refresh token reuse detected" {
+			s.handleTokenReuse(refreshToken, ipAddress, userAgent)
+		}
+		return nil, err
+	}
+
+	// A token rotated within the grace period may be rotated again, but isn't valid itself
 	if refreshToken.IsRevoked {
 		return nil, errors.New("refresh token has been revoked")
 	}
 
-	// Check if token is expired
+	return refreshToken, nil
+}
+
+// checkRefreshToken checks whether a refresh token may be rotated. A token that was
+// already rotated may only be presented again within the reuse grace period, by the
+// client it was rotated for and while its session is live; anything else is reuse.
+func (s *TokenService) checkRefreshToken(db *gorm.DB, refreshToken *models.RefreshToken, ipAddress, userAgent string) error {
+	if refreshToken.IsRevoked {
+		if refreshToken.ReplacedBy == nil {
+			return errors.New("refresh token has been revoked")
+		}
+		if !s.withinReuseGrace(refreshToken) {
+			return errors.New("refresh token reuse detected")
+		}
+
+		var replacement models.RefreshToken
+		if err := db.Select("ip_address", "user_agent").First(&replacement, *refreshToken.ReplacedBy).Error; err != nil {
+			return err
+		}
+		if replacement.IPAddress != ipAddress || replacement.UserAgent != userAgent {
+			return errors.New("refresh token reuse detected")
+		}
+
+		// The session may have been signed out since the rotation
+		var live int64
+		if err := db.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND is_revoked = ? AND expires_at > ?", refreshToken.FamilyID, false, time.Now()).
+			Count(&live).Error; err != nil {
+			return err
+		}
+		if live == 0 {
+			return errors.New("refresh token has been revoked")
+		}
+	}
+
 	if time.Now().After(refreshToken.ExpiresAt) {
-		return nil, errors.New("refresh token has expired")
+		return errors.New("refresh token has expired")
+	}
+
+	return nil
+}
+
+// findRefreshToken looks a refresh token up by its prefix and verifies the rest of it
+// against the stored hash, so the database never holds a usable token
+func findRefreshToken(db *gorm.DB, token string) (*models.RefreshToken, error) {
+	if len(token) <= refreshTokenPrefixLength {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var refreshToken models.RefreshToken
+	if err := db.Where("token_prefix = ?", token[:refreshTokenPrefixLength]).First(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashRefreshToken(token)), []byte(refreshToken.TokenHash)) != 1 {
+		return nil, gorm.ErrRecordNotFound
 	}
 
 	return &refreshToken, nil
 }
 
-// RotateRefreshToken rotates a refresh token by revoking the old one and creating a new one
+// hashRefreshToken hashes a refresh token for storage
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RotateRefreshToken rotates a refresh token by revoking the old one and creating a new one.
+// The old token's row stays locked until the rotation commits, so concurrent requests
+// sharing one cookie take turns on every instance. Those that lose the race get a token
+// of their own in the same session, provided they come from the same client within the
+// reuse grace period.
 func (s *TokenService) RotateRefreshToken(oldToken string, ipAddress, userAgent string) (*models.RefreshToken, error) {
-	// Validate old token
-	oldRefreshToken, err := s.ValidateRefreshToken(oldToken)
+	var oldRefreshToken, newRefreshToken *models.RefreshToken
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		oldRefreshToken, err = findRefreshToken(tx.Clauses(clause.Locking{Strength: "UPDATE"}), oldToken)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid refresh token")
+			}
+			return err
+		}
+
+		if err := s.checkRefreshToken(tx, oldRefreshToken, ipAddress, userAgent); err != nil {
+			return err
+		}
+
+		// Create new refresh token in the same family. Its lifetime is measured from the
+		// original login, so rotating never extends a session past its absolute lifetime.
+		familyID := oldRefreshToken.FamilyID
+		if familyID == "" {
+			familyID = uuid.New().String()
+		}
+		sessionStart := oldRefreshToken.CreatedAt
+		var session models.Session
+		if err := tx.Where("id = ?", familyID).First(&session).Error; err == nil {
+			sessionStart = session.CreatedAt
+		}
+		newRefreshToken, err = s.createRefreshToken(tx, oldRefreshToken.UserID, familyID, sessionStart, ipAddress, userAgent)
+		if err != nil {
+			return err
+		}
+
+		// A token rotated within the grace period keeps pointing at its first replacement
+		if oldRefreshToken.IsRevoked {
+			return nil
+		}
+
+		return tx.Model(&models.RefreshToken{}).
+			Where("id = ?", oldRefreshToken.ID).
+			Updates(map[string]interface{}{
+				"is_revoked":  true,
+				"revoked_at":  time.Now(),
+				"replaced_by": newRefreshToken.ID,
+			}).Error
+	})
 	if err != nil {
+		switch err.Error() {
+		case "refresh token reuse detected":
+			s.handleTokenReuse(oldRefreshToken, ipAddress, userAgent)
+		case "session has expired":
+			// Nothing else in the family may be rotated either
+			if _, revokeErr := s.RevokeTokenFamily(oldRefreshToken.FamilyID); revokeErr != nil {
+				logger.Errorf("Failed to revoke expired session %s: %v", oldRefreshToken.FamilyID, revokeErr)
+			}
+		}
 		return nil, err
 	}
 
-	// Create new refresh token
-	newRefreshToken, err := s.CreateRefreshToken(oldRefreshToken.UserID, ipAddress, userAgent)
+	return newRefreshToken, nil
+}
+
+// withinReuseGrace reports whether a rotated token was replaced recently enough that
+// presenting it again is a benign race (e.g. another instance rotated it) rather than reuse
+func (s *TokenService) withinReuseGrace(refreshToken *models.RefreshToken) bool {
+	return refreshToken.RevokedAt != nil && time.Since(*refreshToken.RevokedAt) <= s.config.RefreshTokenReuseGrace
+}
+
+// handleTokenReuse revokes the whole token family of a replayed token, records a
+// security audit event and notifies the user if a notifier is configured
+func (s *TokenService) handleTokenReuse(refreshToken *models.RefreshToken, ipAddress, userAgent string) {
+	revoked, err := s.RevokeTokenFamily(refreshToken.FamilyID)
 	if err != nil {
-		return nil, err
+		logger.Errorf("Failed to revoke token family %s: %v", refreshToken.FamilyID, err)
 	}
 
-	// Revoke old token and mark replacement
-	now := time.Now()
-	oldRefreshToken.IsRevoked = true
-	oldRefreshToken.RevokedAt = &now
-	oldRefreshToken.ReplacedBy = &newRefreshToken.ID
+	var user models.Users
+	if err := s.db.First(&user, refreshToken.UserID).Error; err != nil {
+		logger.Errorf("Failed to load user %d for token reuse handling: %v", refreshToken.UserID, err)
+	}
 
-	if err := s.db.Save(oldRefreshToken).Error; err != nil {
-		// Rollback: delete new token if we can't revoke old one
-		s.db.Delete(newRefreshToken)
-		return nil, err
+	if s.auditService != nil {
+		_ = s.auditService.LogWithContext(
+			&refreshToken.UserID,
+			user.Username,
+			"TOKEN_REUSE_DETECTED",
+			"auth",
+			refreshToken.FamilyID,
+			nil,
+			map[string]interface{}{
+				"token_id":       refreshToken.ID,
+				"family_id":      refreshToken.FamilyID,
+				"revoked_tokens": revoked,
+			},
+			ipAddress,
+			userAgent,
+			"",
+		)
 	}
 
-	return newRefreshToken, nil
+	if s.notifier != nil && user.ID != 0 {
+		if err := s.notifier.NotifyTokenReuse(user, ipAddress, userAgent); err != nil {
+			logger.Errorf("Failed to notify user %d about token reuse: %v", user.ID, err)
+		}
+	}
+}
+
+// SessionActive reports whether the session an access token was issued for is still
+// signed in, i.e. its refresh token family has not been revoked and has not expired
+func (s *TokenService) SessionActive(sessionID string) (bool, error) {
+	s.sessionMu.Lock()
+	session, ok := s.liveSessions[sessionID]
+	generation := s.sessionGeneration
+	s.sessionMu.Unlock()
+	if ok && time.Since(session.checkedAt) < liveSessionTTL {
+		return time.Now().Before(session.expiresAt), nil
+	}
+
+	token, err := s.liveSessionToken(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if generation == s.sessionGeneration {
+		now := time.Now()
+		for id, cached := range s.liveSessions {
+			if now.Sub(cached.checkedAt) >= liveSessionTTL {
+				delete(s.liveSessions, id)
+			}
+		}
+		s.liveSessions[sessionID] = liveSession{userID: token.UserID, expiresAt: token.ExpiresAt, checkedAt: now}
+	}
+
+	return true, nil
+}
+
+// liveSessionToken returns the live refresh token of a session, whose expiry is when the
+// session ends. It returns gorm.ErrRecordNotFound once the session is revoked or expired.
+func (s *TokenService) liveSessionToken(sessionID string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.db.Select("user_id", "expires_at").
+		Where("family_id = ? AND is_revoked = ? AND expires_at > ?", sessionID, false, time.Now()).
+		Order("expires_at DESC").
+		First(&token).Error
+	return token, err
+}
+
+// forgetSessions drops cached sessions after a revocation, either one session or,
+// when sessionID is empty, every session of the user
+func (s *TokenService) forgetSessions(userID uint, sessionID string) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	s.sessionGeneration++
+	if sessionID != "" {
+		delete(s.liveSessions, sessionID)
+		return
+	}
+	for id, cached := range s.liveSessions {
+		if cached.userID == userID {
+			delete(s.liveSessions, id)
+		}
+	}
+}
+
+// RevokeTokenFamily revokes every active token descending from the same login.
+// It returns the number of tokens that were revoked.
+func (s *TokenService) RevokeTokenFamily(familyID string) (int64, error) {
+	if familyID == "" {
+		return 0, nil
+	}
+	defer s.forgetSessions(0, familyID)
+
+	return revokeTokenFamily(s.db, familyID)
+}
+
+// revokeTokenFamily revokes the active tokens of a family within db
+func revokeTokenFamily(db *gorm.DB, familyID string) (int64, error) {
+	result := db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND is_revoked = ?", familyID, false).
+		Updates(map[string]interface{}{
+			"is_revoked": true,
+			"revoked_at": time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
 }
 
 // RevokeRefreshToken revokes a specific refresh token
 func (s *TokenService) RevokeRefreshToken(token string) error {
-	var refreshToken models.RefreshToken
-
-	if err := s.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+	refreshToken, err := findRefreshToken(s.db, token)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("refresh token not found")
 		}
@@ -126,13 +622,15 @@ func (s *TokenService) RevokeRefreshToken(token string) error {
 	now := time.Now()
 	refreshToken.IsRevoked = true
 	refreshToken.RevokedAt = &now
+	defer s.forgetSessions(refreshToken.UserID, refreshToken.FamilyID)
 
-	return s.db.Save(&refreshToken).Error
+	return s.db.Save(refreshToken).Error
 }
 
 // RevokeAllUserTokens revokes all refresh tokens for a specific user
 func (s *TokenService) RevokeAllUserTokens(userID uint) error {
 	now := time.Now()
+	defer s.forgetSessions(userID, "")
 
 	return s.db.Model(&models.RefreshToken{}).
 		Where("user_id = ? AND is_revoked = ?", userID, false).
@@ -147,8 +645,113 @@ func (s *TokenService) CleanupExpiredTokens() error {
 	// Delete tokens that expired more than 30 days ago
 	cutoffDate := time.Now().AddDate(0, 0, -30)
 
-	return s.db.Where("expires_at < ?", cutoffDate).
-		Delete(&models.RefreshToken{}).Error
+	if err := s.db.Where("expires_at < ?", cutoffDate).
+		Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+
+	// Drop sessions whose refresh tokens are all gone
+	return s.db.Where("id NOT IN (?)", s.db.Model(&models.RefreshToken{}).Select("family_id")).
+		Delete(&models.Session{}).Error
+}
+
+// StartSession creates the refresh token of a new session for the user, whose roles must
+// be preloaded. If the user is at the concurrent session cap, the oldest sessions are
+// signed out, or the login is refused when the policy is to reject. The user row stays
+// locked from counting the sessions to inserting the new one, so concurrent logins
+// can't both take the last free slot.
+func (s *TokenService) StartSession(user models.Users, ipAddress, userAgent string) (*models.RefreshToken, error) {
+	limit := s.config.MaxSessionsPerUser
+	if roleLimit := user.MaxSessions(); roleLimit > 0 {
+		limit = roleLimit
+	}
+
+	var refreshToken *models.RefreshToken
+	var evicted []models.Session
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Users{}, user.ID).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if evicted, err = s.enforceSessionLimit(tx, user.ID, limit); err != nil {
+			return err
+		}
+
+		refreshToken, err = s.createRefreshToken(tx, user.ID, uuid.New().String(), time.Now(), ipAddress, userAgent)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(evicted) > 0 {
+		s.forgetSessions(user.ID, "")
+	}
+	for _, session := range evicted {
+		if s.auditService != nil {
+			_ = s.auditService.LogWithContext(
+				&user.ID,
+				user.Username,
+				"SESSION_EVICTED",
+				"session",
+				session.ID,
+				nil,
+				map[string]interface{}{
+					"reason":       "session limit reached",
+					"limit":        limit,
+					"last_seen_at": session.LastSeenAt,
+					"ip_address":   session.IPAddress,
+					"user_agent":   session.UserAgent,
+				},
+				ipAddress,
+				userAgent,
+				"",
+			)
+		}
+	}
+
+	return refreshToken, nil
+}
+
+// enforceSessionLimit revokes the oldest sessions of the user so a new one fits within
+// limit, and returns them. Callers must hold the lock on the user row.
+func (s *TokenService) enforceSessionLimit(tx *gorm.DB, userID uint, limit int) ([]models.Session, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var familyIDs []string
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Distinct().
+		Pluck("family_id", &familyIDs).Error; err != nil {
+		return nil, err
+	}
+
+	excess := len(familyIDs) - limit + 1
+	if excess <= 0 {
+		return nil, nil
+	}
+	if s.config.SessionLimitPolicy == config.SessionLimitReject {
+		return nil, errors.New("session limit reached")
+	}
+
+	var oldest []models.Session
+	if err := tx.Where("id IN ?", familyIDs).
+		Order("created_at ASC").
+		Limit(excess).
+		Find(&oldest).Error; err != nil {
+		return nil, err
+	}
+
+	for _, session := range oldest {
+		if _, err := revokeTokenFamily(tx, session.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return oldest, nil
 }
 
 // GetUserActiveTokens returns all active (non-revoked, non-expired) tokens for a user
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+)
+
+func newTestTokenService(t *testing.T) *TokenService {
+	t.Helper()
+
+	db := newTestDB(t, &models.Organization{}, &models.Role{}, &models.Users{}, &models.UserRole{},
+		&models.Session{}, &models.RefreshToken{}, &models.AuditLog{})
+	cfg := &config.Config{
+		JWTSigningAlg:      "HS256",
+		JWTSecret:          "test-secret",
+		JWTExpiry:          24 * time.Hour,
+		RefreshTokenExpiry: 7 * 24 * time.Hour,
+	}
+
+	keyRing, err := NewKeyRing(cfg)
+	if err != nil {
+		t.Fatalf("new key ring: %v", err)
+	}
+	return NewTokenService(db, cfg, NewAuditService(db), keyRing)
+}
+
+func TestSessionActiveAfterRevocation(t *testing.T) {
+	s := newTestTokenService(t)
+	sessions := NewSessionService(s.db, s)
+	user := createTestUser(t, s.db, "alice")
+
+	login := func() string {
+		t.Helper()
+		refreshToken, err := s.CreateRefreshToken(user.ID, "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("create refresh token: %v", err)
+		}
+		if active, err := s.SessionActive(refreshToken.FamilyID); err != nil || !active {
+			t.Fatalf("new session active = %v (%v), want active", active, err)
+		}
+		return refreshToken.FamilyID
+	}
+	assertRevoked := func(sessionID string) {
+		t.Helper()
+		if active, err := s.SessionActive(sessionID); err != nil || active {
+			t.Errorf("session active = %v (%v) after revocation, want revoked", active, err)
+		}
+	}
+
+	first := login()
+	if err := sessions.RevokeSession(user.ID, first); err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	assertRevoked(first)
+
+	current, other := login(), login()
+	if _, err := sessions.RevokeOtherSessions(user.ID, current); err != nil {
+		t.Fatalf("revoke other sessions: %v", err)
+	}
+	assertRevoked(other)
+	if active, _ := s.SessionActive(current); !active {
+		t.Error("current session revoked with the others")
+	}
+
+	if err := sessions.RevokeAllSessions(user.ID); err != nil {
+		t.Fatalf("revoke all sessions: %v", err)
+	}
+	assertRevoked(current)
+	assertRevoked("unknown-session")
+}
+
+func TestAccessTokenEndsWithSession(t *testing.T) {
+	s := newTestTokenService(t)
+	user := createTestUser(t, s.db, "alice")
+	s.db.Model(&models.Role{}).Where("id = ?", user.RoleID).Update("session_idle_timeout_minutes", 30)
+
+	refreshToken, err := s.CreateRefreshToken(user.ID, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+
+	_, expiresAt, err := s.GenerateAccessToken(user, refreshToken.FamilyID)
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	if !expiresAt.Equal(refreshToken.ExpiresAt) {
+		t.Errorf("access token expires at %v, want the session end %v", expiresAt, refreshToken.ExpiresAt)
+	}
+
+	// Let the session reach its idle timeout
+	s.db.Model(&models.RefreshToken{}).Where("family_id = ?", refreshToken.FamilyID).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	s.forgetSessions(user.ID, "")
+
+	if active, err := s.SessionActive(refreshToken.FamilyID); err != nil || active {
+		t.Errorf("session active = %v (%v) after its idle timeout, want ended", active, err)
+	}
+	if _, _, err := s.GenerateAccessToken(user, refreshToken.FamilyID); err == nil || err.Error() != "session not found" {
+		t.Errorf("error = %v, want session not found", err)
+	}
+}
+
+func TestStartSessionEvictsOldestAtLimit(t *testing.T) {
+	s := newTestTokenService(t)
+	s.config.MaxSessionsPerUser = 2
+	user := createTestUser(t, s.db, "alice")
+
+	var sessionIDs []string
+	for range 3 {
+		refreshToken, err := s.StartSession(user, "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("start session: %v", err)
+		}
+		sessionIDs = append(sessionIDs, refreshToken.FamilyID)
+		time.Sleep(time.Millisecond) // Keep creation times ordered
+	}
+
+	for i, sessionID := range sessionIDs {
+		active, err := s.SessionActive(sessionID)
+		if err != nil {
+			t.Fatalf("session active: %v", err)
+		}
+		if want := i > 0; active != want {
+			t.Errorf("session %d active = %v, want %v", i, active, want)
+		}
+	}
+
+	var evictions int64
+	s.db.Model(&models.AuditLog{}).Where("action = ?", "SESSION_EVICTED").Count(&evictions)
+	if evictions != 1 {
+		t.Errorf("%d evictions audited, want 1", evictions)
+	}
+
+	s.config.SessionLimitPolicy = config.SessionLimitReject
+	if _, err := s.StartSession(user, "127.0.0.1", "test"); err == nil || err.Error() != "session limit reached" {
+		t.Errorf("error = %v, want session limit reached", err)
+	}
+}
+
+func TestSessionPolicyAppliesEveryRole(t *testing.T) {
+	s := newTestTokenService(t)
+	user := createTestUser(t, s.db, "alice")
+	s.db.Model(&models.Role{}).Where("id = ?", user.RoleID).Update("session_idle_timeout_minutes", 60)
+	strict := models.Role{Name: "strict", DisplayName: "Strict", IsActive: true, SessionIdleTimeoutMinutes: 10, MaxSessions: 1}
+	s.db.Create(&strict)
+	s.db.Create(&models.UserRole{UserID: user.ID, RoleID: user.RoleID})
+	s.db.Create(&models.UserRole{UserID: user.ID, RoleID: strict.ID})
+	s.db.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID)
+
+	first, err := s.StartSession(user, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	if limit := time.Now().Add(10 * time.Minute); first.ExpiresAt.After(limit) {
+		t.Errorf("session expires at %v, want within the strict role's idle timeout", first.ExpiresAt)
+	}
+
+	if _, err := s.StartSession(user, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	if active, _ := s.SessionActive(first.FamilyID); active {
+		t.Error("first session kept past the strict role's session cap")
+	}
+}
+
+func TestRotateRefreshTokenReuseGrace(t *testing.T) {
+	s := newTestTokenService(t)
+	s.config.RefreshTokenReuseGrace = time.Minute
+	user := createTestUser(t, s.db, "alice")
+
+	original, err := s.CreateRefreshToken(user.ID, "127.0.0.1", "browser")
+	if err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	rotated, err := s.RotateRefreshToken(original.Token, "127.0.0.1", "browser")
+	if err != nil {
+		t.Fatalf("rotate refresh token: %v", err)
+	}
+
+	// A parallel request from the same client that lost the race stays in the session
+	sibling, err := s.RotateRefreshToken(original.Token, "127.0.0.1", "browser")
+	if err != nil {
+		t.Fatalf("rotate within grace period: %v", err)
+	}
+	if sibling.FamilyID != original.FamilyID || sibling.ID == rotated.ID {
+		t.Errorf("grace rotation issued token %d in family %s, want a new token in %s", sibling.ID, sibling.FamilyID, original.FamilyID)
+	}
+
+	// The same token presented by another client is theft, even within the grace period
+	if _, err := s.RotateRefreshToken(original.Token, "10.0.0.1", "curl"); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Errorf("error = %v, want refresh token reuse detected", err)
+	}
+	if active, _ := s.SessionActive(original.FamilyID); active {
+		t.Error("session kept after its refresh token was reused")
+	}
+
+	// Once the grace period has passed the original client is treated the same way
+	second, err := s.CreateRefreshToken(user.ID, "127.0.0.1", "browser")
+	if err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	if _, err := s.RotateRefreshToken(second.Token, "127.0.0.1", "browser"); err != nil {
+		t.Fatalf("rotate refresh token: %v", err)
+	}
+	s.db.Model(&models.RefreshToken{}).Where("id = ?", second.ID).Update("revoked_at", time.Now().Add(-2*time.Minute))
+	if _, err := s.RotateRefreshToken(second.Token, "127.0.0.1", "browser"); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Errorf("error = %v, want refresh token reuse detected", err)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import "strings"
+
+// userAgentRule maps a user agent substring to a display name. Rules are checked in
+// order, so more specific tokens (e.g. "Edg/") must come before the ones they contain.
+type userAgentRule struct {
+	token string
+	name  string
+}
+
+var browserRules = []userAgentRule{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+var osRules = []userAgentRule{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// parseUserAgent extracts the device type, browser and operating system from a
+// User-Agent header for display in the sessions list
+func parseUserAgent(userAgent string) (device, browser, os string) {
+	browser = matchUserAgent(userAgent, browserRules)
+	os = matchUserAgent(userAgent, osRules)
+
+	switch {
+	case userAgent == "":
+		device = "Unknown"
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet"):
+		device = "Tablet"
+	case strings.Contains(userAgent, "Mobi") || strings.Contains(userAgent, "iPhone"):
+		device = "Mobile"
+	case os == "Android":
+		// Android browsers without "Mobile" are tablets
+		device = "Tablet"
+	case os == "Unknown":
+		device = "Other"
+	default:
+		device = "Desktop"
+	}
+
+	return device, browser, os
+}
+
+// matchUserAgent returns the name of the first rule whose token appears in the user agent
+func matchUserAgent(userAgent string, rules []userAgentRule) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule.token) {
+			return rule.name
+		}
+	}
+	return "Unknown"
+}
@@ -1,13 +1,15 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/Aebroyx/sass-api/internal/config"
 	"github.com/Aebroyx/sass-api/internal/domain/models"
 	"github.com/Aebroyx/sass-api/internal/pagination"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/Aebroyx/sass-api/internal/tenant"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -17,6 +19,9 @@ type UserService struct {
 	db           *gorm.DB
 	config       *config.Config
 	tokenService *TokenService
+	mfaService   *MFAService
+	webAuthn     *WebAuthnService
+	passwords    *PasswordPolicyService
 }
 
 // UserQueryParams represents the query parameters for user listing
@@ -38,80 +43,87 @@ type UserListResponse struct {
 	TotalPages int            `json:"totalPages"`
 }
 
-func NewUserService(db *gorm.DB, config *config.Config, tokenService *TokenService) *UserService {
+func NewUserService(db *gorm.DB, config *config.Config, tokenService *TokenService, mfaService *MFAService, webAuthn *WebAuthnService, passwords *PasswordPolicyService) *UserService {
 	return &UserService{
 		db:           db,
 		config:       config,
 		tokenService: tokenService,
+		mfaService:   mfaService,
+		webAuthn:     webAuthn,
+		passwords:    passwords,
 	}
 }
 
+// WithContext returns a copy of the service bound to ctx. Administration methods called
+// on it only see and change users of the request's organization.
+func (s *UserService) WithContext(ctx context.Context) *UserService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 // Register creates a new user with the provided registration data
 func (s *UserService) Register(req *models.RegisterRequest) (*models.RegisterResponse, error) {
+	// Usernames and emails are unique across organizations
+	allOrganizations := tenant.AllOrganizations(s.db)
+
 	// Check if username already exists
 	var existingUser models.Users
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := allOrganizations.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return nil, errors.New("username already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
 	// Check if email already exists
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := allOrganizations.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("email already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
+	if err := s.passwords.Validate(req.Password, &models.Users{Username: req.Username, Email: req.Email}); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get default role
-	var defaultRole models.Role
-	if err := s.db.Where("is_default = ?", true).First(&defaultRole).Error; err != nil {
-		// Fallback to user role if no default
-		if err := s.db.Where("name = ?", "user").First(&defaultRole).Error; err != nil {
-			return nil, errors.New("default role not found")
-		}
+	// Self-registered users join the default organization with its default role
+	organization, err := defaultOrganization(s.db, s.config)
+	if err != nil {
+		return nil, err
+	}
+	role, err := defaultRole(s.db, organization.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create new user with RoleID
+	now := time.Now()
 	user := models.Users{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hashedPassword),
-		Name:     req.Name,
-		RoleID:   defaultRole.ID,
+		Username:          req.Username,
+		Email:             req.Email,
+		Password:          string(hashedPassword),
+		Name:              req.Name,
+		RoleID:            role.ID,
+		OrganizationID:    &organization.ID,
+		PasswordChangedAt: &now,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
+	if err := s.createUser(&user, nil); err != nil {
 		return nil, err
 	}
 
-	// Preload role for response
-	s.db.Preload("Role").First(&user, user.ID)
+	// Preload roles for response
+	s.db.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID)
 
 	// Return user data without password
-	return &models.RegisterResponse{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Name:     user.Name,
-		IsActive: user.IsActive,
-		Role: models.RoleResponse{
-			ID:          user.Role.ID,
-			Name:        user.Role.Name,
-			DisplayName: user.Role.DisplayName,
-			Description: user.Role.Description,
-			IsDefault:   user.Role.IsDefault,
-			IsActive:    user.Role.IsActive,
-			CreatedAt:   user.Role.CreatedAt,
-			UpdatedAt:   user.Role.UpdatedAt,
-		},
-	}, nil
+	response := newRegisterResponse(user)
+	return &response, nil
 }
 
 // Login authenticates a user and returns tokens
@@ -124,15 +136,23 @@ func (s *UserService) Login(req *models.LoginRequest) (*models.LoginResponse, er
 func (s *UserService) LoginWithContext(req *models.LoginRequest, ipAddress, userAgent string) (*models.LoginResponse, error) {
 	// Find user by username with role preloaded
 	var user models.Users
-	if err := s.db.Preload("Role").Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").Where("username = ?", req.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid username or password")
 		}
 		return nil, err
 	}
 
+	// Refuse locked accounts before checking the password so guessing makes no progress
+	if err := s.checkAccountLocked(&user); err != nil {
+		return nil, err
+	}
+
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if err := s.recordFailedLogin(user.ID); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("invalid username or password")
 	}
 
@@ -141,81 +161,233 @@ func (s *UserService) LoginWithContext(req *models.LoginRequest, ipAddress, user
 		return nil, errors.New("user is not active")
 	}
 
-	// Generate access token (JWT)
-	accessToken, accessExp, err := s.generateToken(user, s.config.JWTExpiry)
-	if err != nil {
-		return nil, err
+	if s.config.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return nil, errors.New("email not verified")
+	}
+
+	if s.passwords.IsExpired(&user) {
+		return nil, errors.New("password expired")
 	}
 
-	// Create refresh token in database (if tokenService is available)
-	var refreshTokenStr string
-	if s.tokenService != nil {
-		refreshToken, err := s.tokenService.CreateRefreshToken(user.ID, ipAddress, userAgent)
+	// Hold back tokens until the second factor is verified
+	if s.mfaService != nil && (user.MFAEnabled || user.RequiresMFA()) {
+		challenge, err := s.mfaService.IssueChallenge(user)
 		if err != nil {
 			return nil, err
 		}
-		refreshTokenStr = refreshToken.Token
+
+		return &models.LoginResponse{
+			User: newRegisterResponse(user),
+			MFA:  challenge,
+		}, nil
+	}
+
+	return s.issueTokens(user, ipAddress, userAgent)
+}
+
+// CompleteMFALogin verifies the second factor of a login and issues tokens.
+// Users whose role requires MFA but who have not enrolled yet confirm their
+// pending enrolment here, and receive their recovery codes in the response.
+func (s *UserService) CompleteMFALogin(req *models.MFAVerifyRequest, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	userID, err := s.mfaService.ParseChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.Users
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").First(&user, userID).Error; err != nil {
+		return nil, errors.New("invalid mfa token")
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	if err := s.checkAccountLocked(&user); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabled {
+		err = s.mfaService.VerifyCode(&user, req.Code)
 	} else {
-		// Fallback to JWT-based refresh token for backward compatibility
-		refreshTokenStr, _, err = s.generateToken(user, s.config.RefreshTokenExpiry)
+		if user.MFASecret == "" {
+			return nil, errors.New("mfa enrollment required")
+		}
+		var enrollment *models.MFARecoveryCodesResponse
+		if enrollment, err = s.mfaService.ConfirmEnrollment(user.ID, req.Code); err == nil {
+			recoveryCodes = enrollment.RecoveryCodes
+		}
+	}
+	if err != nil {
+		// Wrong codes count towards the lockout like wrong passwords
+		if err.Error() == "invalid mfa code" {
+			if lockErr := s.recordFailedLogin(user.ID); lockErr != nil {
+				return nil, lockErr
+			}
+		}
+		return nil, err
+	}
+
+	response, err := s.issueTokens(user, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	return response, nil
+}
+
+// BeginMFAChallengeEnrollment starts TOTP enrolment for a user in the middle of a
+// login whose role requires MFA
+func (s *UserService) BeginMFAChallengeEnrollment(mfaToken string) (*models.MFAEnrollmentResponse, error) {
+	userID, err := s.mfaService.ParseChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mfaService.BeginEnrollment(userID)
+}
+
+// LoginWithWebAuthn verifies a passkey assertion and issues tokens.
+// Passkeys require user verification on the authenticator, so they satisfy MFA on their own.
+func (s *UserService) LoginWithWebAuthn(req *models.WebAuthnFinishRequest, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	user, err := s.webAuthn.FinishLogin(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	if err := s.checkAccountLocked(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(*user, ipAddress, userAgent)
+}
+
+// LoginWithExternalIdentity signs in a user authenticated by an external OpenID Connect
+// provider. The provider stands in for the password, so MFA still applies.
+func (s *UserService) LoginWithExternalIdentity(user *models.Users, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	return s.loginWithFirstFactor(user, ipAddress, userAgent)
+}
+
+// LoginWithMagicLink signs in a user who followed a magic link. Access to the mailbox
+// stands in for the password, so MFA still applies.
+func (s *UserService) LoginWithMagicLink(user *models.Users, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	return s.loginWithFirstFactor(user, ipAddress, userAgent)
+}
+
+// loginWithFirstFactor completes a login whose first factor was verified outside of
+// this service: it issues tokens, or an MFA challenge if a second factor is required
+func (s *UserService) loginWithFirstFactor(user *models.Users, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	if err := s.checkAccountLocked(user); err != nil {
+		return nil, err
+	}
+
+	if err := loadUserRoles(s.db, user); err != nil {
+		return nil, err
+	}
+
+	if s.mfaService != nil && (user.MFAEnabled || user.RequiresMFA()) {
+		challenge, err := s.mfaService.IssueChallenge(*user)
 		if err != nil {
 			return nil, err
 		}
+
+		return &models.LoginResponse{
+			User: newRegisterResponse(*user),
+			MFA:  challenge,
+		}, nil
 	}
 
+	return s.issueTokens(*user, ipAddress, userAgent)
+}
+
+// issueTokens creates the access and refresh tokens for an authenticated user
+func (s *UserService) issueTokens(user models.Users, ipAddress, userAgent string) (*models.LoginResponse, error) {
+	if err := loadUserRoles(s.db, &user); err != nil {
+		return nil, err
+	}
+
+	// Start a new session, signing out old ones or refusing the login if the user is at the session cap
+	refreshToken, err := s.tokenService.StartSession(user, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate access token (JWT)
+	accessToken, accessExp, err := s.tokenService.GenerateAccessToken(user, refreshToken.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failed attempts are only forgiven once every factor has been verified, so a known
+	// password doesn't give unlimited guesses at the second factor
+	s.clearFailedLogins(&user)
+
 	// Create response
 	return &models.LoginResponse{
-		User: models.RegisterResponse{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Name:     user.Name,
-			IsActive: user.IsActive,
-			Role: models.RoleResponse{
-				ID:          user.Role.ID,
-				Name:        user.Role.Name,
-				DisplayName: user.Role.DisplayName,
-				Description: user.Role.Description,
-				IsDefault:   user.Role.IsDefault,
-				IsActive:    user.Role.IsActive,
-				CreatedAt:   user.Role.CreatedAt,
-				UpdatedAt:   user.Role.UpdatedAt,
-			},
-		},
+		User: newRegisterResponse(user),
 		Token: models.TokenResponse{
 			AccessToken:  accessToken,
-			RefreshToken: refreshTokenStr,
+			RefreshToken: refreshToken.Token,
 			TokenType:    "Bearer",
 			ExpiresIn:    int64(time.Until(accessExp).Seconds()),
+
+			RefreshExpiresIn: int64(time.Until(refreshToken.ExpiresAt).Seconds()),
 		},
 	}, nil
 }
 
-// generateToken generates a JWT token for the user
-func (s *UserService) generateToken(user models.Users, expiry time.Duration) (string, time.Time, error) {
-	expirationTime := time.Now().Add(expiry)
-	claims := &models.Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		RoleID:   user.RoleID,
-		RoleName: user.Role.Name,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "the-blade-api",
-			Subject:   user.Username,
+// newRegisterResponse maps a user with preloaded roles to its public representation
+func newRegisterResponse(user models.Users) models.RegisterResponse {
+	return models.RegisterResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Name:          user.Name,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role: models.RoleResponse{
+			ID:          user.Role.ID,
+			Name:        user.Role.Name,
+			DisplayName: user.Role.DisplayName,
+			Description: user.Role.Description,
+			IsDefault:   user.Role.IsDefault,
+			IsActive:    user.Role.IsActive,
+			RequireMFA:  user.RequiresMFA(),
+			CreatedAt:   user.Role.CreatedAt,
+			UpdatedAt:   user.Role.UpdatedAt,
 		},
+		Roles:          roleSummaries(user.Roles()),
+		OrganizationID: user.OrganizationID,
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.config.JWTSecret))
-	if err != nil {
-		return "", time.Time{}, err
+// roleSummaries maps roles to the role details included with a user
+func roleSummaries(roles []models.Role) []models.RoleResponse {
+	summaries := make([]models.RoleResponse, len(roles))
+	for i, role := range roles {
+		summaries[i] = models.RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			IsDefault:   role.IsDefault,
+			IsActive:    role.IsActive,
+			RequireMFA:  role.RequireMFA,
+			CreatedAt:   role.CreatedAt,
+			UpdatedAt:   role.UpdatedAt,
+		}
 	}
-
-	return tokenString, expirationTime, nil
+	return summaries
 }
 
 // GetAllUsers retrieves users with pagination, search, and filters
@@ -262,7 +434,7 @@ func (s *UserService) GetAllUsers(params pagination.QueryParams) (*pagination.Pa
 
 func (s *UserService) GetUserById(id string) (models.Users, error) {
 	var user models.Users
-	if err := s.db.Preload("Role").Where("id = ?", id).First(&user).Error; err != nil {
+	if err := s.db.Preload("Role").Preload("UserRoles.Role").Where("id = ?", id).First(&user).Error; err != nil {
 		return models.Users{}, err
 	}
 	return user, nil
@@ -270,25 +442,35 @@ func (s *UserService) GetUserById(id string) (models.Users, error) {
 
 // CreateUser creates a new user with the provided data
 func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.CreateUserResponse, error) {
+	// Usernames and emails are unique across organizations
+	allOrganizations := tenant.AllOrganizations(s.db)
+
 	// Check if username already exists
 	var existingUser models.Users
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := allOrganizations.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return nil, errors.New("username already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
 	// Check if email already exists
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := allOrganizations.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("email already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
 	// Validate role exists
-	var role models.Role
-	if err := s.db.First(&role, req.RoleID).Error; err != nil {
-		return nil, errors.New("invalid role")
+	_, organizationID, err := assignableRole(s.db, s.config, req.RoleID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAdditionalRoles(s.db, req.RoleIDs, organizationID); err != nil {
+		return nil, err
+	}
+
+	if err := s.passwords.Validate(req.Password, &models.Users{Username: req.Username, Email: req.Email}); err != nil {
+		return nil, err
 	}
 
 	// Hash password
@@ -302,22 +484,26 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.CreateU
 		return nil, errors.New("is_active is required")
 	}
 
-	// Create new user
+	// Create new user; accounts provisioned by an administrator don't need to confirm their address
+	now := time.Now()
 	user := models.Users{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hashedPassword),
-		Name:     req.Name,
-		RoleID:   req.RoleID,
-		IsActive: *req.IsActive,
-	}
-
-	if err := s.db.Create(&user).Error; err != nil {
+		Username:          req.Username,
+		Email:             req.Email,
+		Password:          string(hashedPassword),
+		Name:              req.Name,
+		RoleID:            req.RoleID,
+		OrganizationID:    organizationID,
+		IsActive:          *req.IsActive,
+		EmailVerifiedAt:   &now,
+		PasswordChangedAt: &now,
+	}
+
+	if err := s.createUser(&user, req.RoleIDs); err != nil {
 		return nil, err
 	}
 
-	// Preload role for response
-	s.db.Preload("Role").First(&user, user.ID)
+	// Preload roles for response
+	s.db.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID)
 
 	// Return user data without password
 	return &models.CreateUserResponse{
@@ -333,25 +519,37 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.CreateU
 			Description: user.Role.Description,
 			IsDefault:   user.Role.IsDefault,
 			IsActive:    user.Role.IsActive,
+			RequireMFA:  user.RequiresMFA(),
 			CreatedAt:   user.Role.CreatedAt,
 			UpdatedAt:   user.Role.UpdatedAt,
 		},
+		Roles:     roleSummaries(user.Roles()),
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
 func (s *UserService) UpdateUser(id string, req *models.UpdateUserRequest) (*models.Users, error) {
 	var user models.Users
-	if err := s.db.Preload("Role").Where("id = ?", id).First(&user).Error; err != nil {
+	if err := s.db.Preload("Role").Preload("UserRoles").Where("id = ?", id).First(&user).Error; err != nil {
 		return nil, err
 	}
 
-	// Validate role exists if being changed
+	// Validate role exists if being changed; the role decides which organization the user is in
 	if req.RoleID != user.RoleID {
-		var role models.Role
-		if err := s.db.First(&role, req.RoleID).Error; err != nil {
-			return nil, errors.New("invalid role")
+		_, organizationID, err := assignableRole(s.db, s.config, req.RoleID, user.OrganizationID)
+		if err != nil {
+			return nil, err
 		}
+		user.OrganizationID = organizationID
+	}
+
+	// Omitted additional roles stay as they are, but must still fit the user's organization
+	additionalRoleIDs := req.RoleIDs
+	if additionalRoleIDs == nil {
+		additionalRoleIDs = user.RoleIDs()[1:]
+	}
+	if err := checkAdditionalRoles(s.db, additionalRoleIDs, user.OrganizationID); err != nil {
+		return nil, err
 	}
 
 	// Validate IsActive is provided
@@ -368,25 +566,32 @@ func (s *UserService) UpdateUser(id string, req *models.UpdateUserRequest) (*mod
 
 	// Only update password if provided
 	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
+		if err := s.passwords.Validate(req.Password, &user); err != nil {
 			return nil, err
 		}
-		user.Password = string(hashedPassword)
 	}
 
-	// Update user - use Select to explicitly specify fields to update (including is_active even when false)
-	fieldsToUpdate := []string{"username", "email", "name", "role_id", "is_active"}
-	if req.Password != "" {
-		fieldsToUpdate = append(fieldsToUpdate, "password")
-	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Update user - use Select to explicitly specify fields to update (including is_active even when false)
+		fieldsToUpdate := []string{"username", "email", "name", "role_id", "organization_id", "is_active"}
+		if err := tx.Model(&user).Select(fieldsToUpdate).Updates(&user).Error; err != nil {
+			return err
+		}
 
-	if err := s.db.Model(&user).Select(fieldsToUpdate).Updates(&user).Error; err != nil {
+		if err := setUserRoles(tx, user.ID, user.RoleID, additionalRoleIDs); err != nil {
+			return err
+		}
+
+		if req.Password != "" {
+			return s.passwords.SetPassword(tx, user.ID, req.Password)
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 
-	// Reload with role
-	s.db.Preload("Role").First(&user, user.ID)
+	// Reload with roles
+	s.db.Preload("Role").Preload("UserRoles.Role").First(&user, user.ID)
 
 	return &user, nil
 }
@@ -440,17 +645,11 @@ func (s *UserService) ResetUserPassword(id string, req *models.ResetUserPassword
 		return nil, errors.New("new password and confirm password do not match")
 	}
 
-	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
+	if err := s.passwords.Validate(req.NewPassword, &user); err != nil {
 		return nil, err
 	}
 
-	// Update user password
-	user.Password = string(hashedPassword)
-
-	// Update user
-	if err := s.db.Model(&user).Updates(&user).Error; err != nil {
+	if err := s.passwords.SetPassword(s.db, user.ID, req.NewPassword); err != nil {
 		return nil, err
 	}
 
@@ -459,3 +658,153 @@ func (s *UserService) ResetUserPassword(id string, req *models.ResetUserPassword
 
 	return &user, nil
 }
+
+// ChangeExpiredPassword replaces a password that has passed its maximum age.
+// The current password stands in for a session since login is refused until the password is changed.
+func (s *UserService) ChangeExpiredPassword(req *models.ChangeExpiredPasswordRequest) (*models.Users, error) {
+	var user models.Users
+	if err := s.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid username or password")
+		}
+		return nil, err
+	}
+
+	if err := s.checkAccountLocked(&user); err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		if err := s.recordFailedLogin(user.ID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid username or password")
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	if !s.passwords.IsExpired(&user) {
+		return nil, errors.New("password not expired")
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		return nil, errors.New("new password and confirm password do not match")
+	}
+
+	if err := s.passwords.Validate(req.NewPassword, &user); err != nil {
+		return nil, err
+	}
+
+	if err := s.passwords.SetPassword(s.db, user.ID, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	// The old password is gone, so attempts against it no longer count
+	s.clearFailedLogins(&user)
+
+	return &user, nil
+}
+
+// assignableRole loads a role the caller may give a user and returns the organization
+// the user belongs to with it. Inside an organization, only its own and shared roles can
+// be assigned and the user stays in it. Outside one, cross-tenant roles take the user out
+// of any organization, an organization's role moves the user there, and shared roles keep
+// the user's current organization or place them in the default one.
+func assignableRole(db *gorm.DB, cfg *config.Config, roleID uint, current *uint) (*models.Role, *uint, error) {
+	var role models.Role
+	if err := db.First(&role, roleID).Error; err != nil {
+		return nil, nil, errors.New("invalid role")
+	}
+	crossTenant := role.OrganizationID == nil && slices.Contains(config.CrossTenantRoles, role.Name)
+
+	if organizationID, scoped := tenant.FromDB(db); scoped {
+		if crossTenant {
+			return nil, nil, errors.New("invalid role")
+		}
+		return &role, &organizationID, nil
+	}
+
+	switch {
+	case crossTenant:
+		return &role, nil, nil
+	case role.OrganizationID != nil:
+		return &role, role.OrganizationID, nil
+	case current != nil:
+		return &role, current, nil
+	}
+
+	organization, err := defaultOrganization(db, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &role, &organization.ID, nil
+}
+
+// checkAdditionalRoles makes sure a user in the given organization can hold the roles
+// on top of their primary role: shared roles and the organization's own, but never a
+// cross-tenant role, which only works as a primary role
+func checkAdditionalRoles(db *gorm.DB, roleIDs []uint, organizationID *uint) error {
+	for _, roleID := range roleIDs {
+		var role models.Role
+		if err := db.First(&role, roleID).Error; err != nil {
+			return errors.New("invalid role")
+		}
+		if role.OrganizationID == nil && slices.Contains(config.CrossTenantRoles, role.Name) {
+			return errors.New("invalid role")
+		}
+		if role.OrganizationID != nil && (organizationID == nil || *role.OrganizationID != *organizationID) {
+			return errors.New("invalid role")
+		}
+	}
+	return nil
+}
+
+// setUserRoles replaces the roles a user holds with the primary role plus the additional ones
+func setUserRoles(tx *gorm.DB, userID, primaryRoleID uint, additionalRoleIDs []uint) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error; err != nil {
+		return err
+	}
+
+	userRoles := []models.UserRole{{UserID: userID, RoleID: primaryRoleID}}
+	for _, roleID := range additionalRoleIDs {
+		if !slices.ContainsFunc(userRoles, func(ur models.UserRole) bool { return ur.RoleID == roleID }) {
+			userRoles = append(userRoles, models.UserRole{UserID: userID, RoleID: roleID})
+		}
+	}
+	return tx.Create(&userRoles).Error
+}
+
+// replacePrimaryRole moves a user to another primary role, keeping their additional roles
+func replacePrimaryRole(tx *gorm.DB, user *models.Users, roleID uint) error {
+	// Also drops the row of an additional role that becomes primary, so it isn't duplicated
+	if err := tx.Where("user_id = ? AND role_id IN ?", user.ID, []uint{user.RoleID, roleID}).Delete(&models.UserRole{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+		return err
+	}
+	return tx.Model(user).Update("role_id", roleID).Error
+}
+
+// loadUserRoles loads every role a user holds, unless they were preloaded already
+func loadUserRoles(db *gorm.DB, user *models.Users) error {
+	if user.UserRoles != nil {
+		return nil
+	}
+	return db.Preload("Role").Where("user_id = ?", user.ID).Find(&user.UserRoles).Error
+}
+
+// createUser inserts a new user with their roles and starts their password history
+func (s *UserService) createUser(user *models.Users, additionalRoleIDs []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := setUserRoles(tx, user.ID, user.RoleID, additionalRoleIDs); err != nil {
+			return err
+		}
+		return s.passwords.RecordPassword(tx, user.ID, user.Password)
+	})
+}
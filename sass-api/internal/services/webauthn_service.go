@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	webAuthnPurposeRegistration = "registration"
+	webAuthnPurposeLogin        = "login"
+
+	defaultPasskeyName = "Passkey"
+
+	// decoyCredentialIDLength matches the credential ID length of common platform authenticators
+	decoyCredentialIDLength = 32
+)
+
+// WebAuthnService runs the passkey registration and login ceremonies.
+// Ceremony state is kept in the webauthn_sessions table so any API instance can
+// finish a ceremony another one started, and so each challenge is single-use.
+type WebAuthnService struct {
+	db       *gorm.DB
+	config   *config.Config
+	webAuthn *webauthn.WebAuthn
+	decoyKey []byte // Derives stable decoy credential IDs for unknown usernames
+}
+
+func NewWebAuthnService(db *gorm.DB, config *config.Config) (*WebAuthnService, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthnRPID,
+		RPDisplayName: config.WebAuthnRPDisplayName,
+		RPOrigins:     config.WebAuthnRPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey: protocol.ResidentKeyRequirementPreferred,
+			// Passkey logins skip the TOTP step, so the authenticator itself must verify the user
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: config.WebAuthnSessionTTL, TimeoutUVD: config.WebAuthnSessionTTL},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: config.WebAuthnSessionTTL, TimeoutUVD: config.WebAuthnSessionTTL},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decoyKey := make([]byte, 32)
+	if _, err := rand.Read(decoyKey); err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		db:       db,
+		config:   config,
+		webAuthn: wa,
+		decoyKey: decoyKey,
+	}, nil
+}
+
+// webAuthnUser adapts a user and their passkeys to the webauthn.User interface
+type webAuthnUser struct {
+	user        models.Users
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return webAuthnUserHandle(u.user.ID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		id, err := base64.RawURLEncoding.DecodeString(c.CredentialID)
+		if err != nil {
+			continue
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       parseTransports(c.Transports),
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    c.SignCount,
+				CloneWarning: c.CloneWarning,
+			},
+		})
+	}
+	return credentials
+}
+
+// BeginRegistration starts registering a new passkey for the user
+func (s *WebAuthnService) BeginRegistration(userID uint) (*models.WebAuthnBeginResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop the authenticator from registering the same passkey twice
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, c := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	creation, session, err := s.webAuthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.saveSession(webAuthnPurposeRegistration, &userID, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnBeginResponse{
+		SessionID: sessionID,
+		Options:   creation,
+	}, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation and stores the new passkey
+func (s *WebAuthnService) FinishRegistration(userID uint, req *models.WebAuthnFinishRequest) (*models.WebAuthnCredentialResponse, error) {
+	session, err := s.consumeSession(req.SessionID, webAuthnPurposeRegistration, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, errors.New("invalid passkey")
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, errors.New("invalid passkey")
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	var count int64
+	s.db.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&count)
+	if count > 0 {
+		return nil, errors.New("passkey already registered")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	record := models.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credentialID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	response := newWebAuthnCredentialResponse(record)
+	return &response, nil
+}
+
+// BeginLogin starts a passkey login. When the username matches a user with
+// passkeys only those are offered. Any other username gets a decoy credential that
+// stays the same across requests, so the options don't reveal whether the user exists
+// or has passkeys. Without a username the browser may pick any discoverable passkey.
+func (s *WebAuthnService) BeginLogin(username string) (*models.WebAuthnBeginResponse, error) {
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		userID    *uint
+		err       error
+	)
+
+	if username != "" {
+		var user models.Users
+		if err := s.db.Where("username = ?", username).First(&user).Error; err == nil {
+			if waUser, err := s.loadUser(user.ID); err == nil && len(waUser.credentials) > 0 {
+				assertion, session, err = s.webAuthn.BeginLogin(waUser)
+				if err != nil {
+					return nil, err
+				}
+				userID = &user.ID
+			}
+		}
+	}
+
+	if assertion == nil {
+		assertion, session, err = s.webAuthn.BeginDiscoverableLogin()
+		if err != nil {
+			return nil, err
+		}
+		if username != "" {
+			assertion.Response.AllowedCredentials = []protocol.CredentialDescriptor{s.decoyCredential(username)}
+		}
+	}
+
+	sessionID, err := s.saveSession(webAuthnPurposeLogin, userID, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnBeginResponse{
+		SessionID: sessionID,
+		Options:   assertion,
+	}, nil
+}
+
+// FinishLogin verifies the authenticator's assertion and returns the user it belongs to
+func (s *WebAuthnService) FinishLogin(req *models.WebAuthnFinishRequest) (*models.Users, error) {
+	session, err := s.consumeSession(req.SessionID, webAuthnPurposeLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, errors.New("invalid passkey")
+	}
+
+	var (
+		user       *webAuthnUser
+		credential *webauthn.Credential
+	)
+	if session.UserID != nil {
+		userID, err := parseWebAuthnUserHandle(session.UserID)
+		if err != nil {
+			return nil, errors.New("invalid passkey")
+		}
+		if user, err = s.loadUser(userID); err != nil {
+			return nil, errors.New("invalid passkey")
+		}
+		credential, err = s.webAuthn.ValidateLogin(user, *session, parsed)
+		if err != nil {
+			return nil, errors.New("invalid passkey")
+		}
+	} else {
+		credential, err = s.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			userID, err := parseWebAuthnUserHandle(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			user, err = s.loadUser(userID)
+			return user, err
+		}, *session, parsed)
+		if err != nil {
+			return nil, errors.New("invalid passkey")
+		}
+	}
+
+	// A counter that did not advance means the private key may have been copied
+	if credential.Authenticator.CloneWarning {
+		s.db.Model(&models.WebAuthnCredential{}).
+			Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credential.ID)).
+			Update("clone_warning", true)
+		return nil, errors.New("invalid passkey")
+	}
+
+	now := time.Now()
+	s.db.Model(&models.WebAuthnCredential{}).
+		Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]any{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": now,
+		})
+
+	return &user.user, nil
+}
+
+// ListCredentials returns the passkeys registered by a user
+func (s *WebAuthnService) ListCredentials(userID uint) ([]models.WebAuthnCredentialResponse, error) {
+	var credentials []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.WebAuthnCredentialResponse, len(credentials))
+	for i, c := range credentials {
+		responses[i] = newWebAuthnCredentialResponse(c)
+	}
+	return responses, nil
+}
+
+// DeleteCredential revokes one of the user's passkeys
+func (s *WebAuthnService) DeleteCredential(userID, credentialID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", credentialID, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("passkey not found")
+	}
+	return nil
+}
+
+// decoyCredential derives a credential descriptor for a username without passkeys. No
+// authenticator holds it, so the login can't complete.
+func (s *WebAuthnService) decoyCredential(username string) protocol.CredentialDescriptor {
+	mac := hmac.New(sha256.New, s.decoyKey)
+	mac.Write([]byte(strings.ToLower(username)))
+	return protocol.CredentialDescriptor{
+		Type:         protocol.PublicKeyCredentialType,
+		CredentialID: mac.Sum(nil)[:decoyCredentialIDLength],
+	}
+}
+
+// loadUser loads a user with role and passkeys
+func (s *WebAuthnService) loadUser(userID uint) (*webAuthnUser, error) {
+	var user models.Users
+	if err := s.db.Preload("Role").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	var credentials []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", userID).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// saveSession persists ceremony state and returns its ID
+func (s *WebAuthnService) saveSession(purpose string, userID *uint, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	// Opportunistically drop abandoned ceremonies
+	s.db.Where("expires_at < ?", time.Now()).Delete(&models.WebAuthnSession{})
+
+	record := models.WebAuthnSession{
+		ID:        uuid.New().String(),
+		Purpose:   purpose,
+		UserID:    userID,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(s.config.WebAuthnSessionTTL),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+// consumeSession loads and deletes ceremony state, so a challenge can only be answered once
+func (s *WebAuthnService) consumeSession(sessionID, purpose string, userID *uint) (*webauthn.SessionData, error) {
+	var record models.WebAuthnSession
+	if err := s.db.Where("id = ? AND purpose = ?", sessionID, purpose).First(&record).Error; err != nil {
+		return nil, errors.New("webauthn session not found")
+	}
+
+	result := s.db.Where("id = ?", record.ID).Delete(&models.WebAuthnSession{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Another request finished this ceremony first
+		return nil, errors.New("webauthn session not found")
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return nil, errors.New("webauthn session not found")
+	}
+
+	if userID != nil && (record.UserID == nil || *record.UserID != *userID) {
+		return nil, errors.New("webauthn session not found")
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(record.Data), &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// webAuthnUserHandle encodes a user ID as the WebAuthn user handle
+func webAuthnUserHandle(userID uint) []byte {
+	return []byte(strconv.FormatUint(uint64(userID), 10))
+}
+
+// parseWebAuthnUserHandle decodes a user handle produced by webAuthnUserHandle
+func parseWebAuthnUserHandle(handle []byte) (uint, error) {
+	id, err := strconv.ParseUint(string(handle), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// parseTransports splits the stored comma-separated transports
+func parseTransports(value string) []protocol.AuthenticatorTransport {
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ",")
+	transports := make([]protocol.AuthenticatorTransport, len(parts))
+	for i, p := range parts {
+		transports[i] = protocol.AuthenticatorTransport(p)
+	}
+	return transports
+}
+
+// newWebAuthnCredentialResponse maps a stored passkey to its profile representation
+func newWebAuthnCredentialResponse(c models.WebAuthnCredential) models.WebAuthnCredentialResponse {
+	transports := []string{}
+	if c.Transports != "" {
+		transports = strings.Split(c.Transports, ",")
+	}
+
+	return models.WebAuthnCredentialResponse{
+		ID:          c.ID,
+		Name:        c.Name,
+		Transports:  transports,
+		BackupState: c.BackupState,
+		LastUsedAt:  c.LastUsedAt,
+		CreatedAt:   c.CreatedAt,
+	}
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/sass-api/internal/config"
+	"github.com/Aebroyx/sass-api/internal/domain/models"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// virtualAuthenticator is a software passkey authenticator holding a single P-256
+// credential. It signs like a platform authenticator that verifies the user.
+type virtualAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newVirtualAuthenticator(t *testing.T) *virtualAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 32)
+	rand.Read(credentialID)
+
+	return &virtualAuthenticator{key: key, credentialID: credentialID}
+}
+
+// authenticatorData builds the authenticator data, with attested credential data when
+// attested is set
+func (a *virtualAuthenticator) authenticatorData(t *testing.T, attested bool) []byte {
+	t.Helper()
+
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attested {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+
+	data = append(data, make([]byte, 16)...) // AAGUID
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, publicKey...)
+}
+
+func clientDataJSON(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatalf("encode client data: %v", err)
+	}
+	return data
+}
+
+// create answers navigator.credentials.create() with a "none" attestation
+func (a *virtualAuthenticator) create(t *testing.T, options any) json.RawMessage {
+	t.Helper()
+
+	creation, ok := options.(*protocol.CredentialCreation)
+	if !ok {
+		t.Fatalf("unexpected creation options %T", options)
+	}
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatalf("encode attestation: %v", err)
+	}
+
+	return a.credential(t, map[string]any{
+		"clientDataJSON":    clientDataJSON(t, "webauthn.create", creation.Response.Challenge),
+		"attestationObject": attestation,
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers navigator.credentials.get() with a signed assertion
+func (a *virtualAuthenticator) get(t *testing.T, options any) json.RawMessage {
+	t.Helper()
+
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("unexpected assertion options %T", options)
+	}
+
+	a.signCount++
+	authData := a.authenticatorData(t, false)
+	clientData := clientDataJSON(t, "webauthn.get", assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	return a.credential(t, map[string]any{
+		"clientDataJSON":    clientData,
+		"authenticatorData": authData,
+		"signature":         signature,
+		"userHandle":        a.userHandle,
+	})
+}
+
+// credential wraps an authenticator response as a PublicKeyCredential, base64url
+// encoding the binary fields like the browser helpers do
+func (a *virtualAuthenticator) credential(t *testing.T, response map[string]any) json.RawMessage {
+	t.Helper()
+
+	encoded := make(map[string]any, len(response))
+	for k, v := range response {
+		if b, ok := v.([]byte); ok {
+			encoded[k] = base64.RawURLEncoding.EncodeToString(b)
+		} else {
+			encoded[k] = v
+		}
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(a.credentialID)
+	body, err := json.Marshal(map[string]any{
+		"id":       id,
+		"rawId":    id,
+		"type":     "public-key",
+		"response": encoded,
+	})
+	if err != nil {
+		t.Fatalf("encode credential: %v", err)
+	}
+	return body
+}
+
+func newTestWebAuthnService(t *testing.T) *WebAuthnService {
+	t.Helper()
+
+	db := newTestDB(t, &models.Role{}, &models.Users{}, &models.UserRole{}, &models.WebAuthnCredential{}, &models.WebAuthnSession{})
+	service, err := NewWebAuthnService(db, &config.Config{
+		WebAuthnRPID:          testRPID,
+		WebAuthnRPDisplayName: "Test",
+		WebAuthnRPOrigins:     []string{testOrigin},
+		WebAuthnSessionTTL:    5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("new webauthn service: %v", err)
+	}
+	return service
+}
+
+// registerPasskey runs the registration ceremony for the user with authenticator
+func registerPasskey(t *testing.T, s *WebAuthnService, userID uint, authenticator *virtualAuthenticator) models.WebAuthnCredentialResponse {
+	t.Helper()
+
+	begin, err := s.BeginRegistration(userID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+
+	credential, err := s.FinishRegistration(userID, &models.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Name:       "Laptop",
+		Credential: authenticator.create(t, begin.Options),
+	})
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	return *credential
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	authenticator := newVirtualAuthenticator(t)
+
+	credential := registerPasskey(t, s, user.ID, authenticator)
+	if credential.Name != "Laptop" {
+		t.Errorf("name = %q, want Laptop", credential.Name)
+	}
+	if len(credential.Transports) != 1 || credential.Transports[0] != "internal" {
+		t.Errorf("transports = %v, want [internal]", credential.Transports)
+	}
+
+	// The same authenticator can't register twice
+	begin, err := s.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	creation := begin.Options.(*protocol.CredentialCreation)
+	if len(creation.Response.CredentialExcludeList) != 1 {
+		t.Errorf("exclude list has %d entries, want 1", len(creation.Response.CredentialExcludeList))
+	}
+	_, err = s.FinishRegistration(user.ID, &models.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Credential: authenticator.create(t, begin.Options),
+	})
+	if err == nil || err.Error() != "passkey already registered" {
+		t.Errorf("second registration error = %v, want passkey already registered", err)
+	}
+}
+
+func TestWebAuthnRegistrationSessionIsSingleUse(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+
+	begin, err := s.BeginRegistration(alice.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	response := newVirtualAuthenticator(t).create(t, begin.Options)
+
+	// Another user can't finish alice's ceremony, and the attempt burns the session
+	_, err = s.FinishRegistration(bob.ID, &models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: response})
+	if err == nil || err.Error() != "webauthn session not found" {
+		t.Fatalf("finish as other user error = %v, want webauthn session not found", err)
+	}
+	_, err = s.FinishRegistration(alice.ID, &models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: response})
+	if err == nil || err.Error() != "webauthn session not found" {
+		t.Errorf("replayed session error = %v, want webauthn session not found", err)
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	authenticator := newVirtualAuthenticator(t)
+	registerPasskey(t, s, user.ID, authenticator)
+
+	for _, username := range []string{"alice", ""} {
+		name := "username"
+		if username == "" {
+			name = "discoverable"
+		}
+		t.Run(name, func(t *testing.T) {
+			begin, err := s.BeginLogin(username)
+			if err != nil {
+				t.Fatalf("begin login: %v", err)
+			}
+
+			loggedIn, err := s.FinishLogin(&models.WebAuthnFinishRequest{
+				SessionID:  begin.SessionID,
+				Credential: authenticator.get(t, begin.Options),
+			})
+			if err != nil {
+				t.Fatalf("finish login: %v", err)
+			}
+			if loggedIn.ID != user.ID {
+				t.Errorf("logged in as user %d, want %d", loggedIn.ID, user.ID)
+			}
+		})
+	}
+
+	var stored models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", user.ID).First(&stored).Error; err != nil {
+		t.Fatalf("load credential: %v", err)
+	}
+	if stored.SignCount != authenticator.signCount {
+		t.Errorf("sign count = %d, want %d", stored.SignCount, authenticator.signCount)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last used time not recorded")
+	}
+}
+
+func TestWebAuthnLoginRejectsClonedAuthenticator(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	authenticator := newVirtualAuthenticator(t)
+	registerPasskey(t, s, user.ID, authenticator)
+
+	authenticator.signCount = 10
+	begin, _ := s.BeginLogin("alice")
+	if _, err := s.FinishLogin(&models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: authenticator.get(t, begin.Options)}); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+
+	// A copy of the key that signs with an older counter is refused
+	authenticator.signCount = 3
+	begin, _ = s.BeginLogin("alice")
+	_, err := s.FinishLogin(&models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: authenticator.get(t, begin.Options)})
+	if err == nil || err.Error() != "invalid passkey" {
+		t.Errorf("cloned login error = %v, want invalid passkey", err)
+	}
+}
+
+func TestWebAuthnLoginRejectsUnknownPasskey(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	registerPasskey(t, s, user.ID, newVirtualAuthenticator(t))
+
+	stranger := newVirtualAuthenticator(t)
+	stranger.userHandle = webAuthnUserHandle(user.ID)
+
+	begin, err := s.BeginLogin("")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	_, err = s.FinishLogin(&models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: stranger.get(t, begin.Options)})
+	if err == nil || err.Error() != "invalid passkey" {
+		t.Errorf("unknown passkey error = %v, want invalid passkey", err)
+	}
+}
+
+func TestWebAuthnBeginLoginHidesUnknownUsers(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	registerPasskey(t, s, user.ID, newVirtualAuthenticator(t))
+	createTestUser(t, s.db, "bob") // Exists, but has no passkeys
+
+	allowed := func(username string) []protocol.CredentialDescriptor {
+		begin, err := s.BeginLogin(username)
+		if err != nil {
+			t.Fatalf("begin login for %q: %v", username, err)
+		}
+		return begin.Options.(*protocol.CredentialAssertion).Response.AllowedCredentials
+	}
+
+	known := allowed("alice")
+	for _, username := range []string{"bob", "nobody"} {
+		decoy := allowed(username)
+		if len(decoy) != len(known) {
+			t.Fatalf("%s: %d allowed credentials, want %d like a user with a passkey", username, len(decoy), len(known))
+		}
+		if len(decoy[0].CredentialID) != decoyCredentialIDLength {
+			t.Errorf("%s: decoy credential ID has %d bytes", username, len(decoy[0].CredentialID))
+		}
+		if again := allowed(username); string(again[0].CredentialID) != string(decoy[0].CredentialID) {
+			t.Errorf("%s: decoy credential changed between requests", username)
+		}
+	}
+}
+
+func TestWebAuthnListAndDeleteCredentials(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+	first := registerPasskey(t, s, alice.ID, newVirtualAuthenticator(t))
+	registerPasskey(t, s, alice.ID, newVirtualAuthenticator(t))
+
+	credentials, err := s.ListCredentials(alice.ID)
+	if err != nil {
+		t.Fatalf("list credentials: %v", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("listed %d credentials, want 2", len(credentials))
+	}
+
+	// Users can only revoke their own passkeys
+	if err := s.DeleteCredential(bob.ID, first.ID); err == nil || err.Error() != "passkey not found" {
+		t.Errorf("delete as other user error = %v, want passkey not found", err)
+	}
+	if err := s.DeleteCredential(alice.ID, first.ID); err != nil {
+		t.Fatalf("delete credential: %v", err)
+	}
+
+	credentials, err = s.ListCredentials(alice.ID)
+	if err != nil {
+		t.Fatalf("list credentials: %v", err)
+	}
+	if len(credentials) != 1 || credentials[0].ID == first.ID {
+		t.Errorf("credentials after delete = %+v", credentials)
+	}
+}
+
+func TestWebAuthnLoginAfterRevokeFails(t *testing.T) {
+	s := newTestWebAuthnService(t)
+	user := createTestUser(t, s.db, "alice")
+	authenticator := newVirtualAuthenticator(t)
+	credential := registerPasskey(t, s, user.ID, authenticator)
+
+	if err := s.DeleteCredential(user.ID, credential.ID); err != nil {
+		t.Fatalf("delete credential: %v", err)
+	}
+
+	begin, err := s.BeginLogin("")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	_, err = s.FinishLogin(&models.WebAuthnFinishRequest{SessionID: begin.SessionID, Credential: authenticator.get(t, begin.Options)})
+	if err == nil || err.Error() != "invalid passkey" {
+		t.Errorf("login with revoked passkey error = %v, want invalid passkey", err)
+	}
+}
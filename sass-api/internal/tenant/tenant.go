@@ -0,0 +1,201 @@
+// Package tenant scopes database access to the organization a request acts in.
+//
+// The organization travels in the context of a GORM statement. When it is set, the
+// Plugin limits queries, updates and deletes of every model with an OrganizationID
+// field to that organization, and stamps the organization on created rows. Without it,
+// as for root, background jobs and the login flow, queries see every organization.
+package tenant
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type contextKey struct{}
+
+const (
+	// skipKey turns scoping off for a statement, see AllOrganizations
+	skipKey = "tenant:skip"
+
+	// scopedClause marks a statement that already has the tenant condition, so running
+	// the same statement twice (count then find) doesn't add it again
+	scopedClause = "tenant_scoped"
+)
+
+// Shared is implemented by models whose rows without an organization are visible to
+// every organization, such as the built-in roles and menus. Shared rows are read-only
+// inside an organization.
+type Shared interface {
+	SharedAcrossTenants()
+}
+
+// WithOrganization returns a context that scopes queries to the organization
+func WithOrganization(ctx context.Context, organizationID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, organizationID)
+}
+
+// OrganizationID returns the organization ctx is scoped to
+func OrganizationID(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	organizationID, ok := ctx.Value(contextKey{}).(uint)
+	return organizationID, ok
+}
+
+// FromDB returns the organization a database handle is scoped to
+func FromDB(db *gorm.DB) (uint, bool) {
+	if db.Statement == nil {
+		return 0, false
+	}
+	return OrganizationID(db.Statement.Context)
+}
+
+// Scoped returns db scoped to the organization
+func Scoped(db *gorm.DB, organizationID uint) *gorm.DB {
+	ctx := context.Background()
+	if db.Statement != nil && db.Statement.Context != nil {
+		ctx = db.Statement.Context
+	}
+	return db.WithContext(WithOrganization(ctx, organizationID))
+}
+
+// AllOrganizations returns db with scoping turned off, for the few lookups inside an
+// organization that must see every row, e.g. checking that a username is free
+func AllOrganizations(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true)
+}
+
+// CanModify reports whether db may change a row owned by organizationID. Inside an
+// organization, shared rows and rows of other organizations are read-only.
+func CanModify(db *gorm.DB, organizationID *uint) bool {
+	current, scoped := FromDB(db)
+	if !scoped {
+		return true
+	}
+	return organizationID != nil && *organizationID == current
+}
+
+// Plugin registers the GORM callbacks that apply tenant scoping. Raw SQL is not scoped.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", setOrganization); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeReads); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", scopeReads); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scopeWrites); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeWrites)
+}
+
+// organizationField returns the statement's organization and the model's OrganizationID
+// field when the statement needs scoping
+func organizationField(db *gorm.DB) (uint, *schema.Field, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return 0, nil, false
+	}
+	if skip, ok := db.Get(skipKey); ok && skip == true {
+		return 0, nil, false
+	}
+
+	organizationID, scoped := FromDB(db)
+	if !scoped {
+		return 0, nil, false
+	}
+
+	field := db.Statement.Schema.LookUpField("OrganizationID")
+	if field == nil {
+		return 0, nil, false
+	}
+	return organizationID, field, true
+}
+
+// setOrganization stamps the organization on created rows that don't set one
+func setOrganization(db *gorm.DB) {
+	organizationID, field, ok := organizationField(db)
+	if !ok {
+		return
+	}
+
+	ctx := db.Statement.Context
+	set := func(value reflect.Value) {
+		if _, zero := field.ValueOf(ctx, value); zero {
+			id := organizationID
+			if err := field.Set(ctx, value, &id); err != nil {
+				_ = db.AddError(err)
+			}
+		}
+	}
+
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			set(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		set(value)
+	}
+}
+
+// scopeReads limits reads to the organization, plus shared rows for Shared models
+func scopeReads(db *gorm.DB) {
+	organizationID, field, ok := organizationField(db)
+	if !ok {
+		return
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	var condition clause.Expression = clause.Eq{Column: column, Value: organizationID}
+	if _, shared := reflect.New(db.Statement.Schema.ModelType).Interface().(Shared); shared {
+		condition = clause.Or(condition, clause.Eq{Column: column, Value: nil})
+	}
+	addCondition(db, condition)
+}
+
+// scopeWrites limits updates and deletes to the organization's own rows
+func scopeWrites(db *gorm.DB) {
+	organizationID, field, ok := organizationField(db)
+	if !ok {
+		return
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	addCondition(db, clause.Eq{Column: column, Value: organizationID})
+}
+
+// addCondition ANDs the tenant condition with the statement's WHERE clause
+func addCondition(db *gorm.DB, condition clause.Expression) {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses[scopedClause]; ok {
+		return
+	}
+
+	// Group the existing conditions first, so an OR among them can't escape the tenant condition
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 1 {
+			where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+			c.Expression = where
+			stmt.Clauses["WHERE"] = c
+		}
+	}
+
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{condition}})
+	stmt.Clauses[scopedClause] = clause.Clause{}
+}